@@ -0,0 +1,208 @@
+package config
+
+import (
+    "os"
+    "runtime"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Scheduling conflict policies (MEETING_CONFLICT_POLICY)
+const (
+    ConflictWarn   = "warn"   // save and report conflicts in the response
+    ConflictReject = "reject" // 409 unless the request sets force
+    ConflictStrict = "strict" // always 409
+    ConflictOff    = "off"    // skip detection
+)
+
+// ConflictPolicy returns how CreateMeeting/UpdateMeeting handle overlapping
+// meetings. Defaults to "warn", so clients unaware of force keep working;
+// "reject" and "strict" are opt-in.
+func ConflictPolicy() string {
+    switch policy := strings.ToLower(os.Getenv("MEETING_CONFLICT_POLICY")); policy {
+    case ConflictWarn, ConflictReject, ConflictStrict, ConflictOff:
+        return policy
+    }
+    return ConflictWarn
+}
+
+// DefaultTimezone is used for users who have not set their own timezone
+func DefaultTimezone() *time.Location {
+    name := os.Getenv("DEFAULT_TIMEZONE")
+    if name == "" {
+        name = "Asia/Jakarta"
+    }
+    loc, err := time.LoadLocation(name)
+    if err != nil {
+        return time.UTC
+    }
+    return loc
+}
+
+// MaxReminderOffset is the earliest a reminder may fire before a meeting, in minutes
+const MaxReminderOffset = 24 * 60
+
+// ReminderSchedulerEnabled reports whether this instance runs the reminder
+// scheduler. Set REMINDER_SCHEDULER=off to disable it.
+func ReminderSchedulerEnabled() bool {
+    return strings.ToLower(os.Getenv("REMINDER_SCHEDULER")) != "off"
+}
+
+// ReminderOffsets returns the default reminder offsets in minutes before a
+// meeting (REMINDER_OFFSETS, comma separated). Defaults to 15.
+func ReminderOffsets() []int {
+    var offsets []int
+    for _, part := range strings.Split(os.Getenv("REMINDER_OFFSETS"), ",") {
+        n, err := strconv.Atoi(strings.TrimSpace(part))
+        if err == nil && n > 0 && n <= MaxReminderOffset {
+            offsets = append(offsets, n)
+        }
+    }
+    if len(offsets) == 0 {
+        return []int{15}
+    }
+    return offsets
+}
+
+// ReminderChannels returns the channels reminders are sent through
+// (REMINDER_CHANNELS, comma separated). Defaults to email.
+func ReminderChannels() []string {
+    var channels []string
+    for _, part := range strings.Split(os.Getenv("REMINDER_CHANNELS"), ",") {
+        if name := strings.ToLower(strings.TrimSpace(part)); name != "" {
+            channels = append(channels, name)
+        }
+    }
+    if len(channels) == 0 {
+        return []string{"email"}
+    }
+    return channels
+}
+
+// NotificationRetention returns how long in-app notifications are kept
+// (NOTIFICATION_RETENTION_DAYS). Defaults to 90 days.
+func NotificationRetention() time.Duration {
+    days, err := strconv.Atoi(os.Getenv("NOTIFICATION_RETENTION_DAYS"))
+    if err != nil || days <= 0 {
+        days = 90
+    }
+    return time.Duration(days) * 24 * time.Hour
+}
+
+// NotificationLimit returns how many in-app notifications are kept per user
+// (NOTIFICATION_LIMIT). Older ones are dropped first. Defaults to 200.
+func NotificationLimit() int {
+    limit, err := strconv.Atoi(os.Getenv("NOTIFICATION_LIMIT"))
+    if err != nil || limit <= 0 {
+        return 200
+    }
+    return limit
+}
+
+// Realtime brokers
+const (
+    RealtimeBrokerMemory = "memory"
+    RealtimeBrokerMongo  = "mongo"
+)
+
+// RealtimeBroker returns how realtime events reach other server instances
+// (REALTIME_BROKER): memory for a single instance (default) or mongo to fan
+// out through a capped collection.
+func RealtimeBroker() string {
+    if strings.ToLower(os.Getenv("REALTIME_BROKER")) == RealtimeBrokerMongo {
+        return RealtimeBrokerMongo
+    }
+    return RealtimeBrokerMemory
+}
+
+// RealtimeHeartbeat returns how often idle event streams get a heartbeat
+// (REALTIME_HEARTBEAT_SECONDS). Defaults to 25 seconds, below common proxy
+// idle timeouts.
+func RealtimeHeartbeat() time.Duration {
+    seconds, err := strconv.Atoi(os.Getenv("REALTIME_HEARTBEAT_SECONDS"))
+    if err != nil || seconds <= 0 {
+        seconds = 25
+    }
+    return time.Duration(seconds) * time.Second
+}
+
+// WebhookWorkerEnabled reports whether this instance delivers webhooks
+// (WEBHOOK_WORKER=off disables it, e.g. on API-only replicas)
+func WebhookWorkerEnabled() bool {
+    return strings.ToLower(os.Getenv("WEBHOOK_WORKER")) != "off"
+}
+
+// WebhookDisableAfter returns how many consecutive failed attempts disable
+// a webhook (WEBHOOK_DISABLE_AFTER). Defaults to 20.
+func WebhookDisableAfter() int {
+    n, err := strconv.Atoi(os.Getenv("WEBHOOK_DISABLE_AFTER"))
+    if err != nil || n <= 0 {
+        return 20
+    }
+    return n
+}
+
+// MeetingPurgeEnabled reports whether this instance purges cancelled
+// meetings past their retention (MEETING_PURGE=off disables it)
+func MeetingPurgeEnabled() bool {
+    return strings.ToLower(os.Getenv("MEETING_PURGE")) != "off"
+}
+
+// MeetingTrashRetention returns how long cancelled meetings stay in the
+// trash and can be restored (MEETING_TRASH_RETENTION_DAYS). Defaults to 30 days.
+func MeetingTrashRetention() time.Duration {
+    days, err := strconv.Atoi(os.Getenv("MEETING_TRASH_RETENTION_DAYS"))
+    if err != nil || days <= 0 {
+        days = 30
+    }
+    return time.Duration(days) * 24 * time.Hour
+}
+
+// ActionItemWatcherEnabled reports whether this instance notifies assignees
+// of overdue action items (ACTION_ITEM_WATCHER=off disables it)
+func ActionItemWatcherEnabled() bool {
+    return strings.ToLower(os.Getenv("ACTION_ITEM_WATCHER")) != "off"
+}
+
+// EmotionClassifier returns the name of the classifier for uploaded frames
+// (EMOTION_CLASSIFIER). There is no default: frames are refused until a
+// classifier is configured.
+func EmotionClassifier() string {
+    return strings.TrimSpace(os.Getenv("EMOTION_CLASSIFIER"))
+}
+
+// EmotionWorkers returns how many frames are classified at once
+// (EMOTION_WORKERS). Defaults to the number of CPUs.
+func EmotionWorkers() int {
+    n, err := strconv.Atoi(os.Getenv("EMOTION_WORKERS"))
+    if err != nil || n <= 0 {
+        return runtime.NumCPU()
+    }
+    return n
+}
+
+// EmotionRetention returns how long emotion samples and reports are kept
+// (EMOTION_RETENTION_DAYS). Defaults to 180 days.
+func EmotionRetention() time.Duration {
+    days, err := strconv.Atoi(os.Getenv("EMOTION_RETENTION_DAYS"))
+    if err != nil || days <= 0 {
+        days = 180
+    }
+    return time.Duration(days) * 24 * time.Hour
+}
+
+// minEmotionParticipants is the lowest emotion aggregate threshold allowed
+const minEmotionParticipants = 3
+
+// EmotionLiveMinParticipants returns how many participants besides the
+// viewer a live emotion aggregate needs before it is shown
+// (EMOTION_LIVE_MIN_PARTICIPANTS), so no individual can be singled out.
+// Defaults to, and is never below, minEmotionParticipants.
+func EmotionLiveMinParticipants() int {
+    n, err := strconv.Atoi(os.Getenv("EMOTION_LIVE_MIN_PARTICIPANTS"))
+    if err != nil || n < minEmotionParticipants {
+        return minEmotionParticipants
+    }
+    return n
+}
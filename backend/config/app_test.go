@@ -0,0 +1,23 @@
+package config
+
+import "testing"
+
+func TestEmotionClassifierHasNoDefault(t *testing.T) {
+    t.Setenv("EMOTION_CLASSIFIER", "")
+    if name := EmotionClassifier(); name != "" {
+        t.Errorf("EmotionClassifier() = %q without configuration, want none", name)
+    }
+    t.Setenv("EMOTION_CLASSIFIER", " onnx ")
+    if name := EmotionClassifier(); name != "onnx" {
+        t.Errorf("EmotionClassifier() = %q, want onnx", name)
+    }
+}
+
+func TestEmotionLiveMinParticipantsFloor(t *testing.T) {
+    for env, want := range map[string]int{"": 3, "1": 3, "2": 3, "abc": 3, "3": 3, "5": 5} {
+        t.Setenv("EMOTION_LIVE_MIN_PARTICIPANTS", env)
+        if got := EmotionLiveMinParticipants(); got != want {
+            t.Errorf("EMOTION_LIVE_MIN_PARTICIPANTS=%q: got %d, want %d", env, got, want)
+        }
+    }
+}
@@ -2,11 +2,13 @@ package config
 
 import (
     "context"
+    "errors"
     "log"
     "os"
     "time"
 
     "github.com/joho/godotenv"
+    "go.mongodb.org/mongo-driver/bson"
     "go.mongodb.org/mongo-driver/mongo"
     "go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -15,6 +17,17 @@ var (
     MongoClient         *mongo.Client
     UserCollectionRef   *mongo.Collection
     MeetingCollectionRef *mongo.Collection
+    TeamCollectionRef    *mongo.Collection
+    ReminderCollectionRef *mongo.Collection
+    NotificationCollectionRef *mongo.Collection
+    WebhookCollectionRef *mongo.Collection
+    WebhookDeliveryCollectionRef *mongo.Collection
+    EmotionSampleCollectionRef *mongo.Collection
+    EmotionReportCollectionRef *mongo.Collection
+    EmotionConsentCollectionRef *mongo.Collection
+    MeetingNoteCollectionRef *mongo.Collection
+    NoteRevisionCollectionRef *mongo.Collection
+    ActionItemCollectionRef *mongo.Collection
 )
 
 func ConnectDB() {
@@ -69,6 +82,18 @@ func ConnectDB() {
         log.Println("Warning: Meeting collection reference is nil")
     }
 
+    TeamCollectionRef = MongoClient.Database(dbName).Collection("teams")
+    ReminderCollectionRef = MongoClient.Database(dbName).Collection("reminders")
+    NotificationCollectionRef = MongoClient.Database(dbName).Collection("notifications")
+    WebhookCollectionRef = MongoClient.Database(dbName).Collection("webhooks")
+    WebhookDeliveryCollectionRef = MongoClient.Database(dbName).Collection("webhook_deliveries")
+    EmotionSampleCollectionRef = MongoClient.Database(dbName).Collection("emotion_samples")
+    EmotionReportCollectionRef = MongoClient.Database(dbName).Collection("emotion_reports")
+    EmotionConsentCollectionRef = MongoClient.Database(dbName).Collection("emotion_consents")
+    MeetingNoteCollectionRef = MongoClient.Database(dbName).Collection("meeting_notes")
+    NoteRevisionCollectionRef = MongoClient.Database(dbName).Collection("meeting_note_revisions")
+    ActionItemCollectionRef = MongoClient.Database(dbName).Collection("action_items")
+
     log.Println("Connected to MongoDB")
 }
 
@@ -80,4 +105,23 @@ func GetDbName() string {
         dbName = "dbPBOMMO"
     }
     return dbName
-}
\ No newline at end of file
+}
+
+// EnsureTTLIndex creates a TTL index on field, or changes the expiry of
+// an existing one when the configured retention changed
+func EnsureTTLIndex(ctx context.Context, coll *mongo.Collection, field string, expireAfter time.Duration) error {
+    seconds := int32(expireAfter.Seconds())
+    _, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys:    bson.D{{Key: field, Value: 1}},
+        Options: options.Index().SetName(field + "_ttl").SetExpireAfterSeconds(seconds),
+    })
+    var cmdErr mongo.CommandError
+    if errors.As(err, &cmdErr) && cmdErr.Name == "IndexOptionsConflict" {
+        // Matched by key, since indexes created earlier may have other names
+        return coll.Database().RunCommand(ctx, bson.D{
+            {Key: "collMod", Value: coll.Name()},
+            {Key: "index", Value: bson.M{"keyPattern": bson.M{field: 1}, "expireAfterSeconds": seconds}},
+        }).Err()
+    }
+    return err
+}
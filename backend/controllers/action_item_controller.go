@@ -0,0 +1,564 @@
+package controllers
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "strings"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/notify"
+    "pbommo/utils"
+)
+
+const (
+    maxActionDescription = 1000
+    maxActionItemPage    = 200
+)
+
+// pendingActionStatuses are the states of items still to be done
+var pendingActionStatuses = []string{models.ActionOpen, models.ActionInProgress}
+
+// actionItemInput is the body of the action item endpoints; omitted fields
+// are left unchanged on update, and an empty assigneeId or dueDate clears it
+type actionItemInput struct {
+    Description *string `json:"description"`
+    AssigneeID  *string `json:"assigneeId"`
+    DueDate     *string `json:"dueDate"` // RFC3339, or YYYY-MM-DD for the end of that day
+    Status      *string `json:"status"`
+}
+
+// GetMeetingActionItems lists the action items of a meeting, or of one
+// occurrence of a recurring meeting given in ?start=
+func GetMeetingActionItems(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meeting, _, errResp := loadAttendedMeeting(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+    meeting, found, errResp := selectOccurrence(c, ctx, meeting, false)
+    if errResp != nil {
+        return errResp()
+    }
+
+    items := []models.ActionItem{}
+    if found {
+        cursor, err := config.ActionItemCollectionRef.Find(ctx, bson.M{"meetingId": meeting.ID},
+            options.Find().SetSort(bson.M{"createdAt": 1}))
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil action item"})
+        }
+        if err := cursor.All(ctx, &items); err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memproses action item"})
+        }
+    }
+    markOverdue(items, time.Now())
+
+    return c.JSON(fiber.Map{"actionItems": items})
+}
+
+// CreateActionItem adds an action item to a meeting. Anyone on the meeting
+// may add items; the assignee must be on the meeting too and is told.
+func CreateActionItem(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meeting, userID, errResp := loadAttendedMeeting(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+
+    var input actionItemInput
+    if err := c.BodyParser(&input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+    }
+
+    meeting, _, errResp = selectOccurrence(c, ctx, meeting, true)
+    if errResp != nil {
+        return errResp()
+    }
+    if meeting.Status == models.MeetingCancelled {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Meeting sudah dibatalkan"})
+    }
+
+    now := time.Now()
+    item := models.ActionItem{
+        MeetingID: meeting.ID,
+        Status:    models.ActionOpen,
+        CreatedBy: userID,
+        CreatedAt: now,
+        UpdatedAt: now,
+    }
+    if err := applyActionItemInput(meeting, &item, input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+    }
+    if item.Description == "" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Deskripsi action item diperlukan"})
+    }
+
+    result, err := config.ActionItemCollectionRef.InsertOne(ctx, item)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat action item"})
+    }
+    item.ID = result.InsertedID.(primitive.ObjectID)
+    notifyActionAssigned(meeting, item, userID)
+    markOverdue([]models.ActionItem{item}, now)
+
+    return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+        "message":    "Action item berhasil dibuat",
+        "actionItem": item,
+    })
+}
+
+// GetActionItems lists the current user's action items across meetings
+// (?assignee=me), soonest due first. ?status= keeps the given
+// comma-separated states and ?overdue=true only overdue items.
+func GetActionItems(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+    if assignee := c.Query("assignee", "me"); assignee != "me" && assignee != userID.Hex() {
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya action item milik sendiri yang dapat dilihat"})
+    }
+
+    now := time.Now()
+    // Items carried to a follow-up are listed through their copy
+    filter := bson.M{"assigneeId": userID, "carriedTo": bson.M{"$exists": false}}
+    if statuses := splitList(c.Query("status")); len(statuses) > 0 {
+        for _, status := range statuses {
+            if !validActionStatus(status) {
+                return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Parameter status tidak valid"})
+            }
+        }
+        filter["status"] = bson.M{"$in": statuses}
+    }
+    if c.QueryBool("overdue") {
+        filter["$and"] = []bson.M{
+            {"status": bson.M{"$in": pendingActionStatuses}},
+            {"dueDate": bson.M{"$lt": now}},
+        }
+    }
+    limit := c.QueryInt("limit", 100)
+    if limit <= 0 || limit > maxActionItemPage {
+        limit = maxActionItemPage
+    }
+
+    // Items with a due date first, soonest first, then the newest
+    cursor, err := config.ActionItemCollectionRef.Aggregate(ctx, mongo.Pipeline{
+        {{Key: "$match", Value: filter}},
+        {{Key: "$addFields", Value: bson.M{
+            "noDueDate": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{bson.M{"$type": "$dueDate"}, "date"}}, 0, 1}},
+        }}},
+        {{Key: "$sort", Value: bson.D{{Key: "noDueDate", Value: 1}, {Key: "dueDate", Value: 1}, {Key: "createdAt", Value: -1}}}},
+        {{Key: "$limit", Value: limit}},
+        {{Key: "$project", Value: bson.M{"noDueDate": 0}}},
+    })
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil action item"})
+    }
+    items := []models.ActionItem{}
+    if err := cursor.All(ctx, &items); err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memproses action item"})
+    }
+    markOverdue(items, now)
+
+    meetings, err := actionItemMeetings(ctx, items)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data meeting"})
+    }
+
+    return c.JSON(fiber.Map{
+        "actionItems": items,
+        "meetings":    meetings,
+    })
+}
+
+// UpdateActionItem changes an action item. The organizer and the item's
+// creator may change everything, the assignee only the status.
+func UpdateActionItem(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    item, meeting, userID, errResp := loadActionItem(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+
+    var input actionItemInput
+    if err := c.BodyParser(&input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+    }
+    if meeting.CreatedBy != userID && item.CreatedBy != userID {
+        isAssignee := item.AssigneeID != nil && *item.AssigneeID == userID
+        if !isAssignee || input.Description != nil || input.AssigneeID != nil || input.DueDate != nil {
+            return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Penerima tugas hanya dapat mengubah status"})
+        }
+    }
+
+    before := item
+    if err := applyActionItemInput(meeting, &item, input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    // Only the given fields are written, and only over the version that
+    // was checked above
+    now := time.Now()
+    set := bson.M{"updatedAt": now}
+    unset := bson.M{}
+    setOptional := func(field string, value interface{}, present bool) {
+        if present {
+            set[field] = value
+        } else {
+            unset[field] = ""
+        }
+    }
+    if input.Description != nil {
+        set["description"] = item.Description
+    }
+    if input.AssigneeID != nil {
+        setOptional("assigneeId", item.AssigneeID, item.AssigneeID != nil)
+    }
+    if input.DueDate != nil {
+        setOptional("dueDate", item.DueDate, item.DueDate != nil)
+    }
+    if input.Status != nil {
+        set["status"] = item.Status
+        setOptional("completedAt", item.CompletedAt, item.CompletedAt != nil)
+    }
+    // A new due date or assignee, or reopening, earns a new overdue notice
+    if !sameTime(before.DueDate, item.DueDate) || !sameID(before.AssigneeID, item.AssigneeID) || item.Status != before.Status {
+        unset["overdueNotifiedAt"] = ""
+        unset["overdueRetryAt"] = ""
+    }
+    update := bson.M{"$set": set}
+    if len(unset) > 0 {
+        update["$unset"] = unset
+    }
+
+    result, err := config.ActionItemCollectionRef.UpdateOne(ctx, bson.M{"_id": item.ID, "updatedAt": before.UpdatedAt}, update)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengupdate action item"})
+    }
+    if result.MatchedCount == 0 {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Action item baru saja diubah, muat ulang dan coba lagi"})
+    }
+    item.UpdatedAt = now
+    if !sameID(before.AssigneeID, item.AssigneeID) {
+        notifyActionAssigned(meeting, item, userID)
+    }
+    markOverdue([]models.ActionItem{item}, now)
+
+    return c.JSON(fiber.Map{
+        "message":    "Action item berhasil diupdate",
+        "actionItem": item,
+    })
+}
+
+// DeleteActionItem removes an action item (organizer or creator only)
+func DeleteActionItem(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    item, meeting, userID, errResp := loadActionItem(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+    if meeting.CreatedBy != userID && item.CreatedBy != userID {
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya pembuat meeting atau action item yang dapat menghapus"})
+    }
+
+    if _, err := config.ActionItemCollectionRef.DeleteOne(ctx, bson.M{"_id": item.ID}); err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus action item"})
+    }
+    return c.JSON(fiber.Map{"message": "Action item berhasil dihapus"})
+}
+
+// EnsureActionItemIndexes creates the indexes for listing items per
+// meeting and per assignee, and for finding overdue ones
+func EnsureActionItemIndexes(ctx context.Context) error {
+    _, err := config.ActionItemCollectionRef.Indexes().CreateMany(ctx, []mongo.IndexModel{
+        {Keys: bson.D{{Key: "meetingId", Value: 1}, {Key: "createdAt", Value: 1}}},
+        {Keys: bson.D{{Key: "assigneeId", Value: 1}, {Key: "status", Value: 1}}},
+        {Keys: bson.D{{Key: "status", Value: 1}, {Key: "dueDate", Value: 1}}},
+    })
+    return err
+}
+
+// carryOverActionItems copies the pending action items of a meeting to its
+// follow-up and returns how many were copied. The copy is stored before the
+// original is linked to the follow-up, so a failure never hides an item;
+// an original linked by a concurrent follow-up meanwhile drops the copy.
+// Assignees not on the follow-up are dropped from the copy.
+func carryOverActionItems(ctx context.Context, from primitive.ObjectID, to models.Meeting) (int64, error) {
+    cursor, err := config.ActionItemCollectionRef.Find(ctx,
+        bson.M{
+            "meetingId": from,
+            "status":    bson.M{"$in": pendingActionStatuses},
+            "carriedTo": bson.M{"$exists": false},
+        },
+        options.Find().SetSort(bson.M{"createdAt": 1}))
+    if err != nil {
+        return 0, err
+    }
+    var items []models.ActionItem
+    if err := cursor.All(ctx, &items); err != nil {
+        return 0, err
+    }
+
+    var carried int64
+    for _, item := range items {
+        originalID := item.ID
+        now := time.Now()
+        item.ID = primitive.NewObjectID()
+        item.MeetingID = to.ID
+        item.CarriedFrom = append(item.CarriedFrom, from)
+        item.OverdueRetryAt = nil
+        item.CreatedAt = now
+        item.UpdatedAt = now
+        if item.AssigneeID != nil && *item.AssigneeID != to.CreatedBy && !isParticipant(to, *item.AssigneeID) {
+            item.AssigneeID = nil
+            item.OverdueNotifiedAt = nil
+        }
+        if _, err := config.ActionItemCollectionRef.InsertOne(ctx, item); err != nil {
+            return carried, err
+        }
+
+        result, err := config.ActionItemCollectionRef.UpdateOne(ctx,
+            bson.M{"_id": originalID, "carriedTo": bson.M{"$exists": false}},
+            bson.M{"$set": bson.M{"carriedTo": to.ID}})
+        if err != nil || result.MatchedCount == 0 {
+            if _, delErr := config.ActionItemCollectionRef.DeleteOne(ctx, bson.M{"_id": item.ID}); delErr != nil {
+                log.Printf("Failed to drop copy %s of action item %s: %v", item.ID.Hex(), originalID.Hex(), delErr)
+            }
+            if err != nil {
+                return carried, err
+            }
+            continue
+        }
+        carried++
+    }
+    return carried, nil
+}
+
+// loadActionItem finds the item in :id and its meeting, which the current
+// user must organize or attend. On failure it returns a function writing
+// the error response.
+func loadActionItem(c *fiber.Ctx, ctx context.Context) (models.ActionItem, models.Meeting, primitive.ObjectID, func() error) {
+    var item models.ActionItem
+    var meeting models.Meeting
+    fail := func(status int, msg string) func() error {
+        return func() error { return c.Status(status).JSON(fiber.Map{"error": msg}) }
+    }
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return item, meeting, userID, fail(fiber.StatusUnauthorized, "Token tidak valid")
+    }
+    itemID, err := primitive.ObjectIDFromHex(c.Params("id"))
+    if err != nil {
+        return item, meeting, userID, fail(fiber.StatusBadRequest, "ID action item tidak valid")
+    }
+
+    err = config.ActionItemCollectionRef.FindOne(ctx, bson.M{"_id": itemID}).Decode(&item)
+    if err == mongo.ErrNoDocuments {
+        return item, meeting, userID, fail(fiber.StatusNotFound, "Action item tidak ditemukan")
+    }
+    if err != nil {
+        return item, meeting, userID, fail(fiber.StatusInternalServerError, "Gagal mengambil action item")
+    }
+    err = config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": item.MeetingID}).Decode(&meeting)
+    if err != nil && err != mongo.ErrNoDocuments {
+        return item, meeting, userID, fail(fiber.StatusInternalServerError, "Gagal mengambil data meeting")
+    }
+    // Items of a meeting in the trash go and come back with it
+    if meeting.DeletedAt != nil {
+        return item, meeting, userID, fail(fiber.StatusNotFound, "Action item tidak ditemukan")
+    }
+
+    isAssignee := item.AssigneeID != nil && *item.AssigneeID == userID
+    if !isAssignee && meeting.CreatedBy != userID && !isParticipant(meeting, userID) {
+        return item, meeting, userID, fail(fiber.StatusForbidden, "Anda tidak memiliki akses ke action item ini")
+    }
+    return item, meeting, userID, nil
+}
+
+// applyActionItemInput validates the given fields and copies them to item
+func applyActionItemInput(meeting models.Meeting, item *models.ActionItem, input actionItemInput) error {
+    if input.Description != nil {
+        description := strings.TrimSpace(*input.Description)
+        if description == "" || len(description) > maxActionDescription {
+            return fmt.Errorf("Deskripsi action item diperlukan (maksimal %d karakter)", maxActionDescription)
+        }
+        item.Description = description
+    }
+    if input.AssigneeID != nil {
+        if *input.AssigneeID == "" {
+            item.AssigneeID = nil
+        } else {
+            assigneeID, err := primitive.ObjectIDFromHex(*input.AssigneeID)
+            if err != nil {
+                return fmt.Errorf("assigneeId tidak valid")
+            }
+            if assigneeID != meeting.CreatedBy && !isParticipant(meeting, assigneeID) {
+                return fmt.Errorf("Penerima tugas harus peserta meeting")
+            }
+            item.AssigneeID = &assigneeID
+        }
+    }
+    if input.DueDate != nil {
+        if *input.DueDate == "" {
+            item.DueDate = nil
+        } else {
+            due, err := parseDueDate(*input.DueDate)
+            if err != nil {
+                return err
+            }
+            item.DueDate = &due
+        }
+    }
+    if input.Status != nil {
+        if !validActionStatus(*input.Status) {
+            return fmt.Errorf("Status action item tidak valid")
+        }
+        if *input.Status == models.ActionDone && item.Status != models.ActionDone {
+            now := time.Now()
+            item.CompletedAt = &now
+        } else if *input.Status != models.ActionDone {
+            item.CompletedAt = nil
+        }
+        item.Status = *input.Status
+    }
+    return nil
+}
+
+// parseDueDate reads RFC3339, or YYYY-MM-DD meaning the end of that day in
+// the default timezone
+func parseDueDate(value string) (time.Time, error) {
+    value = strings.ReplaceAll(value, " ", "+")
+    if t, err := time.Parse(time.RFC3339, value); err == nil {
+        return t, nil
+    }
+    day, err := time.ParseInLocation("2006-01-02", value, config.DefaultTimezone())
+    if err != nil {
+        return time.Time{}, fmt.Errorf("dueDate tidak valid")
+    }
+    return day.AddDate(0, 0, 1).Add(-time.Second), nil
+}
+
+func validActionStatus(status string) bool {
+    switch status {
+    case models.ActionOpen, models.ActionInProgress, models.ActionDone, models.ActionCancelled:
+        return true
+    }
+    return false
+}
+
+// markOverdue flags pending items past their due date
+func markOverdue(items []models.ActionItem, now time.Time) {
+    for i := range items {
+        items[i].Overdue = isOverdue(items[i], now)
+    }
+}
+
+func isOverdue(item models.ActionItem, now time.Time) bool {
+    pending := item.Status == models.ActionOpen || item.Status == models.ActionInProgress
+    return pending && item.DueDate != nil && now.After(*item.DueDate)
+}
+
+// actionItemMeetings summarizes the meetings of the items, keyed by id
+func actionItemMeetings(ctx context.Context, items []models.ActionItem) (map[primitive.ObjectID]fiber.Map, error) {
+    meetings := map[primitive.ObjectID]fiber.Map{}
+    if len(items) == 0 {
+        return meetings, nil
+    }
+    var ids []primitive.ObjectID
+    for _, item := range items {
+        ids = append(ids, item.MeetingID)
+    }
+    cursor, err := config.MeetingCollectionRef.Find(ctx, bson.M{"_id": bson.M{"$in": ids}},
+        options.Find().SetProjection(bson.M{"title": 1, "startTime": 1, "duration": 1, "status": 1, "seriesId": 1, "recurrence": 1}))
+    if err != nil {
+        return nil, err
+    }
+    var list []models.Meeting
+    if err := cursor.All(ctx, &list); err != nil {
+        return nil, err
+    }
+    now := time.Now()
+    for _, m := range list {
+        meetings[m.ID] = fiber.Map{
+            "title":     m.Title,
+            "startTime": m.StartTime,
+            "status":    meetingStatus(m, now),
+        }
+    }
+    return meetings, nil
+}
+
+// notifyActionAssigned tells the assignee about an item assigned to them
+// by someone else
+func notifyActionAssigned(meeting models.Meeting, item models.ActionItem, actorID primitive.ObjectID) {
+    if item.AssigneeID == nil || *item.AssigneeID == actorID {
+        return
+    }
+    assigneeID := *item.AssigneeID
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        defer cancel()
+
+        users, err := usersByID(ctx, []primitive.ObjectID{assigneeID, actorID})
+        if err != nil {
+            log.Printf("Failed to load users for action item %s: %v", item.ID.Hex(), err)
+            return
+        }
+        assignee, ok := users[assigneeID]
+        if !ok || assignee.Disabled {
+            return
+        }
+        n := newActionItemNotification(models.NotificationActionItem, meeting, item, assignee,
+            notify.T(assignee.Language, "action.assigned", users[actorID].Nama, item.Description))
+        n.ActorID = &actorID
+        if err := notify.SaveInApp(ctx, n); err != nil {
+            log.Printf("Failed to save action item notification: %v", err)
+        }
+    }()
+}
+
+// newActionItemNotification is a meeting notification whose body is the
+// item's due date, when it has one
+func newActionItemNotification(kind string, meeting models.Meeting, item models.ActionItem, user models.User, title string) models.Notification {
+    n := newMeetingNotification(kind, meeting, user, title)
+    n.MeetingID = &item.MeetingID
+    if item.DueDate != nil {
+        n.Body = notify.FormatDateTime(user.Language, item.DueDate.In(userLocation(user)))
+    }
+    return n
+}
+
+func sameTime(a, b *time.Time) bool {
+    if a == nil || b == nil {
+        return a == b
+    }
+    return a.Equal(*b)
+}
+
+func sameID(a, b *primitive.ObjectID) bool {
+    if a == nil || b == nil {
+        return a == b
+    }
+    return *a == *b
+}
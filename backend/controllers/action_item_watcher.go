@@ -0,0 +1,151 @@
+package controllers
+
+import (
+    "context"
+    "log"
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/notify"
+)
+
+const (
+    actionItemTick  = 15 * time.Minute
+    actionItemBatch = 100
+    // actionItemLease is how long a claimed item is left to the instance
+    // notifying about it before another may retry
+    actionItemLease = 5 * time.Minute
+    // actionItemRecheck is when items of cancelled or trashed meetings are
+    // looked at again, in case the meeting comes back
+    actionItemRecheck = 24 * time.Hour
+)
+
+// StartActionItemWatcher tells assignees once about each of their action
+// items past its due date, in the background until ctx is done. Items are
+// claimed atomically for a while, so several instances may run it; an item
+// only counts as notified once the notification is saved.
+func StartActionItemWatcher(ctx context.Context) {
+    if !config.ActionItemWatcherEnabled() {
+        log.Println("📌 Action item watcher disabled")
+        return
+    }
+
+    go func() {
+        ticker := time.NewTicker(actionItemTick)
+        defer ticker.Stop()
+        for {
+            notifyOverdueActionItems(ctx, time.Now())
+            select {
+            case <-ctx.Done():
+                return
+            case <-ticker.C:
+            }
+        }
+    }()
+}
+
+// notifyOverdueActionItems claims up to a batch of overdue items not yet
+// notified and tells their assignees
+func notifyOverdueActionItems(ctx context.Context, now time.Time) {
+    for i := 0; i < actionItemBatch; i++ {
+        var item models.ActionItem
+        err := config.ActionItemCollectionRef.FindOneAndUpdate(ctx,
+            bson.M{
+                "status":            bson.M{"$in": pendingActionStatuses},
+                "dueDate":           bson.M{"$lt": now},
+                "assigneeId":        bson.M{"$exists": true},
+                "carriedTo":         bson.M{"$exists": false},
+                "overdueNotifiedAt": bson.M{"$exists": false},
+                "$or": []bson.M{
+                    {"overdueRetryAt": bson.M{"$exists": false}},
+                    {"overdueRetryAt": bson.M{"$lte": now}},
+                },
+            },
+            bson.M{"$set": bson.M{"overdueRetryAt": now.Add(actionItemLease)}},
+            options.FindOneAndUpdate().SetSort(bson.M{"dueDate": 1}).SetReturnDocument(options.After),
+        ).Decode(&item)
+        if err == mongo.ErrNoDocuments {
+            return
+        }
+        if err != nil {
+            log.Printf("Failed to claim overdue action item: %v", err)
+            return
+        }
+
+        notified, err := notifyActionOverdue(ctx, item)
+        if err != nil {
+            // The lease runs out and the item is retried
+            log.Printf("Failed to notify overdue action item %s: %v", item.ID.Hex(), err)
+            continue
+        }
+        update := bson.M{"$set": bson.M{"overdueNotifiedAt": now}, "$unset": bson.M{"overdueRetryAt": ""}}
+        if !notified {
+            update = bson.M{"$set": bson.M{"overdueRetryAt": now.Add(actionItemRecheck)}}
+        }
+        // Only the claim this run holds is settled; an update of the item
+        // meanwhile has released it
+        _, err = config.ActionItemCollectionRef.UpdateOne(ctx,
+            bson.M{"_id": item.ID, "overdueRetryAt": *item.OverdueRetryAt}, update)
+        if err != nil {
+            log.Printf("Failed to mark overdue action item %s: %v", item.ID.Hex(), err)
+        }
+    }
+}
+
+// notifyActionOverdue tells the assignee about an overdue item. Nothing is
+// sent for items of cancelled or trashed meetings, which it reports as not
+// notified.
+func notifyActionOverdue(ctx context.Context, item models.ActionItem) (bool, error) {
+    var meeting models.Meeting
+    if err := config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": item.MeetingID}).Decode(&meeting); err != nil {
+        if err == mongo.ErrNoDocuments {
+            return false, nil
+        }
+        return false, err
+    }
+    active, err := meetingActive(ctx, meeting)
+    if err != nil || !active {
+        return false, err
+    }
+    var user models.User
+    if err := config.UserCollectionRef.FindOne(ctx, bson.M{"_id": *item.AssigneeID}).Decode(&user); err != nil {
+        if err == mongo.ErrNoDocuments {
+            return true, nil
+        }
+        return false, err
+    }
+    if user.Disabled {
+        return true, nil
+    }
+    n := newActionItemNotification(models.NotificationActionOverdue, meeting, item, user,
+        notify.T(user.Language, "action.overdue", item.Description))
+    if err := notify.SaveInApp(ctx, n); err != nil {
+        return false, err
+    }
+    return true, nil
+}
+
+// meetingActive reports whether neither the meeting nor, for an occurrence,
+// its series is cancelled or in the trash
+func meetingActive(ctx context.Context, meeting models.Meeting) (bool, error) {
+    if meeting.Status == models.MeetingCancelled || meeting.DeletedAt != nil {
+        return false, nil
+    }
+    if meeting.SeriesID == nil {
+        return true, nil
+    }
+    var series models.Meeting
+    err := config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": *meeting.SeriesID}).Decode(&series)
+    if err == mongo.ErrNoDocuments {
+        return false, nil
+    }
+    if err != nil {
+        return false, err
+    }
+    return series.Status != models.MeetingCancelled && series.DeletedAt == nil, nil
+}
@@ -0,0 +1,544 @@
+package controllers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "strings"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/notify"
+    "pbommo/realtime"
+)
+
+const (
+    maxAgendaTitle = 200
+    maxAgendaNotes = 5000
+    maxAgendaItems = 50
+)
+
+var errAgendaChanged = errors.New("agenda changed")
+
+// agendaInput is the body of the agenda item endpoints; omitted fields are
+// left unchanged on update, and an empty ownerId removes the owner
+type agendaInput struct {
+    Title   *string `json:"title"`
+    OwnerID *string `json:"ownerId"`
+    Minutes *int    `json:"minutes"`
+    Notes   *string `json:"notes"`
+}
+
+// GetAgenda returns the agenda of a meeting with the minutes it takes up
+func GetAgenda(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meeting, _, errResp := loadAttendedMeeting(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+    return c.JSON(agendaResponse(meeting))
+}
+
+// AddAgendaItem appends an item to the agenda. The organizer adds items
+// directly; participants may propose items before the meeting when the
+// organizer allows proposals, and the organizer is told about them.
+func AddAgendaItem(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meeting, userID, errResp := loadAttendedMeeting(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+    if msg := agendaLocked(meeting); msg != "" {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": msg})
+    }
+
+    organizer := meeting.CreatedBy == userID
+    if !organizer {
+        if !meeting.AgendaProposals {
+            return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya pembuat meeting yang dapat menambah agenda"})
+        }
+        if !acceptsProposals(meeting, time.Now()) {
+            return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Usulan agenda hanya dapat diajukan sebelum meeting dimulai"})
+        }
+    }
+    if len(meeting.Agenda) >= maxAgendaItems {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Agenda maksimal %d item", maxAgendaItems)})
+    }
+
+    var input agendaInput
+    if err := c.BodyParser(&input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+    }
+    item := models.AgendaItem{
+        ID:        primitive.NewObjectID(),
+        Status:    models.AgendaOpen,
+        CreatedAt: time.Now(),
+    }
+    if !organizer {
+        item.Status = models.AgendaProposed
+        item.ProposedBy = &userID
+        item.OwnerID = &userID
+    }
+    if err := applyAgendaInput(meeting, &item, input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+    }
+    if item.Title == "" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Judul agenda diperlukan"})
+    }
+    if item.Minutes <= 0 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Alokasi waktu agenda diperlukan"})
+    }
+
+    agenda := append(append([]models.AgendaItem{}, meeting.Agenda...), item)
+    if err := checkAgendaFits(meeting, agenda); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+    }
+    meeting, err := saveAgenda(ctx, meeting, agenda)
+    if err != nil {
+        return agendaSaveError(c, err)
+    }
+    if !organizer {
+        notifyAgendaProposal(meeting, userID, item)
+    }
+
+    return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+        "message": "Agenda berhasil ditambahkan",
+        "item":    item,
+    })
+}
+
+// UpdateAgendaItem changes an agenda item. The organizer may change
+// everything, the proposer their item while it is still proposed, and the
+// owner only the notes.
+func UpdateAgendaItem(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meeting, userID, errResp := loadAttendedMeeting(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+    if msg := agendaLocked(meeting); msg != "" {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": msg})
+    }
+    index, errResp := findAgendaItem(c, meeting)
+    if errResp != nil {
+        return errResp()
+    }
+
+    var input agendaInput
+    if err := c.BodyParser(&input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+    }
+
+    agenda := append([]models.AgendaItem{}, meeting.Agenda...)
+    item := agenda[index]
+    switch {
+    case meeting.CreatedBy == userID:
+    case item.Status == models.AgendaProposed && item.ProposedBy != nil && *item.ProposedBy == userID:
+        input.OwnerID = nil
+    case item.OwnerID != nil && *item.OwnerID == userID:
+        if input.Title != nil || input.OwnerID != nil || input.Minutes != nil {
+            return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Pemilik agenda hanya dapat mengubah catatan"})
+        }
+    default:
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya pembuat meeting yang dapat mengubah agenda"})
+    }
+
+    if err := applyAgendaInput(meeting, &item, input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+    }
+    agenda[index] = item
+    if err := checkAgendaFits(meeting, agenda); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+    }
+    if _, err := saveAgenda(ctx, meeting, agenda); err != nil {
+        return agendaSaveError(c, err)
+    }
+
+    return c.JSON(fiber.Map{
+        "message": "Agenda berhasil diupdate",
+        "item":    item,
+    })
+}
+
+// DeleteAgendaItem removes an item; proposers may withdraw their own
+// proposals
+func DeleteAgendaItem(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meeting, userID, errResp := loadAttendedMeeting(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+    if msg := agendaLocked(meeting); msg != "" {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": msg})
+    }
+    index, errResp := findAgendaItem(c, meeting)
+    if errResp != nil {
+        return errResp()
+    }
+
+    item := meeting.Agenda[index]
+    ownProposal := item.Status == models.AgendaProposed && item.ProposedBy != nil && *item.ProposedBy == userID
+    if meeting.CreatedBy != userID && !ownProposal {
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya pembuat meeting yang dapat menghapus agenda"})
+    }
+
+    agenda := append(append([]models.AgendaItem{}, meeting.Agenda[:index]...), meeting.Agenda[index+1:]...)
+    if _, err := saveAgenda(ctx, meeting, agenda); err != nil {
+        return agendaSaveError(c, err)
+    }
+
+    return c.JSON(fiber.Map{"message": "Agenda berhasil dihapus"})
+}
+
+// ReorderAgenda puts the agenda in the order of {"itemIds"}, which must
+// list every item exactly once
+func ReorderAgenda(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meeting, userID, errResp := loadAttendedMeeting(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+    if meeting.CreatedBy != userID {
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya pembuat meeting yang dapat mengubah urutan agenda"})
+    }
+    if msg := agendaLocked(meeting); msg != "" {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": msg})
+    }
+
+    var input struct {
+        ItemIDs []string `json:"itemIds"`
+    }
+    if err := c.BodyParser(&input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+    }
+    if len(input.ItemIDs) != len(meeting.Agenda) {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "itemIds harus memuat semua item agenda"})
+    }
+
+    items := map[string]models.AgendaItem{}
+    for _, item := range meeting.Agenda {
+        items[item.ID.Hex()] = item
+    }
+    agenda := make([]models.AgendaItem, 0, len(input.ItemIDs))
+    for _, id := range input.ItemIDs {
+        item, ok := items[id]
+        if !ok {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "itemIds harus memuat semua item agenda"})
+        }
+        delete(items, id)
+        agenda = append(agenda, item)
+    }
+    meeting, err := saveAgenda(ctx, meeting, agenda)
+    if err != nil {
+        return agendaSaveError(c, err)
+    }
+
+    return c.JSON(fiber.Map{
+        "message": "Urutan agenda berhasil diubah",
+        "agenda":  meeting.Agenda,
+    })
+}
+
+// CompleteAgendaItem marks an item done, or open again with {"done": false}.
+// The organizer and the item's owner may do this.
+func CompleteAgendaItem(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meeting, userID, errResp := loadAttendedMeeting(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+    if msg := agendaLocked(meeting); msg != "" {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": msg})
+    }
+    index, errResp := findAgendaItem(c, meeting)
+    if errResp != nil {
+        return errResp()
+    }
+
+    agenda := append([]models.AgendaItem{}, meeting.Agenda...)
+    item := agenda[index]
+    if meeting.CreatedBy != userID && (item.OwnerID == nil || *item.OwnerID != userID) {
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya pembuat meeting atau pemilik agenda yang dapat menyelesaikan agenda"})
+    }
+    if item.Status == models.AgendaProposed {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Usulan agenda belum diterima"})
+    }
+
+    input := struct {
+        Done *bool `json:"done"`
+    }{}
+    if len(c.Body()) > 0 {
+        if err := c.BodyParser(&input); err != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+        }
+    }
+    if input.Done == nil || *input.Done {
+        now := time.Now()
+        item.Status = models.AgendaDone
+        item.CompletedAt = &now
+    } else {
+        item.Status = models.AgendaOpen
+        item.CompletedAt = nil
+    }
+    agenda[index] = item
+    if _, err := saveAgenda(ctx, meeting, agenda); err != nil {
+        return agendaSaveError(c, err)
+    }
+
+    return c.JSON(fiber.Map{
+        "message": "Status agenda berhasil diubah",
+        "item":    item,
+    })
+}
+
+// AcceptAgendaItem adds a proposed item to the agenda (organizer only)
+func AcceptAgendaItem(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meeting, userID, errResp := loadAttendedMeeting(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+    if meeting.CreatedBy != userID {
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya pembuat meeting yang dapat menerima usulan agenda"})
+    }
+    if msg := agendaLocked(meeting); msg != "" {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": msg})
+    }
+    index, errResp := findAgendaItem(c, meeting)
+    if errResp != nil {
+        return errResp()
+    }
+
+    agenda := append([]models.AgendaItem{}, meeting.Agenda...)
+    if agenda[index].Status != models.AgendaProposed {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Agenda ini bukan usulan"})
+    }
+    agenda[index].Status = models.AgendaOpen
+    if err := checkAgendaFits(meeting, agenda); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+    }
+    if _, err := saveAgenda(ctx, meeting, agenda); err != nil {
+        return agendaSaveError(c, err)
+    }
+
+    return c.JSON(fiber.Map{
+        "message": "Usulan agenda diterima",
+        "item":    agenda[index],
+    })
+}
+
+// prepareAgenda validates the agenda sent with a new meeting; its items
+// start open
+func prepareAgenda(meeting *models.Meeting) error {
+    if len(meeting.Agenda) > maxAgendaItems {
+        return fmt.Errorf("Agenda maksimal %d item", maxAgendaItems)
+    }
+    now := time.Now()
+    for i, item := range meeting.Agenda {
+        item.ID = primitive.NewObjectID()
+        item.Status = models.AgendaOpen
+        item.ProposedBy = nil
+        item.CompletedAt = nil
+        item.CreatedAt = now
+        item.Title = strings.TrimSpace(item.Title)
+        if item.Title == "" || len(item.Title) > maxAgendaTitle {
+            return fmt.Errorf("Judul agenda diperlukan (maksimal %d karakter)", maxAgendaTitle)
+        }
+        if item.Minutes <= 0 {
+            return fmt.Errorf("Alokasi waktu agenda diperlukan")
+        }
+        if len(item.Notes) > maxAgendaNotes {
+            return fmt.Errorf("Catatan agenda maksimal %d karakter", maxAgendaNotes)
+        }
+        if item.OwnerID != nil && *item.OwnerID != meeting.CreatedBy && !isParticipant(*meeting, *item.OwnerID) {
+            return fmt.Errorf("Pemilik agenda harus peserta meeting")
+        }
+        meeting.Agenda[i] = item
+    }
+    return checkAgendaFits(*meeting, meeting.Agenda)
+}
+
+// applyAgendaInput validates the given fields and copies them to item
+func applyAgendaInput(meeting models.Meeting, item *models.AgendaItem, input agendaInput) error {
+    if input.Title != nil {
+        title := strings.TrimSpace(*input.Title)
+        if title == "" || len(title) > maxAgendaTitle {
+            return fmt.Errorf("Judul agenda diperlukan (maksimal %d karakter)", maxAgendaTitle)
+        }
+        item.Title = title
+    }
+    if input.Minutes != nil {
+        if *input.Minutes <= 0 {
+            return fmt.Errorf("Alokasi waktu agenda harus lebih dari 0 menit")
+        }
+        item.Minutes = *input.Minutes
+    }
+    if input.Notes != nil {
+        if len(*input.Notes) > maxAgendaNotes {
+            return fmt.Errorf("Catatan agenda maksimal %d karakter", maxAgendaNotes)
+        }
+        item.Notes = *input.Notes
+    }
+    if input.OwnerID != nil {
+        if *input.OwnerID == "" {
+            item.OwnerID = nil
+            return nil
+        }
+        ownerID, err := primitive.ObjectIDFromHex(*input.OwnerID)
+        if err != nil {
+            return fmt.Errorf("ownerId tidak valid")
+        }
+        if ownerID != meeting.CreatedBy && !isParticipant(meeting, ownerID) {
+            return fmt.Errorf("Pemilik agenda harus peserta meeting")
+        }
+        item.OwnerID = &ownerID
+    }
+    return nil
+}
+
+// agendaMinutes is the time taken by the items that are not mere proposals
+func agendaMinutes(agenda []models.AgendaItem) int {
+    total := 0
+    for _, item := range agenda {
+        if item.Status != models.AgendaProposed {
+            total += item.Minutes
+        }
+    }
+    return total
+}
+
+func checkAgendaFits(meeting models.Meeting, agenda []models.AgendaItem) error {
+    if total := agendaMinutes(agenda); total > meeting.Duration {
+        return fmt.Errorf("Total waktu agenda (%d menit) melebihi durasi meeting (%d menit)", total, meeting.Duration)
+    }
+    return nil
+}
+
+// agendaLocked explains why the agenda can no longer change, if it can't
+func agendaLocked(meeting models.Meeting) string {
+    if meeting.Status == models.MeetingCancelled {
+        return "Meeting sudah dibatalkan"
+    }
+    return ""
+}
+
+// acceptsProposals reports whether participants may still propose items:
+// until the meeting starts, or for as long as a recurring series runs
+func acceptsProposals(meeting models.Meeting, now time.Time) bool {
+    if meetingStatus(meeting, now) != models.MeetingScheduled {
+        return false
+    }
+    return meeting.Recurrence != nil || now.Before(meeting.StartTime)
+}
+
+// findAgendaItem returns the index of the item in :itemId. On failure it
+// returns a function writing the error response.
+func findAgendaItem(c *fiber.Ctx, meeting models.Meeting) (int, func() error) {
+    itemID, err := primitive.ObjectIDFromHex(c.Params("itemId"))
+    if err != nil {
+        return -1, func() error {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID agenda tidak valid"})
+        }
+    }
+    for i, item := range meeting.Agenda {
+        if item.ID == itemID {
+            return i, nil
+        }
+    }
+    return -1, func() error {
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Agenda tidak ditemukan"})
+    }
+}
+
+// saveAgenda stores the agenda unless someone else changed it since the
+// meeting was loaded, and pushes the change to everyone on the meeting
+func saveAgenda(ctx context.Context, meeting models.Meeting, agenda []models.AgendaItem) (models.Meeting, error) {
+    // Meetings created before agendas existed have no version yet
+    var version interface{} = meeting.AgendaVersion
+    if meeting.AgendaVersion == 0 {
+        version = bson.M{"$in": bson.A{0, nil}}
+    }
+    now := time.Now()
+    result, err := config.MeetingCollectionRef.UpdateOne(ctx,
+        bson.M{"_id": meeting.ID, "agendaVersion": version},
+        bson.M{
+            "$set": bson.M{"agenda": agenda, "updatedAt": now},
+            "$inc": bson.M{"agendaVersion": 1},
+        })
+    if err != nil {
+        return meeting, err
+    }
+    if result.MatchedCount == 0 {
+        return meeting, errAgendaChanged
+    }
+    meeting.Agenda = agenda
+    meeting.AgendaVersion++
+    meeting.UpdatedAt = now
+    publishMeetingEvent(realtime.EventMeetingUpdated, meeting)
+    return meeting, nil
+}
+
+func agendaSaveError(c *fiber.Ctx, err error) error {
+    if err == errAgendaChanged {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Agenda baru saja diubah, muat ulang dan coba lagi"})
+    }
+    return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan agenda"})
+}
+
+func agendaResponse(meeting models.Meeting) fiber.Map {
+    agenda := meeting.Agenda
+    if agenda == nil {
+        agenda = []models.AgendaItem{}
+    }
+    total := agendaMinutes(agenda)
+    return fiber.Map{
+        "agenda":           agenda,
+        "totalMinutes":     total,
+        "remainingMinutes": meeting.Duration - total,
+        "proposalsOpen":    meeting.AgendaProposals && acceptsProposals(meeting, time.Now()),
+    }
+}
+
+// notifyAgendaProposal tells the organizer about a proposed agenda item
+func notifyAgendaProposal(meeting models.Meeting, proposerID primitive.ObjectID, item models.AgendaItem) {
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        defer cancel()
+
+        users, err := usersByID(ctx, []primitive.ObjectID{meeting.CreatedBy, proposerID})
+        if err != nil {
+            log.Printf("Failed to load users for agenda proposal: %v", err)
+            return
+        }
+        organizer, ok := users[meeting.CreatedBy]
+        if !ok || organizer.Disabled {
+            return
+        }
+        n := newMeetingNotification(models.NotificationAgendaProposal, meeting, organizer,
+            notify.T(organizer.Language, "agenda.proposed", users[proposerID].Nama, item.Title, meeting.Title))
+        n.ActorID = &proposerID
+        if err := notify.SaveInApp(ctx, n); err != nil {
+            log.Printf("Failed to save agenda proposal notification: %v", err)
+        }
+    }()
+}
@@ -3,11 +3,13 @@ package controllers
 import (
     "context"
     "log"
+    "strings"
     "time"
 
     "github.com/gofiber/fiber/v2"
     "go.mongodb.org/mongo-driver/bson"
     "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo/options"
 
     "pbommo/config"
     "pbommo/models"
@@ -28,8 +30,10 @@ func Register(c *fiber.Ctx) error {
     log.Printf("Password length received: %d", len(user.Password))
 
     // Check if email already exists
+    user.Email = strings.ToLower(strings.TrimSpace(user.Email))
     var existingUser models.User
-    err := config.UserCollectionRef.FindOne(ctx, bson.M{"email": user.Email}).Decode(&existingUser)
+    err := config.UserCollectionRef.FindOne(ctx, bson.M{"email": user.Email},
+        options.FindOne().SetCollation(emailCollation)).Decode(&existingUser)
     if err == nil {
         return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Email sudah terdaftar"})
     }
@@ -51,10 +55,10 @@ func Register(c *fiber.Ctx) error {
         user.ID = primitive.NewObjectID()
     }
 
-    // Default role if not provided
-    if user.Role == "" {
-        user.Role = "User"
-    }
+    // Self-registered users are never admins, whatever the body says
+    user.Role = models.RoleUser
+    user.Disabled = false
+    user.ExternalID = ""
 
     _, err = config.UserCollectionRef.InsertOne(ctx, user)
     if err != nil {
@@ -93,7 +97,8 @@ func Login(c *fiber.Ctx) error {
     log.Printf("Password length received: %d", len(input.Password))
 
     var user models.User
-    err := config.UserCollectionRef.FindOne(ctx, bson.M{"email": input.Email}).Decode(&user)
+    err := config.UserCollectionRef.FindOne(ctx, bson.M{"email": strings.TrimSpace(input.Email)},
+        options.FindOne().SetCollation(emailCollation)).Decode(&user)
     if err != nil {
         log.Printf("User not found for email %s: %v", input.Email, err)
         return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Email atau password salah"})
@@ -107,6 +112,12 @@ func Login(c *fiber.Ctx) error {
         return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Email atau password salah"})
     }
     
+    // Only reveal that an account is disabled to someone who knows its password
+    if user.Disabled {
+        log.Printf("Login rejected for disabled user: %s", user.Email)
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Akun dinonaktifkan"})
+    }
+
     log.Printf("Login successful for user: %s", user.Email)
 
     // Generate JWT
@@ -127,4 +138,44 @@ func Login(c *fiber.Ctx) error {
             "profileImage": user.ProfileImage,
         },
     })
-}
\ No newline at end of file
+}
+
+// AcceptInvite mengatur password untuk user yang dibuat lewat import
+func AcceptInvite(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    var input struct {
+        Token    string `json:"token"`
+        Password string `json:"password"`
+    }
+
+    if err := c.BodyParser(&input); err != nil || input.Token == "" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+    }
+
+    if input.Password == "" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Password diperlukan"})
+    }
+
+    var user models.User
+    err := config.UserCollectionRef.FindOne(ctx, bson.M{"inviteToken": input.Token}).Decode(&user)
+    if err != nil || time.Now().After(user.InviteExpiresAt) {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Undangan tidak valid atau sudah kedaluwarsa"})
+    }
+
+    hashedPassword, err := utils.HashPassword(input.Password)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal hash password"})
+    }
+
+    _, err = config.UserCollectionRef.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+        "$set":   bson.M{"password": hashedPassword, "updatedAt": time.Now()},
+        "$unset": bson.M{"inviteToken": "", "inviteExpiresAt": ""},
+    })
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan password"})
+    }
+
+    return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Password berhasil diatur, silakan login"})
+}
@@ -0,0 +1,450 @@
+package controllers
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "sort"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/utils"
+)
+
+// Reasons of availability warnings
+const (
+    WarningOutsideWorkingHours = "outside_working_hours"
+    WarningOutOfOffice         = "out_of_office"
+    WarningHoliday             = "holiday"
+)
+
+// defaultWorkingHours applies to users who have not set their own:
+// Monday to Friday, 09:00-17:00
+var defaultWorkingHours = []models.WorkingDay{
+    {Weekday: 1, Start: "09:00", End: "17:00"},
+    {Weekday: 2, Start: "09:00", End: "17:00"},
+    {Weekday: 3, Start: "09:00", End: "17:00"},
+    {Weekday: 4, Start: "09:00", End: "17:00"},
+    {Weekday: 5, Start: "09:00", End: "17:00"},
+}
+
+// AvailabilityWarning tells the organizer that an invitee is not expected
+// to be available. Repeated hits of a series are reported once with the
+// first affected occurrence.
+type AvailabilityWarning struct {
+    UserID          primitive.ObjectID `json:"userId"`
+    Nama            string             `json:"nama"`
+    Reason          string             `json:"reason"`
+    Detail          string             `json:"detail,omitempty"`
+    OccurrenceStart time.Time          `json:"occurrenceStart"`
+    Occurrences     int                `json:"occurrences"`
+}
+
+// availability is a user's working time within a period
+type availability struct {
+    Location *time.Location
+    Holidays map[string]string // local date (YYYY-MM-DD) -> holiday name
+    Working  []timeRange       // working hours, excluding holidays
+}
+
+// availabilityInput is the body of UpdateAvailability
+type availabilityInput struct {
+    Timezone        *string              `json:"timezone"`
+    WorkingHours    *[]models.WorkingDay `json:"workingHours"`
+    HolidayCalendar *string              `json:"holidayCalendar"`
+}
+
+// GetAvailability returns the current user's timezone, working hours,
+// holiday calendar and absences
+func GetAvailability(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    var user models.User
+    if err := config.UserCollectionRef.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User tidak ditemukan"})
+    }
+
+    return c.JSON(availabilityResponse(user))
+}
+
+// UpdateAvailability replaces the fields present in the body. An empty
+// workingHours list restores the default working week.
+func UpdateAvailability(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    var input availabilityInput
+    if err := c.BodyParser(&input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+    }
+
+    set := bson.M{"updatedAt": time.Now()}
+    unset := bson.M{}
+    if input.Timezone != nil {
+        if *input.Timezone == "" {
+            unset["timezone"] = ""
+        } else if _, err := time.LoadLocation(*input.Timezone); err != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Timezone tidak valid"})
+        } else {
+            set["timezone"] = *input.Timezone
+        }
+    }
+    if input.WorkingHours != nil {
+        if len(*input.WorkingHours) == 0 {
+            unset["workingHours"] = ""
+        } else {
+            for _, d := range *input.WorkingHours {
+                if err := validateWorkingDay(d); err != nil {
+                    return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+                }
+            }
+            set["workingHours"] = *input.WorkingHours
+        }
+    }
+    if input.HolidayCalendar != nil {
+        if *input.HolidayCalendar == "" {
+            unset["holidayCalendar"] = ""
+        } else if !utils.HolidayCalendarExists(*input.HolidayCalendar) {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Kalender hari libur tidak ditemukan"})
+        } else {
+            set["holidayCalendar"] = *input.HolidayCalendar
+        }
+    }
+
+    update := bson.M{"$set": set}
+    if len(unset) > 0 {
+        update["$unset"] = unset
+    }
+    if _, err := config.UserCollectionRef.UpdateOne(ctx, bson.M{"_id": userID}, update); err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan jam kerja"})
+    }
+
+    var user models.User
+    if err := config.UserCollectionRef.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User tidak ditemukan"})
+    }
+
+    response := availabilityResponse(user)
+    response["message"] = "Jam kerja berhasil disimpan"
+    return c.JSON(response)
+}
+
+// AddAbsence records an out-of-office period for the current user
+func AddAbsence(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    var absence models.Absence
+    if err := c.BodyParser(&absence); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+    }
+    if absence.Start.IsZero() || absence.End.IsZero() || !absence.End.After(absence.Start) {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Waktu mulai dan selesai tidak valid"})
+    }
+    absence.ID = primitive.NewObjectID()
+    absence.CreatedAt = time.Now()
+
+    result, err := config.UserCollectionRef.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{
+        "$push": bson.M{"absences": absence},
+        "$set":  bson.M{"updatedAt": time.Now()},
+    })
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan jadwal cuti"})
+    }
+    if result.MatchedCount == 0 {
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User tidak ditemukan"})
+    }
+
+    return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+        "message": "Jadwal cuti berhasil disimpan",
+        "absence": absence,
+    })
+}
+
+// DeleteAbsence removes one of the current user's out-of-office periods
+func DeleteAbsence(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    absenceID, err := primitive.ObjectIDFromHex(c.Params("absenceId"))
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID cuti tidak valid"})
+    }
+
+    result, err := config.UserCollectionRef.UpdateOne(ctx,
+        bson.M{"_id": userID, "absences._id": absenceID},
+        bson.M{
+            "$pull": bson.M{"absences": bson.M{"_id": absenceID}},
+            "$set":  bson.M{"updatedAt": time.Now()},
+        })
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus jadwal cuti"})
+    }
+    if result.MatchedCount == 0 {
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Jadwal cuti tidak ditemukan"})
+    }
+
+    return c.JSON(fiber.Map{"message": "Jadwal cuti berhasil dihapus"})
+}
+
+// GetHolidayCalendars lists the holiday calendars users can choose from,
+// with the holidays of the requested year (?year=, default current year)
+func GetHolidayCalendars(c *fiber.Ctx) error {
+    names, err := utils.ListHolidayCalendars()
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membaca kalender hari libur"})
+    }
+
+    year := c.QueryInt("year", time.Now().Year())
+    from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+    to := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+    calendars := []fiber.Map{}
+    for _, name := range names {
+        holidays, err := utils.HolidaysBetween(name, from, to)
+        if err != nil {
+            log.Printf("Failed to load holiday calendar %s: %v", name, err)
+            continue
+        }
+        calendars = append(calendars, fiber.Map{"name": name, "holidays": holidays})
+    }
+
+    return c.JSON(fiber.Map{"year": year, "calendars": calendars})
+}
+
+func availabilityResponse(user models.User) fiber.Map {
+    workingHours := user.WorkingHours
+    if len(workingHours) == 0 {
+        workingHours = defaultWorkingHours
+    }
+    absences := user.Absences
+    if absences == nil {
+        absences = []models.Absence{}
+    }
+    return fiber.Map{
+        "timezone":            userLocation(user).String(),
+        "workingHours":        workingHours,
+        "defaultWorkingHours": len(user.WorkingHours) == 0,
+        "holidayCalendar":     user.HolidayCalendar,
+        "absences":            absences,
+    }
+}
+
+func validateWorkingDay(d models.WorkingDay) error {
+    if d.Weekday < 0 || d.Weekday > 6 {
+        return fmt.Errorf("Hari kerja harus 0 (Minggu) sampai 6 (Sabtu)")
+    }
+    start, err := parseClock(d.Start)
+    if err != nil {
+        return fmt.Errorf("Jam mulai tidak valid: %s", d.Start)
+    }
+    end, err := parseClock(d.End)
+    if err != nil {
+        return fmt.Errorf("Jam selesai tidak valid: %s", d.End)
+    }
+    if end <= start {
+        return fmt.Errorf("Jam selesai harus setelah jam mulai")
+    }
+    return nil
+}
+
+// parseClock parses "HH:MM" into an offset from midnight; "24:00" ends the day
+func parseClock(s string) (time.Duration, error) {
+    var h, m int
+    if n, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil || n != 2 || len(s) != 5 {
+        return 0, fmt.Errorf("invalid time %q", s)
+    }
+    if h < 0 || m < 0 || m > 59 || h > 24 || h == 24 && m != 0 {
+        return 0, fmt.Errorf("invalid time %q", s)
+    }
+    return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// loadAvailability evaluates the user's working hours and holiday calendar
+// in their own timezone for [from, to). A holiday calendar that cannot be
+// read is logged and ignored.
+func loadAvailability(user models.User, from, to time.Time) availability {
+    loc := userLocation(user)
+    a := availability{Location: loc, Holidays: map[string]string{}, Working: []timeRange{}}
+
+    if user.HolidayCalendar != "" {
+        holidays, err := utils.HolidaysBetween(user.HolidayCalendar, from.In(loc), to.In(loc))
+        if err != nil {
+            log.Printf("Failed to load holiday calendar %s: %v", user.HolidayCalendar, err)
+        }
+        for _, h := range holidays {
+            a.Holidays[h.Date] = h.Name
+        }
+    }
+
+    workingHours := user.WorkingHours
+    if len(workingHours) == 0 {
+        workingHours = defaultWorkingHours
+    }
+
+    local := from.In(loc)
+    day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+    for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+        if _, ok := a.Holidays[day.Format("2006-01-02")]; ok {
+            continue
+        }
+        for _, d := range workingHours {
+            if time.Weekday(d.Weekday) != day.Weekday() {
+                continue
+            }
+            start, err1 := parseClock(d.Start)
+            end, err2 := parseClock(d.End)
+            if err1 != nil || err2 != nil {
+                continue
+            }
+            r := timeRange{Start: atClock(day, start), End: atClock(day, end)}
+            if r.Start.Before(from) {
+                r.Start = from
+            }
+            if r.End.After(to) {
+                r.End = to
+            }
+            if r.Start.Before(r.End) {
+                a.Working = append(a.Working, r)
+            }
+        }
+    }
+    sort.Slice(a.Working, func(i, j int) bool { return a.Working[i].Start.Before(a.Working[j].Start) })
+    a.Working = mergeRanges(a.Working)
+    return a
+}
+
+// availabilityWarnings checks every invitee of the meeting (or its upcoming
+// occurrences) against their absences, holidays and working hours
+func availabilityWarnings(ctx context.Context, meeting models.Meeting) ([]AvailabilityWarning, error) {
+    warnings := []AvailabilityWarning{}
+    if len(meeting.Participants) == 0 {
+        return warnings, nil
+    }
+
+    starts, err := checkedOccurrences(meeting)
+    if err != nil || len(starts) == 0 {
+        return warnings, err
+    }
+    length := time.Duration(meeting.Duration) * time.Minute
+
+    cursor, err := config.UserCollectionRef.Find(ctx, bson.M{"_id": bson.M{"$in": meeting.Participants}})
+    if err != nil {
+        return nil, err
+    }
+    var users []models.User
+    if err := cursor.All(ctx, &users); err != nil {
+        return nil, err
+    }
+
+    // Pad the period by a day so working hours of neighbouring local days
+    // are known in every timezone
+    from := starts[0].Add(-24 * time.Hour)
+    to := starts[len(starts)-1].Add(length + 24*time.Hour)
+
+    for _, user := range users {
+        a := loadAvailability(user, from, to)
+        found := map[string]int{} // reason -> index in warnings
+
+        for _, start := range starts {
+            slot := timeRange{Start: start, End: start.Add(length)}
+            reason, detail := a.unavailableReason(user, slot)
+            if reason == "" {
+                continue
+            }
+            if i, ok := found[reason]; ok {
+                warnings[i].Occurrences++
+                continue
+            }
+            found[reason] = len(warnings)
+            warnings = append(warnings, AvailabilityWarning{
+                UserID:          user.ID,
+                Nama:            user.Nama,
+                Reason:          reason,
+                Detail:          detail,
+                OccurrenceStart: start,
+                Occurrences:     1,
+            })
+        }
+    }
+    return warnings, nil
+}
+
+// unavailableReason explains why the user is not expected to attend the
+// slot, or returns an empty reason
+func (a availability) unavailableReason(user models.User, slot timeRange) (string, string) {
+    for _, absence := range user.Absences {
+        if (timeRange{Start: absence.Start, End: absence.End}).overlaps(slot) {
+            return WarningOutOfOffice, absence.Reason
+        }
+    }
+    if name, ok := a.holidayDuring(slot); ok {
+        return WarningHoliday, name
+    }
+    for _, w := range a.Working {
+        if w.contains(slot) {
+            return "", ""
+        }
+    }
+    return WarningOutsideWorkingHours, slot.Start.In(a.Location).Format("Mon 15:04 MST")
+}
+
+// holidayDuring returns the first holiday on any local date the slot
+// covers, so a meeting running past midnight into a holiday is caught
+func (a availability) holidayDuring(slot timeRange) (string, bool) {
+    first := slot.Start.In(a.Location)
+    last := first
+    if slot.End.After(slot.Start) {
+        // The end is exclusive: a slot ending at midnight stays on its day
+        last = slot.End.Add(-time.Nanosecond).In(a.Location)
+    }
+    day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, a.Location)
+    for ; !day.After(last); day = day.AddDate(0, 0, 1) {
+        if name, ok := a.Holidays[day.Format("2006-01-02")]; ok {
+            return name, true
+        }
+    }
+    return "", false
+}
+
+// atClock returns the wall-clock time offset into the day, so DST changes
+// do not shift working hours
+func atClock(day time.Time, offset time.Duration) time.Time {
+    return time.Date(day.Year(), day.Month(), day.Day(), 0, int(offset.Minutes()), 0, 0, day.Location())
+}
+
+// userLocation returns the user's timezone, falling back to DEFAULT_TIMEZONE
+func userLocation(user models.User) *time.Location {
+    if user.Timezone != "" {
+        if loc, err := time.LoadLocation(user.Timezone); err == nil {
+            return loc
+        }
+    }
+    return config.DefaultTimezone()
+}
@@ -0,0 +1,275 @@
+package controllers
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/utils"
+)
+
+// ExportMeetingICS returns a meeting as an iCalendar file. Recurring
+// meetings are exported with their RRULE, exceptions and overrides.
+func ExportMeetingICS(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meetingID, err := primitive.ObjectIDFromHex(c.Params("id"))
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID meeting tidak valid"})
+    }
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    var meeting models.Meeting
+    err = config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": meetingID, "deletedAt": bson.M{"$exists": false}}).Decode(&meeting)
+    if err != nil {
+        if err == mongo.ErrNoDocuments {
+            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Meeting tidak ditemukan"})
+        }
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data meeting"})
+    }
+
+    if !canViewMeeting(meeting, userID) {
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Akses ditolak"})
+    }
+
+    // Export an overridden occurrence as part of its series
+    seriesID := meeting.ID
+    if meeting.SeriesID != nil {
+        seriesID = *meeting.SeriesID
+    }
+    cursor, err := config.MeetingCollectionRef.Find(ctx, bson.M{
+        "$or":       []bson.M{{"_id": seriesID}, {"seriesId": seriesID}},
+        "deletedAt": bson.M{"$exists": false},
+    })
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data meeting"})
+    }
+    var meetings []models.Meeting
+    if err := cursor.All(ctx, &meetings); err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memproses data meeting"})
+    }
+
+    events, err := meetingsToICalEvents(ctx, meetings)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat file kalender"})
+    }
+
+    c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+    c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="meeting-%s.ics"`, seriesID.Hex()))
+    return c.SendString(utils.BuildICalendar("PUBLISH", events))
+}
+
+// GetCalendarFeed returns the user's secret calendar subscription URL,
+// creating the token on first use
+func GetCalendarFeed(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    var user models.User
+    if err := config.UserCollectionRef.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User tidak ditemukan"})
+    }
+
+    token := user.CalendarToken
+    if token == "" {
+        token, err = setCalendarToken(ctx, userID)
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat token kalender"})
+        }
+    }
+
+    return c.JSON(fiber.Map{"url": calendarFeedURL(c, token)})
+}
+
+// ResetCalendarFeed rotates the subscription token, invalidating the old URL
+func ResetCalendarFeed(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    token, err := setCalendarToken(ctx, userID)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat token kalender"})
+    }
+
+    return c.JSON(fiber.Map{
+        "message": "URL kalender berhasil diganti",
+        "url":     calendarFeedURL(c, token),
+    })
+}
+
+// CalendarFeed serves the iCalendar feed of every meeting the token owner
+// creates or participates in. Deleted meetings drop out of the feed and
+// cancelled occurrences are published as EXDATEs.
+func CalendarFeed(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    token := c.Params("token")
+    if token == "" {
+        return c.Status(fiber.StatusNotFound).SendString("Not found")
+    }
+
+    var user models.User
+    err := config.UserCollectionRef.FindOne(ctx, bson.M{"calendarToken": token}).Decode(&user)
+    if err != nil || user.Disabled {
+        return c.Status(fiber.StatusNotFound).SendString("Not found")
+    }
+
+    cursor, err := config.MeetingCollectionRef.Find(ctx, bson.M{
+        "$or": []bson.M{
+            {"createdBy": user.ID},
+            {"participants": user.ID},
+        },
+        "deletedAt": bson.M{"$exists": false},
+    })
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).SendString("Failed to load meetings")
+    }
+    var meetings []models.Meeting
+    if err := cursor.All(ctx, &meetings); err != nil {
+        return c.Status(fiber.StatusInternalServerError).SendString("Failed to load meetings")
+    }
+
+    events, err := meetingsToICalEvents(ctx, meetings)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).SendString("Failed to build calendar")
+    }
+
+    c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+    return c.SendString(utils.BuildICalendar("", events))
+}
+
+// meetingsToICalEvents converts meetings to VEVENTs, resolving organizer and
+// participant emails in a single query
+func meetingsToICalEvents(ctx context.Context, meetings []models.Meeting) ([]utils.ICalEvent, error) {
+    ids := []primitive.ObjectID{}
+    for _, m := range meetings {
+        ids = append(ids, m.CreatedBy)
+        ids = append(ids, m.Participants...)
+    }
+    emails, err := userEmails(ctx, ids)
+    if err != nil {
+        return nil, err
+    }
+
+    events := make([]utils.ICalEvent, 0, len(meetings))
+    for _, m := range meetings {
+        uid := m.ID
+        if m.SeriesID != nil {
+            uid = *m.SeriesID
+        }
+        event := utils.ICalEvent{
+            UID:          uid.Hex() + "@pbommo",
+            Sequence:     m.Sequence,
+            Start:        m.StartTime,
+            End:          m.StartTime.Add(time.Duration(m.Duration) * time.Minute),
+            Summary:      m.Title,
+            Description:  m.Description,
+            Organizer:    emails[m.CreatedBy],
+            RecurrenceID: m.RecurrenceID,
+            Created:      m.CreatedAt,
+            LastModified: m.UpdatedAt,
+            URL:          utils.FrontendURL() + "/meetings",
+        }
+        if m.Status == models.MeetingCancelled {
+            event.Status = "CANCELLED"
+        }
+        for _, p := range m.Participants {
+            if email := emails[p]; email != "" {
+                event.Attendees = append(event.Attendees, email)
+            }
+        }
+        for _, g := range m.Guests {
+            event.Attendees = append(event.Attendees, g.Email)
+        }
+        if m.Recurrence != nil {
+            event.RRule = m.Recurrence.RRule
+            event.Timezone = m.Recurrence.Timezone
+            event.ExDates = m.Recurrence.ExDates
+        }
+        events = append(events, event)
+    }
+    return events, nil
+}
+
+// userEmails maps user ids to their email addresses
+func userEmails(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]string, error) {
+    emails := map[primitive.ObjectID]string{}
+    if len(ids) == 0 {
+        return emails, nil
+    }
+    cursor, err := config.UserCollectionRef.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+    if err != nil {
+        return nil, err
+    }
+    var users []models.User
+    if err := cursor.All(ctx, &users); err != nil {
+        return nil, err
+    }
+    for _, u := range users {
+        emails[u.ID] = u.Email
+    }
+    return emails, nil
+}
+
+// canViewMeeting reports whether the user created or participates in the meeting
+func canViewMeeting(meeting models.Meeting, userID primitive.ObjectID) bool {
+    if meeting.CreatedBy == userID {
+        return true
+    }
+    for _, participant := range meeting.Participants {
+        if participant == userID {
+            return true
+        }
+    }
+    return false
+}
+
+// EnsureCalendarTokenIndex makes feed tokens unique, so a token always
+// resolves to the one user it was generated for
+func EnsureCalendarTokenIndex(ctx context.Context) error {
+    _, err := config.UserCollectionRef.Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys: bson.D{{Key: "calendarToken", Value: 1}},
+        Options: options.Index().SetUnique(true).
+            SetPartialFilterExpression(bson.M{"calendarToken": bson.M{"$type": "string"}}),
+    })
+    return err
+}
+
+// setCalendarToken stores a fresh feed token; tokens are only ever set here
+func setCalendarToken(ctx context.Context, userID primitive.ObjectID) (string, error) {
+    token, err := utils.GenerateRandomToken(24)
+    if err != nil {
+        return "", err
+    }
+    _, err = config.UserCollectionRef.UpdateOne(ctx, bson.M{"_id": userID},
+        bson.M{"$set": bson.M{"calendarToken": token, "updatedAt": time.Now()}})
+    return token, err
+}
+
+func calendarFeedURL(c *fiber.Ctx, token string) string {
+    return c.BaseURL() + "/calendar/" + token + "/meetings.ics"
+}
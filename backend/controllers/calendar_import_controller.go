@@ -0,0 +1,376 @@
+package controllers
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/utils"
+)
+
+const (
+    maxICalImportSize   = 2 * 1024 * 1024
+    maxICalImportEvents = 500
+)
+
+// ICalImportResult describes the outcome of importing a single VEVENT
+type ICalImportResult struct {
+    UID              string              `json:"uid"`
+    Title            string              `json:"title"`
+    RecurrenceID     *time.Time          `json:"recurrenceId,omitempty"`
+    Status           string              `json:"status"` // created, updated, cancelled, skipped, error
+    MeetingID        *primitive.ObjectID `json:"meetingId,omitempty"`
+    UnknownAttendees []string            `json:"unknownAttendees,omitempty"`
+    Error            string              `json:"error,omitempty"`
+}
+
+// ImportMeetingsICS creates meetings from the VEVENTs of an uploaded .ics
+// file. Attendees are matched to users by email; re-importing the same
+// file updates the meetings previously created from each UID.
+func ImportMeetingsICS(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    fileHeader, err := c.FormFile("file")
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "File .ics diperlukan"})
+    }
+    if fileHeader.Size > maxICalImportSize {
+        return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": "Ukuran file maksimal 2 MB"})
+    }
+
+    file, err := fileHeader.Open()
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuka file"})
+    }
+    defer file.Close()
+
+    calendar, err := utils.ParseICalendar(file)
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "File iCalendar tidak valid: " + err.Error()})
+    }
+
+    var events []*utils.ICalComponent
+    for _, comp := range calendar.Components {
+        if comp.Name == "VEVENT" {
+            events = append(events, comp)
+        }
+    }
+    if len(events) == 0 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "File tidak berisi VEVENT"})
+    }
+    if len(events) > maxICalImportEvents {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Maksimal %d event per file", maxICalImportEvents)})
+    }
+
+    // Series masters go first so their overrides can be attached
+    sort.SliceStable(events, func(i, j int) bool {
+        return events[i].Get("RECURRENCE-ID") == nil && events[j].Get("RECURRENCE-ID") != nil
+    })
+
+    usersByEmail, err := icalAttendeeUsers(ctx, events)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mencocokkan peserta"})
+    }
+
+    results := make([]ICalImportResult, 0, len(events))
+    unknown := map[string]bool{}
+    counts := map[string]int{}
+    for _, event := range events {
+        result := importICalEvent(ctx, userID, event, usersByEmail)
+        for _, email := range result.UnknownAttendees {
+            unknown[email] = true
+        }
+        counts[result.Status]++
+        results = append(results, result)
+    }
+
+    unknownList := make([]string, 0, len(unknown))
+    for email := range unknown {
+        unknownList = append(unknownList, email)
+    }
+    sort.Strings(unknownList)
+
+    return c.JSON(fiber.Map{
+        "message":          "Import selesai",
+        "created":          counts["created"],
+        "updated":          counts["updated"],
+        "cancelled":        counts["cancelled"],
+        "skipped":          counts["skipped"],
+        "failed":           counts["error"],
+        "unknownAttendees": unknownList,
+        "events":           results,
+    })
+}
+
+// importICalEvent creates or updates the meeting for a single VEVENT
+func importICalEvent(ctx context.Context, userID primitive.ObjectID, event *utils.ICalComponent, usersByEmail map[string]primitive.ObjectID) ICalImportResult {
+    result := ICalImportResult{
+        UID:   event.Text("UID"),
+        Title: event.Text("SUMMARY"),
+    }
+    fail := func(msg string) ICalImportResult {
+        result.Status = "error"
+        result.Error = msg
+        return result
+    }
+
+    if result.UID == "" {
+        return fail("UID tidak ada")
+    }
+    if result.Title == "" {
+        result.Title = "(Tanpa judul)"
+    }
+
+    var recurrenceID *time.Time
+    if p := event.Get("RECURRENCE-ID"); p != nil {
+        t, _, err := utils.ParseICalDateTime(*p)
+        if err != nil {
+            return fail("RECURRENCE-ID tidak valid")
+        }
+        t = t.UTC()
+        recurrenceID = &t
+        result.RecurrenceID = recurrenceID
+    }
+
+    // Look up what a previous import created from this UID. Meetings the
+    // organizer moved to the trash stay there.
+    var series models.Meeting
+    seriesErr := config.MeetingCollectionRef.FindOne(ctx, bson.M{
+        "createdBy":    userID,
+        "icalUid":      result.UID,
+        "recurrenceId": bson.M{"$exists": false},
+    }, options.FindOne().SetSort(bson.M{"deletedAt": 1})).Decode(&series)
+    if seriesErr != nil && seriesErr != mongo.ErrNoDocuments {
+        return fail("Gagal mengambil data meeting")
+    }
+    if seriesErr == nil && series.DeletedAt != nil {
+        return skipTrashed(result)
+    }
+    if recurrenceID != nil && (seriesErr != nil || series.Recurrence == nil) {
+        return fail("Series untuk RECURRENCE-ID tidak ditemukan")
+    }
+
+    var existing *models.Meeting
+    if recurrenceID == nil && seriesErr == nil {
+        existing = &series
+    } else if recurrenceID != nil {
+        var override models.Meeting
+        err := config.MeetingCollectionRef.FindOne(ctx, bson.M{"seriesId": series.ID, "recurrenceId": *recurrenceID}).Decode(&override)
+        if err == nil && override.DeletedAt != nil {
+            return skipTrashed(result)
+        } else if err == nil {
+            existing = &override
+        } else if err != mongo.ErrNoDocuments {
+            return fail("Gagal mengambil data meeting")
+        }
+    }
+
+    // Cancelled events move what was imported before to the trash
+    if strings.EqualFold(event.Text("STATUS"), "CANCELLED") {
+        var err error
+        switch {
+        case recurrenceID != nil:
+            err = excludeOccurrence(ctx, series.ID, *recurrenceID)
+        case existing != nil:
+            if err = trashMeeting(ctx, *existing, time.Now()); err == mongo.ErrNoDocuments {
+                err = nil
+            }
+        default:
+            result.Status = "skipped"
+            return result
+        }
+        if err != nil {
+            return fail("Gagal membatalkan meeting")
+        }
+        result.Status = "cancelled"
+        return result
+    }
+
+    dtstart := event.Get("DTSTART")
+    if dtstart == nil {
+        return fail("DTSTART tidak ada")
+    }
+    start, allDay, err := utils.ParseICalDateTime(*dtstart)
+    if err != nil {
+        return fail(err.Error())
+    }
+
+    duration := time.Hour
+    if allDay {
+        duration = 24 * time.Hour
+    }
+    if p := event.Get("DTEND"); p != nil {
+        end, _, err := utils.ParseICalDateTime(*p)
+        if err != nil {
+            return fail(err.Error())
+        }
+        duration = end.Sub(start)
+    } else if p := event.Get("DURATION"); p != nil {
+        d, err := utils.ParseICalDuration(p.Value)
+        if err != nil {
+            return fail(err.Error())
+        }
+        duration = d
+    }
+    if duration <= 0 {
+        return fail("Durasi event tidak valid")
+    }
+
+    meeting := models.Meeting{
+        Title:        result.Title,
+        Description:  event.Text("DESCRIPTION"),
+        StartTime:    start.UTC(),
+        Duration:     int(duration.Minutes()),
+        CreatedBy:    userID,
+        Participants: []primitive.ObjectID{},
+        ICalUID:      result.UID,
+    }
+    if meeting.Duration == 0 {
+        meeting.Duration = 1
+    }
+
+    // Attendees map to registered users; the rest are reported
+    seen := map[primitive.ObjectID]bool{userID: true}
+    for _, p := range event.GetAll("ATTENDEE") {
+        email := icalMailto(p.Value)
+        if email == "" {
+            continue
+        }
+        id, ok := usersByEmail[email]
+        if !ok {
+            result.UnknownAttendees = append(result.UnknownAttendees, email)
+            continue
+        }
+        if !seen[id] {
+            seen[id] = true
+            meeting.Participants = append(meeting.Participants, id)
+        }
+    }
+
+    if p := event.Get("RRULE"); p != nil && recurrenceID == nil {
+        meeting.Recurrence = &models.Recurrence{RRule: p.Value}
+        if tzid := dtstart.Params["TZID"]; tzid != "" {
+            if _, err := time.LoadLocation(tzid); err == nil {
+                meeting.Recurrence.Timezone = tzid
+            }
+        }
+        for _, ex := range event.GetAll("EXDATE") {
+            for _, value := range strings.Split(ex.Value, ",") {
+                exProp := utils.ICalProperty{Name: ex.Name, Params: ex.Params, Value: value}
+                if t, _, err := utils.ParseICalDateTime(exProp); err == nil {
+                    meeting.Recurrence.ExDates = append(meeting.Recurrence.ExDates, t.UTC())
+                }
+            }
+        }
+        if _, _, err := parseRecurrence(meeting.Recurrence); err != nil {
+            return fail(err.Error())
+        }
+    }
+
+    now := time.Now()
+    if existing != nil {
+        // Only what the file describes is overwritten; guests, responses,
+        // agenda and status are kept
+        set := bson.M{
+            "title":        meeting.Title,
+            "description":  meeting.Description,
+            "startTime":    meeting.StartTime,
+            "duration":     meeting.Duration,
+            "participants": meeting.Participants,
+            "responses":    pruneResponses(existing.Responses, meeting.Participants, existing.Guests),
+            "updatedAt":    now,
+        }
+        update := bson.M{"$set": set, "$inc": bson.M{"sequence": 1}}
+        if meeting.Recurrence != nil {
+            set["recurrence"] = meeting.Recurrence
+        } else if existing.Recurrence != nil {
+            update["$unset"] = bson.M{"recurrence": ""}
+            if err := trashOccurrences(ctx, bson.M{"seriesId": existing.ID}, now); err != nil {
+                return fail("Gagal mengupdate meeting")
+            }
+        }
+        if _, err := config.MeetingCollectionRef.UpdateOne(ctx, bson.M{"_id": existing.ID}, update); err != nil {
+            return fail("Gagal mengupdate meeting")
+        }
+        result.Status = "updated"
+        result.MeetingID = &existing.ID
+        return result
+    }
+
+    meeting.ID = primitive.NewObjectID()
+    meeting.CreatedAt = now
+    meeting.UpdatedAt = now
+    if recurrenceID != nil {
+        seriesID := series.ID
+        meeting.SeriesID = &seriesID
+        meeting.RecurrenceID = recurrenceID
+    }
+    if _, err := config.MeetingCollectionRef.InsertOne(ctx, meeting); err != nil {
+        return fail("Gagal membuat meeting")
+    }
+    result.Status = "created"
+    result.MeetingID = &meeting.ID
+    return result
+}
+
+// skipTrashed reports an event whose meeting is in the organizer's trash
+func skipTrashed(result ICalImportResult) ICalImportResult {
+    result.Status = "skipped"
+    result.Error = "Meeting ada di tempat sampah, pulihkan terlebih dahulu"
+    return result
+}
+
+// icalAttendeeUsers resolves every attendee email in the file in one query
+func icalAttendeeUsers(ctx context.Context, events []*utils.ICalComponent) (map[string]primitive.ObjectID, error) {
+    var emails []string
+    for _, event := range events {
+        for _, p := range event.GetAll("ATTENDEE") {
+            if email := icalMailto(p.Value); email != "" {
+                emails = append(emails, email)
+            }
+        }
+    }
+
+    users := map[string]primitive.ObjectID{}
+    if len(emails) == 0 {
+        return users, nil
+    }
+    cursor, err := config.UserCollectionRef.Find(ctx, bson.M{"email": bson.M{"$in": emails}},
+        options.Find().SetCollation(emailCollation))
+    if err != nil {
+        return nil, err
+    }
+    var found []models.User
+    if err := cursor.All(ctx, &found); err != nil {
+        return nil, err
+    }
+    for _, u := range found {
+        users[strings.ToLower(u.Email)] = u.ID
+    }
+    return users, nil
+}
+
+// icalMailto extracts the lower-cased address from a "mailto:" CAL-ADDRESS
+func icalMailto(value string) string {
+    value = strings.TrimSpace(value)
+    if len(value) >= 7 && strings.EqualFold(value[:7], "mailto:") {
+        value = value[7:]
+    }
+    return strings.ToLower(value)
+}
@@ -0,0 +1,273 @@
+package controllers
+
+import (
+    "context"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/utils"
+)
+
+const (
+    // maxMeetingLength bounds the length of suggested time slots
+    maxMeetingLength = 24 * time.Hour
+    // conflictHorizon bounds how many occurrences of a new series are checked
+    conflictHorizon        = 90 * 24 * time.Hour
+    maxConflictOccurrences = 100
+)
+
+// Conflict is one overlap between the checked meeting and an existing one
+type Conflict struct {
+    UserID          primitive.ObjectID `json:"userId"`
+    Nama            string             `json:"nama"`
+    MeetingID       primitive.ObjectID `json:"meetingId"`
+    Title           string             `json:"title"`
+    StartTime       time.Time          `json:"startTime"`
+    EndTime         time.Time          `json:"endTime"`
+    OccurrenceStart time.Time          `json:"occurrenceStart"` // start of the checked (occurrence of the) meeting
+}
+
+// CheckConflicts reports scheduling conflicts and availability warnings for
+// a meeting without saving it, so the form can warn before submitting
+func CheckConflicts(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    var input meetingInput
+    if err := c.BodyParser(&input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+    }
+    meeting := input.Meeting
+    meeting.CreatedBy = userID
+    if meeting.StartTime.IsZero() {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Waktu mulai meeting diperlukan"})
+    }
+    if meeting.Duration <= 0 {
+        meeting.Duration = 60
+    }
+    if meeting.Recurrence != nil {
+        if _, _, err := parseRecurrence(meeting.Recurrence); err != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+        }
+    }
+
+    participants, err := resolveParticipants(ctx, input.Participants, userID)
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+    }
+    meeting.Participants = participants.UserIDs
+
+    conflicts, err := findConflicts(ctx, meeting)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa jadwal"})
+    }
+
+    warnings, err := availabilityWarnings(ctx, meeting)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa jadwal"})
+    }
+
+    return c.JSON(fiber.Map{
+        "conflicts": conflicts,
+        "warnings":  warnings,
+        "policy":    config.ConflictPolicy(),
+    })
+}
+
+// conflictResponse applies the configured policy. When the request must be
+// rejected with 409 it returns a function writing that response.
+func conflictResponse(c *fiber.Ctx, conflicts []Conflict, force bool) func() error {
+    if len(conflicts) == 0 {
+        return nil
+    }
+    switch config.ConflictPolicy() {
+    case config.ConflictStrict:
+    case config.ConflictReject:
+        if force {
+            return nil
+        }
+    default:
+        return nil
+    }
+    return func() error {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+            "error":     "Jadwal bentrok dengan meeting lain",
+            "conflicts": conflicts,
+            "canForce":  config.ConflictPolicy() == config.ConflictReject,
+        })
+    }
+}
+
+// findConflicts returns the existing meetings overlapping the meeting (or,
+// for a series, its upcoming occurrences) for the organizer and every
+// participant. Meetings a user declined do not count as busy.
+func findConflicts(ctx context.Context, meeting models.Meeting) ([]Conflict, error) {
+    conflicts := []Conflict{}
+    if config.ConflictPolicy() == config.ConflictOff {
+        return conflicts, nil
+    }
+
+    length := time.Duration(meeting.Duration) * time.Minute
+    starts, err := checkedOccurrences(meeting)
+    if err != nil {
+        return nil, err
+    }
+    if len(starts) == 0 {
+        return conflicts, nil
+    }
+    from := starts[0]
+    to := starts[len(starts)-1].Add(length)
+
+    users := append([]primitive.ObjectID{meeting.CreatedBy}, meeting.Participants...)
+    existing, err := busyMeetings(ctx, users, from, to)
+    if err != nil {
+        return nil, err
+    }
+
+    // The meeting itself, its series and its overrides never conflict
+    self := map[primitive.ObjectID]bool{meeting.ID: true}
+    if meeting.SeriesID != nil {
+        self[*meeting.SeriesID] = true
+    }
+
+    names, err := userNames(ctx, users)
+    if err != nil {
+        return nil, err
+    }
+
+    for _, start := range starts {
+        end := start.Add(length)
+        for _, other := range existing {
+            if self[other.ID] || other.SeriesID != nil && self[*other.SeriesID] {
+                continue
+            }
+            otherEnd := other.StartTime.Add(time.Duration(other.Duration) * time.Minute)
+            if !other.StartTime.Before(end) || !otherEnd.After(start) {
+                continue
+            }
+            for _, u := range users {
+                if !isBusyIn(other, u) {
+                    continue
+                }
+                conflicts = append(conflicts, Conflict{
+                    UserID:          u,
+                    Nama:            names[u],
+                    MeetingID:       other.ID,
+                    Title:           other.Title,
+                    StartTime:       other.StartTime,
+                    EndTime:         otherEnd,
+                    OccurrenceStart: start,
+                })
+            }
+        }
+    }
+    return conflicts, nil
+}
+
+// checkedOccurrences returns the start of a single meeting, or the upcoming
+// occurrences of a series within the conflict horizon
+func checkedOccurrences(meeting models.Meeting) ([]time.Time, error) {
+    if meeting.Recurrence == nil {
+        return []time.Time{meeting.StartTime}, nil
+    }
+    rule, loc, err := parseRecurrence(meeting.Recurrence)
+    if err != nil {
+        return nil, err
+    }
+    starts := rule.Between(meeting.StartTime, loc, meeting.StartTime, meeting.StartTime.Add(conflictHorizon), meeting.Recurrence.ExDates)
+    if len(starts) > maxConflictOccurrences {
+        starts = starts[:maxConflictOccurrences]
+    }
+    return starts, nil
+}
+
+// busyMeetings loads the meetings (with recurring series expanded) of the
+// given users that overlap [from, to), however long they are
+func busyMeetings(ctx context.Context, users []primitive.ObjectID, from, to time.Time) ([]models.Meeting, error) {
+    // Single meetings match on their end, startTime + duration minutes
+    endsAfterFrom := bson.M{"$gt": bson.A{
+        bson.M{"$add": bson.A{"$startTime", bson.M{"$multiply": bson.A{"$duration", 60000}}}},
+        from,
+    }}
+    filter := bson.M{
+        "$and": []bson.M{
+            {"$or": []bson.M{
+                {"createdBy": bson.M{"$in": users}},
+                {"participants": bson.M{"$in": users}},
+            }},
+            {"startTime": bson.M{"$lt": to}},
+            {"$or": []bson.M{
+                {"recurrence": bson.M{"$exists": false}, "$expr": endsAfterFrom},
+                {"recurrence": bson.M{"$exists": true}},
+            }},
+            {"status": bson.M{"$ne": models.MeetingCancelled}},
+            {"deletedAt": bson.M{"$exists": false}},
+        },
+    }
+    cursor, err := config.MeetingCollectionRef.Find(ctx, filter)
+    if err != nil {
+        return nil, err
+    }
+    var meetings []models.Meeting
+    if err := cursor.All(ctx, &meetings); err != nil {
+        return nil, err
+    }
+
+    // Occurrences starting before from may still run into the window
+    lookback := time.Duration(0)
+    for _, m := range meetings {
+        if length := time.Duration(m.Duration) * time.Minute; m.Recurrence != nil && length > lookback {
+            lookback = length
+        }
+    }
+    expanded, err := expandMeetings(ctx, meetings, from.Add(-lookback), to)
+    if err != nil {
+        return nil, err
+    }
+    busy := expanded[:0]
+    for _, m := range expanded {
+        if m.StartTime.Add(time.Duration(m.Duration) * time.Minute).After(from) {
+            busy = append(busy, m)
+        }
+    }
+    return busy, nil
+}
+
+// isBusyIn reports whether the meeting occupies the user's time
+func isBusyIn(meeting models.Meeting, userID primitive.ObjectID) bool {
+    if meeting.CreatedBy == userID {
+        return true
+    }
+    if !isParticipant(meeting, userID) {
+        return false
+    }
+    id := userID
+    return findResponse(meeting, &id, "").Status != models.ResponseDeclined
+}
+
+// userNames maps user ids to display names
+func userNames(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]string, error) {
+    names := map[primitive.ObjectID]string{}
+    cursor, err := config.UserCollectionRef.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+    if err != nil {
+        return nil, err
+    }
+    var users []models.User
+    if err := cursor.All(ctx, &users); err != nil {
+        return nil, err
+    }
+    for _, u := range users {
+        names[u.ID] = u.Nama
+    }
+    return names, nil
+}
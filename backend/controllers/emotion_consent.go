@@ -0,0 +1,273 @@
+package controllers
+
+import (
+    "context"
+    "log"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/notify"
+    "pbommo/utils"
+)
+
+// GetEmotionConsent returns the current user's consent for a meeting. The
+// organizer also gets every participant's consent.
+func GetEmotionConsent(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meeting, userID, errResp := loadAttendedMeeting(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+
+    filter := bson.M{"meetingId": meeting.ID}
+    if meeting.CreatedBy != userID {
+        filter["userId"] = userID
+    }
+    cursor, err := config.EmotionConsentCollectionRef.Find(ctx, filter)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil persetujuan"})
+    }
+    consents := []models.EmotionConsent{}
+    if err := cursor.All(ctx, &consents); err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memproses persetujuan"})
+    }
+
+    response := fiber.Map{
+        "emotionTracking": meeting.EmotionTracking,
+        "consented":       false,
+    }
+    for _, consent := range consents {
+        if consent.UserID == userID {
+            response["consent"] = consent
+            response["consented"] = consent.WithdrawnAt == nil
+        }
+    }
+    if meeting.CreatedBy == userID {
+        response["consents"] = consents
+    }
+    return c.JSON(response)
+}
+
+// GrantEmotionConsent opts the current user in to emotion tracking of a meeting
+func GrantEmotionConsent(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meeting, userID, errResp := loadAttendedMeeting(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+
+    var consent models.EmotionConsent
+    err := config.EmotionConsentCollectionRef.FindOneAndUpdate(ctx,
+        bson.M{"meetingId": meeting.ID, "userId": userID},
+        bson.M{
+            "$set":   bson.M{"grantedAt": time.Now()},
+            "$unset": bson.M{"withdrawnAt": ""},
+        },
+        options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+    ).Decode(&consent)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan persetujuan"})
+    }
+
+    return c.JSON(fiber.Map{
+        "message": "Persetujuan emotion tracking disimpan",
+        "consent": consent,
+    })
+}
+
+// WithdrawEmotionConsent opts the current user out and deletes all of
+// their samples for the meeting. Cached reports are dropped so they are
+// rebuilt without them.
+func WithdrawEmotionConsent(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    meeting, userID, errResp := loadAttendedMeeting(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+
+    result, err := config.EmotionConsentCollectionRef.UpdateOne(ctx,
+        bson.M{"meetingId": meeting.ID, "userId": userID, "withdrawnAt": bson.M{"$exists": false}},
+        bson.M{"$set": bson.M{"withdrawnAt": time.Now()}})
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mencabut persetujuan"})
+    }
+    if result.MatchedCount == 0 {
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Anda belum menyetujui emotion tracking"})
+    }
+
+    // Consent to a series also covered its edited occurrences
+    ids := []primitive.ObjectID{meeting.ID}
+    if meeting.Recurrence != nil {
+        cursor, err := config.MeetingCollectionRef.Find(ctx, bson.M{"seriesId": meeting.ID},
+            options.Find().SetProjection(bson.M{"_id": 1}))
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus data emosi"})
+        }
+        var overrides []models.Meeting
+        if err := cursor.All(ctx, &overrides); err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus data emosi"})
+        }
+        for _, o := range overrides {
+            ids = append(ids, o.ID)
+        }
+    }
+
+    deleted, err := config.EmotionSampleCollectionRef.DeleteMany(ctx, bson.M{"meetingId": bson.M{"$in": ids}, "userId": userID})
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus data emosi"})
+    }
+    for _, id := range ids {
+        forgetLiveSamples(id, userID)
+    }
+    if _, err := config.EmotionReportCollectionRef.DeleteMany(ctx, bson.M{"meetingId": bson.M{"$in": ids}}); err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus laporan emosi"})
+    }
+
+    return c.JSON(fiber.Map{
+        "message":        "Persetujuan dicabut dan data emosi Anda dihapus",
+        "deletedSamples": deleted.DeletedCount,
+    })
+}
+
+// loadAttendedMeeting finds the meeting in :id, which the current user must
+// organize or attend. For a recurring meeting this is the whole series.
+func loadAttendedMeeting(c *fiber.Ctx, ctx context.Context) (models.Meeting, primitive.ObjectID, func() error) {
+    var meeting models.Meeting
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return meeting, userID, func() error {
+            return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+        }
+    }
+    meetingID, err := primitive.ObjectIDFromHex(c.Params("id"))
+    if err != nil {
+        return meeting, userID, func() error {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID meeting tidak valid"})
+        }
+    }
+    err = config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": meetingID, "deletedAt": bson.M{"$exists": false}}).Decode(&meeting)
+    if err == mongo.ErrNoDocuments {
+        return meeting, userID, func() error {
+            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Meeting tidak ditemukan"})
+        }
+    }
+    if err != nil {
+        return meeting, userID, func() error {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data meeting"})
+        }
+    }
+    if meeting.CreatedBy != userID && !isParticipant(meeting, userID) {
+        return meeting, userID, func() error {
+            return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Anda tidak memiliki akses ke meeting ini"})
+        }
+    }
+    return meeting, userID, nil
+}
+
+// hasEmotionConsent reports whether the user opted in to tracking of the
+// occurrence, through its own meeting or the series it belongs to
+func hasEmotionConsent(ctx context.Context, occurrence models.Meeting, userID primitive.ObjectID) (bool, error) {
+    ids := []primitive.ObjectID{occurrence.ID}
+    if occurrence.SeriesID != nil {
+        ids = append(ids, *occurrence.SeriesID)
+    }
+    count, err := config.EmotionConsentCollectionRef.CountDocuments(ctx, bson.M{
+        "meetingId":   bson.M{"$in": ids},
+        "userId":      userID,
+        "withdrawnAt": bson.M{"$exists": false},
+    })
+    return count > 0, err
+}
+
+// requestEmotionConsent asks participants to opt in when emotion tracking
+// is turned on for them
+func requestEmotionConsent(meeting models.Meeting, userIDs []primitive.ObjectID) {
+    if !meeting.EmotionTracking || len(userIDs) == 0 {
+        return
+    }
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        defer cancel()
+
+        users, err := usersByID(ctx, userIDs)
+        if err != nil {
+            log.Printf("Failed to load users for emotion consent: %v", err)
+            return
+        }
+        var notifications []models.Notification
+        for _, id := range userIDs {
+            user, ok := users[id]
+            if !ok || user.Disabled || id == meeting.CreatedBy {
+                continue
+            }
+            notifications = append(notifications, newMeetingNotification(models.NotificationEmotionConsent, meeting, user,
+                notify.T(user.Language, "emotion.consent", meeting.Title)))
+        }
+        if err := notify.SaveInApp(ctx, notifications...); err != nil {
+            log.Printf("Failed to save emotion consent notifications: %v", err)
+        }
+    }()
+}
+
+// validEmotionVisibility accepts the known visibilities, or empty for the default
+func validEmotionVisibility(v string) bool {
+    return v == "" || v == models.EmotionVisibilityOrganizer || v == models.EmotionVisibilityAggregate
+}
+
+// visibleEmotionReport hides other participants' results unless the user
+// is the organizer and the meeting allows it. Aggregates are held to the
+// same threshold as the live mood: they are only shown when at least
+// config.EmotionLiveMinParticipants people besides the user contributed,
+// so nobody can be singled out.
+func visibleEmotionReport(report models.EmotionReport, meeting models.Meeting, userID primitive.ObjectID) models.EmotionReport {
+    if meeting.CreatedBy == userID && meeting.EmotionVisibility != models.EmotionVisibilityAggregate {
+        return report
+    }
+    own := []models.ParticipantEmotion{}
+    for _, p := range report.Participants {
+        if p.UserID == userID {
+            own = append(own, p)
+        }
+    }
+    report.Participants = own
+
+    // Minutes only carry a participant count, so a user with samples is
+    // assumed to be in each of them
+    minOthers := config.EmotionLiveMinParticipants()
+    if report.ParticipantCount-len(own) < minOthers {
+        report.Distribution = map[string]float64{}
+        report.EngagementScore = 0
+        report.Timeline = []models.EmotionMinute{}
+        report.Peaks = []models.EmotionPeak{}
+        return report
+    }
+    timeline := []models.EmotionMinute{}
+    shown := map[int]bool{}
+    for _, m := range report.Timeline {
+        if m.Participants-len(own) >= minOthers {
+            timeline = append(timeline, m)
+            shown[m.Minute] = true
+        }
+    }
+    peaks := []models.EmotionPeak{}
+    for _, p := range report.Peaks {
+        if shown[p.Minute] {
+            peaks = append(peaks, p)
+        }
+    }
+    report.Timeline, report.Peaks = timeline, peaks
+    return report
+}
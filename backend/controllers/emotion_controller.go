@@ -0,0 +1,472 @@
+package controllers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "math"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+
+    "pbommo/config"
+    "pbommo/emotion"
+    "pbommo/models"
+    "pbommo/utils"
+)
+
+const (
+    maxEmotionBatch = 120       // e.g. two minutes of samples at 1/s
+    maxEmotionBody  = 64 * 1024 // bytes
+    // emotionGrace lets clients flush buffered samples shortly after the meeting ends
+    emotionGrace = time.Minute
+    // emotionClockSkew is how far in the future a sample timestamp may be
+    emotionClockSkew = 5 * time.Second
+    duplicateKeyCode = 11000
+)
+
+var emotionLabelSet = func() map[string]bool {
+    set := map[string]bool{}
+    for _, l := range models.EmotionLabels {
+        set[l] = true
+    }
+    return set
+}()
+
+type emotionSampleInput struct {
+    Timestamp  time.Time          `json:"timestamp"`
+    Scores     map[string]float64 `json:"scores"`
+    Confidence float64            `json:"confidence"`
+}
+
+type emotionBatchInput struct {
+    Samples []emotionSampleInput `json:"samples"`
+}
+
+// RejectedSample explains why a sample of a batch was not stored
+type RejectedSample struct {
+    Index int    `json:"index"`
+    Error string `json:"error"`
+}
+
+// IngestEmotionSamples stores a batch of the current user's emotion samples
+// for a meeting with emotion tracking. Samples are only accepted while the
+// meeting is in progress and from its organizer and participants who opted
+// in. Invalid samples are rejected individually; resent samples are ignored.
+func IngestEmotionSamples(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    // Checked from the header, before the body is read or parsed; batches
+    // without a declared length are refused
+    length := c.Request().Header.ContentLength()
+    if length < 0 {
+        return c.Status(fiber.StatusLengthRequired).JSON(fiber.Map{"error": "Header Content-Length wajib diisi"})
+    }
+    if length > maxEmotionBody {
+        return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+            "error": fmt.Sprintf("Payload maksimal %d KB", maxEmotionBody/1024),
+        })
+    }
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    var input emotionBatchInput
+    if err := c.BodyParser(&input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+    }
+    if len(input.Samples) == 0 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Samples tidak boleh kosong"})
+    }
+    if len(input.Samples) > maxEmotionBatch {
+        return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+            "error": fmt.Sprintf("Maksimal %d sample per batch", maxEmotionBatch),
+        })
+    }
+
+    occurrence, errResp := loadTrackedOccurrence(c, ctx, userID)
+    if errResp != nil {
+        return errResp()
+    }
+
+    var samples []models.EmotionSample
+    rejected := []RejectedSample{}
+    for i, in := range input.Samples {
+        sample, err := newEmotionSample(occurrence, userID, in.Timestamp, in.Scores, in.Confidence, models.EmotionSourceClient)
+        if err != nil {
+            rejected = append(rejected, RejectedSample{Index: i, Error: err.Error()})
+            continue
+        }
+        samples = append(samples, sample)
+    }
+
+    stored, err := saveEmotionSamples(ctx, samples)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan sample emosi"})
+    }
+    observeLiveSamples(samples)
+
+    return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+        "accepted":   stored,
+        "duplicates": len(samples) - stored,
+        "rejected":   rejected,
+    })
+}
+
+// classifySlots bounds how many frames are classified at once
+var (
+    classifySlots     chan struct{}
+    classifySlotsOnce sync.Once
+)
+
+type emotionFrameInput struct {
+    Timestamp time.Time `json:"timestamp"`
+    Landmarks []float64 `json:"landmarks"`
+}
+
+// ClassifyEmotionFrame classifies a face frame (multipart field "frame",
+// JPEG or PNG, with an optional "timestamp") or a landmark vector (JSON
+// {timestamp, landmarks}) on the server and stores the resulting sample.
+// The same meeting and participant rules as IngestEmotionSamples apply.
+// Frames are only held in memory for the classification and never stored.
+func ClassifyEmotionFrame(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    name := config.EmotionClassifier()
+    if name == "" {
+        return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Classifier emosi belum dikonfigurasi"})
+    }
+    classifier, ok := emotion.Lookup(name)
+    if !ok {
+        return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Classifier emosi tidak tersedia"})
+    }
+
+    in, timestamp, err := parseEmotionFrame(c)
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+    }
+    if err := emotion.Validate(in); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    occurrence, errResp := loadTrackedOccurrence(c, ctx, userID)
+    if errResp != nil {
+        return errResp()
+    }
+
+    classifySlotsOnce.Do(func() {
+        classifySlots = make(chan struct{}, config.EmotionWorkers())
+    })
+    select {
+    case classifySlots <- struct{}{}:
+    case <-ctx.Done():
+        return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Server sedang sibuk, coba lagi"})
+    }
+    result, err := classifier.Classify(ctx, in)
+    <-classifySlots
+    if errors.Is(err, emotion.ErrNoFace) {
+        return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Wajah tidak terdeteksi"})
+    }
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengklasifikasi frame"})
+    }
+
+    sample, err := newEmotionSample(occurrence, userID, timestamp, result.Scores, result.Confidence, models.EmotionSourceServer)
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+    }
+    if _, err := saveEmotionSamples(ctx, []models.EmotionSample{sample}); err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan sample emosi"})
+    }
+    observeLiveSamples([]models.EmotionSample{sample})
+
+    return c.Status(fiber.StatusCreated).JSON(fiber.Map{"sample": sample})
+}
+
+// parseEmotionFrame reads a multipart frame upload or a JSON landmark
+// vector. The timestamp defaults to now.
+func parseEmotionFrame(c *fiber.Ctx) (emotion.Input, time.Time, error) {
+    var in emotion.Input
+    timestamp := time.Now()
+
+    if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+        file, err := c.FormFile("frame")
+        if err != nil {
+            return in, timestamp, fmt.Errorf("field frame wajib diisi")
+        }
+        if file.Size > emotion.MaxFrameBytes {
+            return in, timestamp, fmt.Errorf("frame maksimal %d KB", emotion.MaxFrameBytes/1024)
+        }
+        f, err := file.Open()
+        if err != nil {
+            return in, timestamp, fmt.Errorf("frame tidak dapat dibaca")
+        }
+        defer f.Close()
+        if in.Image, err = io.ReadAll(io.LimitReader(f, emotion.MaxFrameBytes+1)); err != nil {
+            return in, timestamp, fmt.Errorf("frame tidak dapat dibaca")
+        }
+        if ts := c.FormValue("timestamp"); ts != "" {
+            if timestamp, err = time.Parse(time.RFC3339, ts); err != nil {
+                return in, timestamp, fmt.Errorf("timestamp harus berformat RFC3339")
+            }
+        }
+        return in, timestamp, nil
+    }
+
+    var input emotionFrameInput
+    if err := c.BodyParser(&input); err != nil {
+        return in, timestamp, fmt.Errorf("request tidak valid")
+    }
+    in.Landmarks = input.Landmarks
+    if !input.Timestamp.IsZero() {
+        timestamp = input.Timestamp
+    }
+    return in, timestamp, nil
+}
+
+// EnsureEmotionIndexes creates the emotion sample, report and consent
+// indexes, including the TTL indexes enforcing config.EmotionRetention
+func EnsureEmotionIndexes(ctx context.Context) error {
+    _, err := config.EmotionSampleCollectionRef.Indexes().CreateMany(ctx, []mongo.IndexModel{
+        {
+            Keys: bson.D{
+                {Key: "meetingId", Value: 1},
+                {Key: "occurrenceStart", Value: 1},
+                {Key: "userId", Value: 1},
+                {Key: "timestamp", Value: 1},
+            },
+            Options: options.Index().SetUnique(true),
+        },
+        {Keys: bson.D{{Key: "meetingId", Value: 1}, {Key: "timestamp", Value: 1}}},
+    })
+    if err != nil {
+        return err
+    }
+    _, err = config.EmotionReportCollectionRef.Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys:    bson.D{{Key: "meetingId", Value: 1}, {Key: "occurrenceStart", Value: 1}},
+        Options: options.Index().SetUnique(true),
+    })
+    if err != nil {
+        return err
+    }
+    _, err = config.EmotionConsentCollectionRef.Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys:    bson.D{{Key: "meetingId", Value: 1}, {Key: "userId", Value: 1}},
+        Options: options.Index().SetUnique(true),
+    })
+    if err != nil {
+        return err
+    }
+
+    // Samples and reports are purged after the retention period
+    retention := config.EmotionRetention()
+    if err := config.EnsureTTLIndex(ctx, config.EmotionSampleCollectionRef, "createdAt", retention); err != nil {
+        return err
+    }
+    return config.EnsureTTLIndex(ctx, config.EmotionReportCollectionRef, "generatedAt", retention)
+}
+
+// loadTrackedOccurrence finds the meeting in :id and the occurrence in
+// progress now, checking that emotion tracking is on and the user attends
+func loadTrackedOccurrence(c *fiber.Ctx, ctx context.Context, userID primitive.ObjectID) (models.Meeting, func() error) {
+    var meeting models.Meeting
+    meetingID, err := primitive.ObjectIDFromHex(c.Params("id"))
+    if err != nil {
+        return meeting, func() error {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID meeting tidak valid"})
+        }
+    }
+    err = config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": meetingID, "deletedAt": bson.M{"$exists": false}}).Decode(&meeting)
+    if err == mongo.ErrNoDocuments {
+        return meeting, func() error {
+            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Meeting tidak ditemukan"})
+        }
+    }
+    if err != nil {
+        return meeting, func() error {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data meeting"})
+        }
+    }
+
+    if meeting.CreatedBy != userID && !isParticipant(meeting, userID) {
+        return meeting, func() error {
+            return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya peserta meeting yang dapat mengirim data emosi"})
+        }
+    }
+    if !meeting.EmotionTracking {
+        return meeting, func() error {
+            return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Emotion tracking tidak aktif untuk meeting ini"})
+        }
+    }
+
+    occurrence, ok, err := occurrenceInProgress(ctx, meeting, time.Now())
+    if err != nil {
+        return meeting, func() error {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa jadwal meeting"})
+        }
+    }
+    if !ok {
+        return meeting, func() error {
+            return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Meeting sedang tidak berlangsung"})
+        }
+    }
+
+    consented, err := hasEmotionConsent(ctx, occurrence, userID)
+    if err != nil {
+        return meeting, func() error {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa persetujuan"})
+        }
+    }
+    if !consented {
+        return meeting, func() error {
+            return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Anda belum menyetujui emotion tracking untuk meeting ini"})
+        }
+    }
+    return occurrence, nil
+}
+
+// occurrenceInProgress returns the occurrence of the meeting running at now,
+// including the grace period after it ends. Started occurrences run from
+// their actual start, ended ones until they were ended, and cancelled ones
+// not at all. Occurrences of a series keep the series ID, also when they
+// are stored as overrides.
+func occurrenceInProgress(ctx context.Context, meeting models.Meeting, now time.Time) (models.Meeting, bool, error) {
+    candidates := []models.Meeting{meeting}
+    if meeting.Recurrence != nil {
+        length := time.Duration(meeting.Duration) * time.Minute
+        var err error
+        candidates, err = expandMeetings(ctx, []models.Meeting{meeting}, now.Add(-length-emotionGrace), now.Add(time.Second))
+        if err != nil {
+            return meeting, false, err
+        }
+        // expandMeetings leaves out overridden occurrences, e.g. started ones
+        cursor, err := config.MeetingCollectionRef.Find(ctx, bson.M{
+            "seriesId":  meeting.ID,
+            "startTime": bson.M{"$gte": now.Add(-maxMeetingLength - emotionGrace), "$lte": now.Add(meetingEarlyStart)},
+        })
+        if err != nil {
+            return meeting, false, err
+        }
+        var overrides []models.Meeting
+        if err := cursor.All(ctx, &overrides); err != nil {
+            return meeting, false, err
+        }
+        for _, o := range overrides {
+            o.ID = meeting.ID
+            candidates = append(candidates, o)
+        }
+    }
+    for _, occ := range candidates {
+        from, to, ok := emotionWindow(occ)
+        if ok && !now.Before(from) && now.Before(to.Add(emotionGrace)) {
+            return occ, true, nil
+        }
+    }
+    return meeting, false, nil
+}
+
+// emotionWindow is when samples of an occurrence may be taken: from its
+// start, or from when it was started if that was earlier, until it ends or
+// is ended. Cancelled occurrences have none.
+func emotionWindow(occurrence models.Meeting) (time.Time, time.Time, bool) {
+    from, to := occurrence.StartTime, meetingEnd(occurrence)
+    if occurrence.ActualStart != nil && occurrence.ActualStart.Before(from) {
+        from = *occurrence.ActualStart
+    }
+    switch occurrence.Status {
+    case models.MeetingCancelled:
+        return from, to, false
+    case models.MeetingCompleted:
+        if occurrence.ActualEnd == nil {
+            return from, to, false
+        }
+        to = *occurrence.ActualEnd
+    }
+    return from, to, true
+}
+
+// newEmotionSample validates a sample for the occurrence and derives its
+// dominant label
+func newEmotionSample(occurrence models.Meeting, userID primitive.ObjectID, timestamp time.Time, scores map[string]float64, confidence float64, source string) (models.EmotionSample, error) {
+    start := occurrence.StartTime
+    from, end, _ := emotionWindow(occurrence)
+    if timestamp.IsZero() {
+        return models.EmotionSample{}, fmt.Errorf("timestamp wajib diisi")
+    }
+    if timestamp.Before(from) || timestamp.After(end) || timestamp.After(time.Now().Add(emotionClockSkew)) {
+        return models.EmotionSample{}, fmt.Errorf("timestamp di luar waktu meeting")
+    }
+    if len(scores) == 0 {
+        return models.EmotionSample{}, fmt.Errorf("scores wajib diisi")
+    }
+    if math.IsNaN(confidence) || confidence < 0 || confidence > 1 {
+        return models.EmotionSample{}, fmt.Errorf("confidence harus antara 0 dan 1")
+    }
+
+    dominant, best := "", -1.0
+    for label, score := range scores {
+        if !emotionLabelSet[label] {
+            return models.EmotionSample{}, fmt.Errorf("label emosi tidak dikenal: %s", label)
+        }
+        if math.IsNaN(score) || score < 0 || score > 1 {
+            return models.EmotionSample{}, fmt.Errorf("score %s harus antara 0 dan 1", label)
+        }
+        if score > best || (score == best && label < dominant) {
+            dominant, best = label, score
+        }
+    }
+
+    // Expanded occurrences keep the series ID
+    return models.EmotionSample{
+        MeetingID:       occurrence.ID,
+        OccurrenceStart: start,
+        UserID:          userID,
+        Timestamp:       timestamp.UTC().Truncate(time.Millisecond),
+        Scores:          scores,
+        Dominant:        dominant,
+        Confidence:      confidence,
+        Source:          source,
+        CreatedAt:       time.Now(),
+    }, nil
+}
+
+// saveEmotionSamples inserts samples, skipping ones already stored, and
+// returns how many were new
+func saveEmotionSamples(ctx context.Context, samples []models.EmotionSample) (int, error) {
+    if len(samples) == 0 {
+        return 0, nil
+    }
+    docs := make([]interface{}, 0, len(samples))
+    for _, s := range samples {
+        docs = append(docs, s)
+    }
+    _, err := config.EmotionSampleCollectionRef.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+    var bulkErr mongo.BulkWriteException
+    if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+        for _, we := range bulkErr.WriteErrors {
+            if we.Code != duplicateKeyCode {
+                return 0, err
+            }
+        }
+        return len(docs) - len(bulkErr.WriteErrors), nil
+    }
+    if err != nil {
+        return 0, err
+    }
+    return len(docs), nil
+}
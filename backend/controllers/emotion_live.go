@@ -0,0 +1,159 @@
+package controllers
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "fmt"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "github.com/valyala/fasthttp"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+
+    "pbommo/config"
+    "pbommo/emotion"
+    "pbommo/models"
+    "pbommo/realtime"
+)
+
+const (
+    liveEmotionWindow   = 30 * time.Second
+    liveEmotionInterval = 5 * time.Second
+)
+
+// forgetEmotion is the payload of realtime.EventEmotionForget
+type forgetEmotion struct {
+    MeetingID primitive.ObjectID `json:"meetingId"`
+    UserID    primitive.ObjectID `json:"userId"`
+}
+
+// Samples reach the live aggregates of every instance through the
+// realtime broker, whichever instance ingested them
+func init() {
+    realtime.Handle(realtime.EventEmotionSamples, func(ev realtime.Event) {
+        var samples []emotion.LiveSample
+        if err := json.Unmarshal(ev.Data, &samples); err == nil {
+            emotion.Observe(samples...)
+        }
+    })
+    realtime.Handle(realtime.EventEmotionForget, func(ev realtime.Event) {
+        var f forgetEmotion
+        if err := json.Unmarshal(ev.Data, &f); err == nil {
+            emotion.Forget(f.MeetingID, f.UserID)
+        }
+    })
+}
+
+// StreamLiveEmotions streams the anonymized mood of a meeting in progress
+// to its organizer as Server-Sent Events: every few seconds the average
+// distribution across participants over the last 30 seconds. With fewer
+// participants besides the organizer than config.EmotionLiveMinParticipants
+// only their count is sent. The stream ends with the meeting, also when it
+// is ended early or cancelled. Authenticates like StreamEvents.
+func StreamLiveEmotions(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := streamUserID(c, ctx)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    meetingID, err := primitive.ObjectIDFromHex(c.Params("id"))
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID meeting tidak valid"})
+    }
+    var meeting models.Meeting
+    err = config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": meetingID, "deletedAt": bson.M{"$exists": false}}).Decode(&meeting)
+    if err != nil {
+        if err == mongo.ErrNoDocuments {
+            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Meeting tidak ditemukan"})
+        }
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data meeting"})
+    }
+    if meeting.CreatedBy != userID {
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya pembuat meeting yang dapat melihat mood live"})
+    }
+    if !meeting.EmotionTracking {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Emotion tracking tidak aktif untuk meeting ini"})
+    }
+    occurrence, ok, err := occurrenceInProgress(ctx, meeting, time.Now())
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa jadwal meeting"})
+    }
+    if !ok {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Meeting sedang tidak berlangsung"})
+    }
+
+    end := occurrence.StartTime.Add(time.Duration(occurrence.Duration)*time.Minute + emotionGrace)
+    minParticipants := config.EmotionLiveMinParticipants()
+
+    setSSEHeaders(c)
+    c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+        ticker := time.NewTicker(liveEmotionInterval)
+        defer ticker.Stop()
+
+        fmt.Fprint(w, "retry: 5000\n\n")
+        for {
+            now := time.Now()
+            if now.After(end) || occurrenceEnded(occurrence) {
+                writeSSE(w, "", "ended", fiber.Map{"meetingId": occurrence.ID})
+                w.Flush()
+                return
+            }
+            writeSSE(w, "", "mood", emotion.Live(occurrence.ID, occurrence.StartTime, now, liveEmotionWindow, minParticipants, userID))
+            // A failed flush means the client went away
+            if err := w.Flush(); err != nil {
+                return
+            }
+            <-ticker.C
+        }
+    }))
+    return nil
+}
+
+// occurrenceEnded reports whether the occurrence, or its whole series, has
+// been completed or cancelled. Errors count as not ended; the stream still
+// stops at the scheduled end.
+func occurrenceEnded(occurrence models.Meeting) bool {
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+
+    docs := []bson.M{{"_id": occurrence.ID}}
+    if occurrence.SeriesID != nil && occurrence.RecurrenceID != nil {
+        docs = append(docs,
+            bson.M{"_id": *occurrence.SeriesID},
+            bson.M{"seriesId": *occurrence.SeriesID, "recurrenceId": *occurrence.RecurrenceID})
+    }
+    count, err := config.MeetingCollectionRef.CountDocuments(ctx, bson.M{
+        "$or":    docs,
+        "status": bson.M{"$in": []string{models.MeetingCompleted, models.MeetingCancelled}},
+    })
+    return err == nil && count > 0
+}
+
+// observeLiveSamples feeds stored samples to the live aggregates
+func observeLiveSamples(samples []models.EmotionSample) {
+    if len(samples) == 0 {
+        return
+    }
+    live := make([]emotion.LiveSample, 0, len(samples))
+    for _, s := range samples {
+        live = append(live, emotion.LiveSample{
+            MeetingID:       s.MeetingID,
+            OccurrenceStart: s.OccurrenceStart,
+            UserID:          s.UserID,
+            Timestamp:       s.Timestamp,
+            Scores:          s.Scores,
+        })
+    }
+    realtime.Broadcast(realtime.EventEmotionSamples, &samples[0].MeetingID, live)
+}
+
+// forgetLiveSamples drops a user's samples from the live aggregates
+func forgetLiveSamples(meetingID, userID primitive.ObjectID) {
+    realtime.Broadcast(realtime.EventEmotionForget, &meetingID, forgetEmotion{MeetingID: meetingID, UserID: userID})
+}
@@ -0,0 +1,292 @@
+package controllers
+
+import (
+    "context"
+    "math"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/utils"
+)
+
+const maxEmotionPeaks = 3
+
+// GetEmotionReport returns the emotion analytics of a meeting: the average
+// distribution per minute, per-participant summaries, peak moments and an
+// engagement score. Recurring meetings report one occurrence, ?start= or
+// the latest one with samples. Reports of ended meetings are cached, since
+// no samples can be added any more. Only the organizer sees other
+// participants' results, unless the meeting is aggregate-only; everyone
+// else sees the aggregates once enough people took part.
+func GetEmotionReport(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    meetingID, err := primitive.ObjectIDFromHex(c.Params("id"))
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID meeting tidak valid"})
+    }
+    var meeting models.Meeting
+    err = config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": meetingID, "deletedAt": bson.M{"$exists": false}}).Decode(&meeting)
+    if err != nil {
+        if err == mongo.ErrNoDocuments {
+            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Meeting tidak ditemukan"})
+        }
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data meeting"})
+    }
+    if meeting.CreatedBy != userID && !isParticipant(meeting, userID) {
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Anda tidak memiliki akses ke meeting ini"})
+    }
+
+    start, errResp := reportOccurrence(ctx, c, meeting)
+    if errResp != nil {
+        return errResp()
+    }
+
+    var report models.EmotionReport
+    err = config.EmotionReportCollectionRef.FindOne(ctx, bson.M{"meetingId": meeting.ID, "occurrenceStart": start}).Decode(&report)
+    if err == nil {
+        return c.JSON(fiber.Map{"report": visibleEmotionReport(report, meeting, userID)})
+    }
+    if err != mongo.ErrNoDocuments {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil laporan emosi"})
+    }
+
+    report, err = buildEmotionReport(ctx, meeting, start)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyusun laporan emosi"})
+    }
+
+    // Reports without samples are not cached: there may be nothing to
+    // report yet because the occurrence never took place
+    end := start.Add(time.Duration(meeting.Duration)*time.Minute + emotionGrace)
+    if time.Now().After(end) && len(report.Participants) > 0 {
+        report.Final = true
+        _, err = config.EmotionReportCollectionRef.ReplaceOne(ctx,
+            bson.M{"meetingId": meeting.ID, "occurrenceStart": start}, report,
+            options.Replace().SetUpsert(true))
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan laporan emosi"})
+        }
+    }
+
+    return c.JSON(fiber.Map{"report": visibleEmotionReport(report, meeting, userID)})
+}
+
+// reportOccurrence picks the occurrence to report on. A ?start= must be an
+// occurrence of the meeting; on failure it returns a function writing the
+// error response.
+func reportOccurrence(ctx context.Context, c *fiber.Ctx, meeting models.Meeting) (time.Time, func() error) {
+    if s := c.Query("start"); s != "" {
+        start, err := time.Parse(time.RFC3339, strings.ReplaceAll(s, " ", "+"))
+        if err != nil {
+            return start, func() error {
+                return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Parameter start harus berformat RFC3339"})
+            }
+        }
+        if meeting.Recurrence == nil && !start.Equal(meeting.StartTime) ||
+            meeting.Recurrence != nil && !isOccurrence(meeting, start) {
+            return start, func() error {
+                return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Occurrence tidak ditemukan"})
+            }
+        }
+        return start.UTC(), nil
+    }
+    if meeting.Recurrence == nil {
+        return meeting.StartTime, nil
+    }
+    var latest models.EmotionSample
+    err := config.EmotionSampleCollectionRef.FindOne(ctx, bson.M{"meetingId": meeting.ID},
+        options.FindOne().SetSort(bson.M{"occurrenceStart": -1})).Decode(&latest)
+    if err != nil {
+        return meeting.StartTime, nil
+    }
+    return latest.OccurrenceStart, nil
+}
+
+// buildEmotionReport aggregates the samples of one occurrence
+func buildEmotionReport(ctx context.Context, meeting models.Meeting, start time.Time) (models.EmotionReport, error) {
+    report := models.EmotionReport{
+        MeetingID:       meeting.ID,
+        OccurrenceStart: start,
+        Distribution:    map[string]float64{},
+        Timeline:        []models.EmotionMinute{},
+        Participants:    []models.ParticipantEmotion{},
+        Peaks:           []models.EmotionPeak{},
+        GeneratedAt:     time.Now(),
+    }
+    match := bson.D{{Key: "$match", Value: bson.M{"meetingId": meeting.ID, "occurrenceStart": start}}}
+    minute := bson.M{"$floor": bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{"$timestamp", start}}, 60000}}}
+
+    // Timeline: one row per minute with samples
+    timelineGroup := emotionAverages()
+    timelineGroup["_id"] = minute
+    timelineGroup["samples"] = bson.M{"$sum": 1}
+    timelineGroup["users"] = bson.M{"$addToSet": "$userId"}
+    cursor, err := config.EmotionSampleCollectionRef.Aggregate(ctx, mongo.Pipeline{
+        match,
+        {{Key: "$group", Value: timelineGroup}},
+        {{Key: "$project", Value: bson.M{
+            "minute":       "$_id",
+            "samples":      1,
+            "participants": bson.M{"$size": "$users"},
+            "distribution": emotionDistribution(),
+        }}},
+        {{Key: "$sort", Value: bson.M{"minute": 1}}},
+    })
+    if err != nil {
+        return report, err
+    }
+    if err := cursor.All(ctx, &report.Timeline); err != nil {
+        return report, err
+    }
+    for i := range report.Timeline {
+        m := &report.Timeline[i]
+        m.Start = start.Add(time.Duration(m.Minute) * time.Minute)
+        roundDistribution(m.Distribution)
+        m.Dominant = dominantEmotion(m.Distribution, "")
+    }
+
+    // Participants
+    participantGroup := emotionAverages()
+    participantGroup["_id"] = "$userId"
+    participantGroup["samples"] = bson.M{"$sum": 1}
+    participantGroup["firstSeen"] = bson.M{"$min": "$timestamp"}
+    participantGroup["lastSeen"] = bson.M{"$max": "$timestamp"}
+    participantGroup["minutes"] = bson.M{"$addToSet": minute}
+    participantGroup["confidence"] = bson.M{"$avg": "$confidence"}
+    cursor, err = config.EmotionSampleCollectionRef.Aggregate(ctx, mongo.Pipeline{
+        match,
+        {{Key: "$group", Value: participantGroup}},
+        {{Key: "$project", Value: bson.M{
+            "userId":        "$_id",
+            "samples":       1,
+            "firstSeen":     1,
+            "lastSeen":      1,
+            "activeMinutes": bson.M{"$size": "$minutes"},
+            "confidence":    1,
+            "distribution":  emotionDistribution(),
+        }}},
+    })
+    if err != nil {
+        return report, err
+    }
+    if err := cursor.All(ctx, &report.Participants); err != nil {
+        return report, err
+    }
+
+    ids := make([]primitive.ObjectID, 0, len(report.Participants))
+    for _, p := range report.Participants {
+        ids = append(ids, p.UserID)
+    }
+    names := map[primitive.ObjectID]string{}
+    if len(ids) > 0 {
+        if names, err = userNames(ctx, ids); err != nil {
+            return report, err
+        }
+    }
+
+    minutes := math.Max(1, float64(meeting.Duration))
+    engagement := 0
+    for i := range report.Participants {
+        p := &report.Participants[i]
+        p.Nama = names[p.UserID]
+        for label, score := range p.Distribution {
+            report.Distribution[label] += score * float64(p.Samples)
+        }
+        roundDistribution(p.Distribution)
+        p.Dominant = dominantEmotion(p.Distribution, "")
+        p.Confidence = math.Round(p.Confidence*1000) / 1000
+        // Presence weighs more than expressiveness
+        presence := math.Min(1, float64(p.ActiveMinutes)/minutes)
+        p.Engagement = int(math.Round(100 * (0.6*presence + 0.4*(1-p.Distribution[models.EmotionNeutral]))))
+        engagement += p.Engagement
+        report.SampleCount += p.Samples
+    }
+    sort.Slice(report.Participants, func(i, j int) bool { return report.Participants[i].Nama < report.Participants[j].Nama })
+    report.ParticipantCount = len(report.Participants)
+    if report.SampleCount > 0 {
+        for label := range report.Distribution {
+            report.Distribution[label] /= float64(report.SampleCount)
+        }
+        roundDistribution(report.Distribution)
+        report.EngagementScore = int(math.Round(float64(engagement) / float64(report.ParticipantCount)))
+    }
+
+    report.Peaks = emotionPeaks(report.Timeline, 1-report.Distribution[models.EmotionNeutral])
+    return report, nil
+}
+
+// emotionPeaks returns the minutes with the most intense non-neutral
+// emotion, if above the meeting's average intensity
+func emotionPeaks(timeline []models.EmotionMinute, average float64) []models.EmotionPeak {
+    peaks := []models.EmotionPeak{}
+    for _, m := range timeline {
+        intensity := math.Round((1-m.Distribution[models.EmotionNeutral])*1000) / 1000
+        if intensity <= average {
+            continue
+        }
+        peaks = append(peaks, models.EmotionPeak{
+            Minute:    m.Minute,
+            Start:     m.Start,
+            Emotion:   dominantEmotion(m.Distribution, models.EmotionNeutral),
+            Intensity: intensity,
+        })
+    }
+    sort.SliceStable(peaks, func(i, j int) bool { return peaks[i].Intensity > peaks[j].Intensity })
+    if len(peaks) > maxEmotionPeaks {
+        peaks = peaks[:maxEmotionPeaks]
+    }
+    sort.Slice(peaks, func(i, j int) bool { return peaks[i].Minute < peaks[j].Minute })
+    return peaks
+}
+
+// emotionAverages is a $group stage body averaging every label, treating
+// missing scores as 0
+func emotionAverages() bson.M {
+    group := bson.M{}
+    for _, label := range models.EmotionLabels {
+        group[label] = bson.M{"$avg": bson.M{"$ifNull": bson.A{"$scores." + label, 0}}}
+    }
+    return group
+}
+
+// emotionDistribution nests the averaged labels into one document
+func emotionDistribution() bson.M {
+    dist := bson.M{}
+    for _, label := range models.EmotionLabels {
+        dist[label] = "$" + label
+    }
+    return dist
+}
+
+func roundDistribution(dist map[string]float64) {
+    for label, score := range dist {
+        dist[label] = math.Round(score*1000) / 1000
+    }
+}
+
+// dominantEmotion returns the label with the highest score, ignoring skip
+func dominantEmotion(dist map[string]float64, skip string) string {
+    dominant, best := "", -1.0
+    for _, label := range models.EmotionLabels {
+        if label != skip && dist[label] > best {
+            dominant, best = label, dist[label]
+        }
+    }
+    return dominant
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "github.com/valyala/fasthttp"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/realtime"
+    "pbommo/utils"
+)
+
+var eventTypes = map[string]bool{
+    realtime.EventMeetingCreated: true,
+    realtime.EventMeetingUpdated: true,
+    realtime.EventMeetingDeleted: true,
+    realtime.EventRSVP:           true,
+    realtime.EventNotification:   true,
+}
+
+// StreamEvents streams realtime events of the current user as Server-Sent
+// Events. EventSource cannot send headers, so the token may also be passed
+// as ?token=. ?types= and ?meetings= (comma separated) limit the stream to
+// some event types or meetings. A heartbeat event is sent while idle.
+func StreamEvents(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := streamUserID(c, ctx)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    sub := realtime.Subscription{}
+    for _, name := range splitList(c.Query("types")) {
+        if !eventTypes[name] {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Tipe event tidak dikenal: %s", name)})
+        }
+        if sub.Types == nil {
+            sub.Types = map[string]bool{}
+        }
+        sub.Types[name] = true
+    }
+    for _, hex := range splitList(c.Query("meetings")) {
+        id, err := primitive.ObjectIDFromHex(hex)
+        if err != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID meeting tidak valid"})
+        }
+        if sub.Meetings == nil {
+            sub.Meetings = map[primitive.ObjectID]bool{}
+        }
+        sub.Meetings[id] = true
+    }
+
+    setSSEHeaders(c)
+    conn := realtime.Connect(userID, sub)
+    c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+        defer realtime.Disconnect(conn)
+
+        heartbeat := time.NewTicker(config.RealtimeHeartbeat())
+        defer heartbeat.Stop()
+
+        fmt.Fprint(w, "retry: 5000\n\n")
+        writeSSE(w, "", "ready", fiber.Map{"userId": userID})
+        for {
+            // A failed flush means the client went away
+            if err := w.Flush(); err != nil {
+                return
+            }
+            select {
+            case ev := <-conn.Events:
+                writeSSE(w, ev.ID.Hex(), ev.Type, ev)
+            case now := <-heartbeat.C:
+                writeSSE(w, "", "heartbeat", fiber.Map{"time": now})
+            case <-conn.Done:
+                return
+            }
+        }
+    }))
+    return nil
+}
+
+// streamUserID authenticates a stream from the Authorization header or
+// ?token=, and checks the account is still enabled
+func streamUserID(c *fiber.Ctx, ctx context.Context) (primitive.ObjectID, error) {
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil && c.Query("token") != "" {
+        userID, err = utils.ParseUserToken(c.Query("token"))
+    }
+    if err != nil {
+        return userID, err
+    }
+    var user models.User
+    if err := config.UserCollectionRef.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+        return userID, err
+    }
+    if user.Disabled {
+        return userID, fmt.Errorf("user disabled")
+    }
+    return userID, nil
+}
+
+func setSSEHeaders(c *fiber.Ctx) {
+    c.Set("Content-Type", "text/event-stream")
+    c.Set("Cache-Control", "no-cache")
+    c.Set("Connection", "keep-alive")
+    c.Set("X-Accel-Buffering", "no")
+}
+
+func writeSSE(w *bufio.Writer, id, event string, data interface{}) {
+    payload, err := json.Marshal(data)
+    if err != nil {
+        return
+    }
+    if id != "" {
+        fmt.Fprintf(w, "id: %s\n", id)
+    }
+    fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
+
+// publishMeetingEvent pushes a meeting change to its organizer and
+// participants, plus any extra users such as removed participants, and
+// queues it for webhooks
+func publishMeetingEvent(eventType string, meeting models.Meeting, extra ...primitive.ObjectID) {
+    users := append([]primitive.ObjectID{meeting.CreatedBy}, meeting.Participants...)
+    users = append(users, extra...)
+    realtime.Publish(eventType, users, &meeting.ID, meeting)
+    enqueueWebhooks(eventType, meeting)
+}
+
+// publishRSVPEvent pushes a saved response to everyone on the meeting and
+// queues it for webhooks
+func publishRSVPEvent(meeting models.Meeting, response models.Response) {
+    users := append([]primitive.ObjectID{meeting.CreatedBy}, meeting.Participants...)
+    realtime.Publish(realtime.EventRSVP, users, &meeting.ID, response)
+    enqueueWebhooks(realtime.EventRSVP, fiber.Map{"meetingId": meeting.ID, "response": response})
+}
+
+func splitList(value string) []string {
+    var items []string
+    for _, part := range strings.Split(value, ",") {
+        if part = strings.TrimSpace(part); part != "" {
+            items = append(items, part)
+        }
+    }
+    return items
+}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+    "context"
+    "fmt"
+    "net/mail"
+    "strings"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/notify"
+    "pbommo/utils"
+)
+
+// meetingInput is the request body of meeting create/update endpoints.
+// Participants may be user ids or email addresses; emails of people who
+// are not registered become external guests.
+type meetingInput struct {
+    models.Meeting
+    Participants []string `json:"participants"`
+    Force        bool     `json:"force"` // save despite scheduling conflicts
+    // AgendaProposals is only changed by updates that send it
+    AgendaProposals *bool `json:"agendaProposals"`
+}
+
+// participantList is the resolved form of meetingInput.Participants
+type participantList struct {
+    UserIDs     []primitive.ObjectID
+    GuestEmails []string
+}
+
+// resolveParticipants maps each entry to a user id, looking up emails of
+// registered users. The organizer is never listed as their own participant.
+func resolveParticipants(ctx context.Context, entries []string, organizerID primitive.ObjectID) (participantList, error) {
+    list := participantList{UserIDs: []primitive.ObjectID{}, GuestEmails: []string{}}
+
+    var emails, invalid []string
+    seenIDs := map[primitive.ObjectID]bool{organizerID: true}
+    for _, entry := range entries {
+        entry = strings.TrimSpace(entry)
+        if entry == "" {
+            continue
+        }
+        if id, err := primitive.ObjectIDFromHex(entry); err == nil {
+            if !seenIDs[id] {
+                seenIDs[id] = true
+                list.UserIDs = append(list.UserIDs, id)
+            }
+            continue
+        }
+        addr, err := mail.ParseAddress(entry)
+        if err != nil {
+            invalid = append(invalid, entry)
+            continue
+        }
+        emails = append(emails, strings.ToLower(addr.Address))
+    }
+    if len(invalid) > 0 {
+        return list, fmt.Errorf("Peserta tidak valid: %s", strings.Join(invalid, ", "))
+    }
+    if len(emails) == 0 {
+        return list, nil
+    }
+
+    // Emails were not always stored lower-cased, so match both forms
+    candidates := append([]string{}, emails...)
+    for _, entry := range entries {
+        candidates = append(candidates, strings.TrimSpace(entry))
+    }
+    cursor, err := config.UserCollectionRef.Find(ctx, bson.M{"email": bson.M{"$in": candidates}})
+    if err != nil {
+        return list, err
+    }
+    var users []models.User
+    if err := cursor.All(ctx, &users); err != nil {
+        return list, err
+    }
+    byEmail := map[string]primitive.ObjectID{}
+    for _, u := range users {
+        byEmail[strings.ToLower(u.Email)] = u.ID
+    }
+
+    seenGuests := map[string]bool{}
+    for _, email := range emails {
+        if id, ok := byEmail[email]; ok {
+            if !seenIDs[id] {
+                seenIDs[id] = true
+                list.UserIDs = append(list.UserIDs, id)
+            }
+        } else if !seenGuests[email] {
+            seenGuests[email] = true
+            list.GuestEmails = append(list.GuestEmails, email)
+        }
+    }
+    return list, nil
+}
+
+// mergeGuests keeps the access token of guests that are still invited and
+// creates tokens for new ones, which are returned separately for inviting
+func mergeGuests(existing []models.Guest, emails []string) ([]models.Guest, []models.Guest, error) {
+    current := map[string]models.Guest{}
+    for _, g := range existing {
+        current[g.Email] = g
+    }
+
+    guests := []models.Guest{}
+    var added []models.Guest
+    for _, email := range emails {
+        if g, ok := current[email]; ok {
+            guests = append(guests, g)
+            continue
+        }
+        token, err := utils.GenerateRandomToken(24)
+        if err != nil {
+            return nil, nil, err
+        }
+        g := models.Guest{Email: email, Token: token, InvitedAt: time.Now()}
+        guests = append(guests, g)
+        added = append(added, g)
+    }
+    return guests, added, nil
+}
+
+// reissueGuestTokens gives the guests of a copied meeting new access
+// tokens, so a token never resolves to more than one meeting
+func reissueGuestTokens(guests []models.Guest) ([]models.Guest, error) {
+    if len(guests) == 0 {
+        return guests, nil
+    }
+    reissued := make([]models.Guest, 0, len(guests))
+    for _, g := range guests {
+        token, err := utils.GenerateRandomToken(24)
+        if err != nil {
+            return nil, err
+        }
+        g.Token = token
+        reissued = append(reissued, g)
+    }
+    return reissued, nil
+}
+
+// sendGuestInvites emails each new guest their personal meeting link
+func sendGuestInvites(meeting models.Meeting, guests []models.Guest) {
+    notifyMeetingChange(notify.KindMeetingInvite, meeting, meetingRecipients{Guests: guests})
+}
+
+// GetGuestMeeting shows meeting details, including whether it was
+// cancelled, to an external guest holding a valid access token. No login is
+// required. Meetings in the trash are not found.
+func GetGuestMeeting(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    token := c.Params("token")
+    var meeting models.Meeting
+    err := config.MeetingCollectionRef.FindOne(ctx, bson.M{"guests.token": token, "deletedAt": bson.M{"$exists": false}}).Decode(&meeting)
+    if token == "" || err != nil {
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Meeting tidak ditemukan"})
+    }
+
+    var guest models.Guest
+    for _, g := range meeting.Guests {
+        if g.Token == token {
+            guest = g
+        }
+    }
+
+    var organizer models.User
+    _ = config.UserCollectionRef.FindOne(ctx, bson.M{"_id": meeting.CreatedBy}).Decode(&organizer)
+
+    return c.JSON(fiber.Map{
+        "guest":    guest.Email,
+        "response": findResponse(meeting, nil, guest.Email),
+        "meeting": fiber.Map{
+            "id":           meeting.ID,
+            "title":        meeting.Title,
+            "description":  meeting.Description,
+            "startTime":    meeting.StartTime,
+            "duration":     meeting.Duration,
+            "recurrence":   meeting.Recurrence,
+            "organizer":    organizer.Nama,
+            "status":       meetingStatus(meeting, time.Now()),
+            "cancelReason": meeting.CancelReason,
+        },
+    })
+}
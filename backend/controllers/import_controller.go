@@ -0,0 +1,277 @@
+package controllers
+
+import (
+    "context"
+    "encoding/csv"
+    "fmt"
+    "io"
+    "log"
+    "net/mail"
+    "strings"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo/options"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/utils"
+)
+
+const (
+    maxImportRows    = 1000
+    inviteExpiration = 7 * 24 * time.Hour
+)
+
+// emailCollation compares emails case-insensitively
+var emailCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// ImportRowResult describes the outcome of a single CSV row
+type ImportRowResult struct {
+    Row    int      `json:"row"`
+    Name   string   `json:"name"`
+    Email  string   `json:"email"`
+    Role   string   `json:"role"`
+    Team   string   `json:"team,omitempty"`
+    Status string   `json:"status"` // valid, created, error
+    Errors []string `json:"errors,omitempty"`
+}
+
+// ImportUsers registers users in bulk from an uploaded CSV file.
+// Columns: name, email, role, team. Use ?dryRun=true to only validate
+// and ?sendInvites=true to email each new user a link to set a password.
+func ImportUsers(c *fiber.Ctx) error {
+    dryRun := c.QueryBool("dryRun")
+    sendInvites := c.QueryBool("sendInvites")
+
+    fileHeader, err := c.FormFile("file")
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "File CSV diperlukan"})
+    }
+
+    file, err := fileHeader.Open()
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuka file"})
+    }
+    defer file.Close()
+
+    rows, err := parseImportCSV(file)
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+    defer cancel()
+
+    // Detect duplicates against existing users, ignoring case since older
+    // accounts may have been stored with mixed-case emails
+    emails := make([]string, 0, len(rows))
+    for _, row := range rows {
+        if row.Email != "" {
+            emails = append(emails, row.Email)
+        }
+    }
+    existing := map[string]bool{}
+    if len(emails) > 0 {
+        cursor, err := config.UserCollectionRef.Find(ctx, bson.M{"email": bson.M{"$in": emails}},
+            options.Find().SetCollation(emailCollation).SetProjection(bson.M{"email": 1}))
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa user yang sudah ada"})
+        }
+        var users []models.User
+        if err := cursor.All(ctx, &users); err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa user yang sudah ada"})
+        }
+        for _, user := range users {
+            existing[strings.ToLower(user.Email)] = true
+        }
+    }
+
+    created, failed := 0, 0
+    for i := range rows {
+        row := &rows[i]
+        if existing[row.Email] {
+            row.Errors = append(row.Errors, "email sudah terdaftar")
+        }
+        if len(row.Errors) > 0 {
+            row.Status = "error"
+            failed++
+            continue
+        }
+        if dryRun {
+            row.Status = "valid"
+            continue
+        }
+
+        if err := createImportedUser(ctx, row, sendInvites); err != nil {
+            log.Printf("Import row %d failed: %v", row.Row, err)
+            row.Status = "error"
+            row.Errors = append(row.Errors, "gagal menyimpan user")
+            failed++
+            continue
+        }
+        row.Status = "created"
+        created++
+    }
+
+    return c.JSON(fiber.Map{
+        "dryRun":  dryRun,
+        "total":   len(rows),
+        "valid":   len(rows) - failed,
+        "created": created,
+        "failed":  failed,
+        "rows":    rows,
+    })
+}
+
+// parseImportCSV reads the CSV and runs per-row validation that does not
+// need the database (required fields, email format, role, in-file duplicates)
+func parseImportCSV(r io.Reader) ([]ImportRowResult, error) {
+    reader := csv.NewReader(r)
+    reader.TrimLeadingSpace = true
+    reader.FieldsPerRecord = -1
+
+    header, err := reader.Read()
+    if err != nil {
+        return nil, fmt.Errorf("File CSV kosong atau tidak valid")
+    }
+
+    columns := map[string]int{}
+    for i, name := range header {
+        name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+        if name == "nama" {
+            name = "name"
+        }
+        columns[name] = i
+    }
+    if _, ok := columns["name"]; !ok {
+        return nil, fmt.Errorf("Header CSV harus memiliki kolom 'name'")
+    }
+    if _, ok := columns["email"]; !ok {
+        return nil, fmt.Errorf("Header CSV harus memiliki kolom 'email'")
+    }
+
+    field := func(record []string, column string) string {
+        i, ok := columns[column]
+        if !ok || i >= len(record) {
+            return ""
+        }
+        return strings.TrimSpace(record[i])
+    }
+
+    var rows []ImportRowResult
+    seen := map[string]int{}
+    line := 1
+    for {
+        record, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        line++
+        if len(rows) >= maxImportRows {
+            return nil, fmt.Errorf("File CSV melebihi batas %d baris", maxImportRows)
+        }
+        if err != nil {
+            rows = append(rows, ImportRowResult{Row: line, Status: "error", Errors: []string{"baris CSV tidak valid"}})
+            continue
+        }
+
+        row := ImportRowResult{
+            Row:   line,
+            Name:  field(record, "name"),
+            Email: strings.ToLower(field(record, "email")),
+            Role:  field(record, "role"),
+            Team:  field(record, "team"),
+        }
+
+        if row.Name == "" {
+            row.Errors = append(row.Errors, "nama diperlukan")
+        }
+        if row.Email == "" {
+            row.Errors = append(row.Errors, "email diperlukan")
+        } else if addr, err := mail.ParseAddress(row.Email); err != nil || addr.Address != row.Email {
+            row.Errors = append(row.Errors, "email tidak valid")
+        } else if first, ok := seen[row.Email]; ok {
+            row.Errors = append(row.Errors, fmt.Sprintf("email duplikat dalam file (baris %d)", first))
+        } else {
+            seen[row.Email] = line
+        }
+
+        switch strings.ToLower(row.Role) {
+        case "", strings.ToLower(models.RoleUser):
+            row.Role = models.RoleUser
+        case strings.ToLower(models.RoleAdmin):
+            row.Role = models.RoleAdmin
+        default:
+            row.Errors = append(row.Errors, fmt.Sprintf("role '%s' tidak dikenal", row.Role))
+        }
+
+        rows = append(rows, row)
+    }
+
+    if len(rows) == 0 {
+        return nil, fmt.Errorf("File CSV tidak memiliki data")
+    }
+    return rows, nil
+}
+
+// createImportedUser inserts a user without a password. The user sets one
+// through the invite link before being able to log in.
+func createImportedUser(ctx context.Context, row *ImportRowResult, sendInvite bool) error {
+    user, err := newInvitedUser(row.Name, row.Email, row.Role, row.Team)
+    if err != nil {
+        return err
+    }
+
+    if user.Team != "" {
+        if _, err := ensureTeam(ctx, user.Team); err != nil {
+            return err
+        }
+    }
+
+    if _, err := config.UserCollectionRef.InsertOne(ctx, user); err != nil {
+        return err
+    }
+
+    if sendInvite {
+        sendInviteEmail(user)
+    }
+
+    return nil
+}
+
+// newInvitedUser builds a password-less user carrying a fresh invite token
+func newInvitedUser(name, email, role, team string) (models.User, error) {
+    token, err := utils.GenerateRandomToken(32)
+    if err != nil {
+        return models.User{}, err
+    }
+
+    now := time.Now()
+    return models.User{
+        ID:              primitive.NewObjectID(),
+        Nama:            name,
+        Email:           email,
+        Role:            role,
+        Team:            team,
+        InviteToken:     token,
+        InviteExpiresAt: now.Add(inviteExpiration),
+        CreatedAt:       now,
+        UpdatedAt:       now,
+    }, nil
+}
+
+// sendInviteEmail emails the user a link to set their password
+func sendInviteEmail(user models.User) {
+    link := fmt.Sprintf("%s/accept-invite?token=%s", utils.FrontendURL(), user.InviteToken)
+    body := fmt.Sprintf(
+        "Hi %s,\n\nAn account has been created for you on PBO-MMO.\nSet your password using the link below (valid for 7 days):\n\n%s\n",
+        user.Nama, link,
+    )
+    if err := utils.SendMail([]string{user.Email}, "You're invited to PBO-MMO", body); err != nil {
+        // The account exists; the admin can resend the invite later
+        log.Printf("Failed to send invite to %s: %v", user.Email, err)
+    }
+}
@@ -2,6 +2,10 @@ package controllers
 
 import (
     "context"
+    "errors"
+    "fmt"
+    "log"
+    "strings"
     "time"
 
     "github.com/gofiber/fiber/v2"
@@ -11,6 +15,8 @@ import (
 
     "pbommo/config"
     "pbommo/models"
+    "pbommo/notify"
+    "pbommo/realtime"
     "pbommo/utils"
 )
 
@@ -19,10 +25,12 @@ func CreateMeeting(c *fiber.Ctx) error {
     ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
     defer cancel()
 
-    var meeting models.Meeting
-    if err := c.BodyParser(&meeting); err != nil {
+    var input meetingInput
+    if err := c.BodyParser(&input); err != nil {
         return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
     }
+    meeting := input.Meeting
+    meeting.AgendaProposals = input.AgendaProposals != nil && *input.AgendaProposals
 
     // Get user ID from JWT token
     userID, err := utils.GetUserIDFromToken(c)
@@ -30,12 +38,42 @@ func CreateMeeting(c *fiber.Ctx) error {
         return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
     }
 
+    // Resolve participant ids and emails; unknown emails become guests
+    var newGuests []models.Guest
+    meeting.Participants = nil
+    meeting.Guests = nil
+    if input.Participants != nil {
+        participants, err := resolveParticipants(ctx, input.Participants, userID)
+        if err != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+        }
+        meeting.Participants = participants.UserIDs
+        meeting.Guests, newGuests, err = mergeGuests(nil, participants.GuestEmails)
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat meeting"})
+        }
+    }
+
     // Set meeting metadata
     meeting.ID = primitive.NewObjectID()
     meeting.CreatedBy = userID
     meeting.CreatedAt = time.Now()
     meeting.UpdatedAt = time.Now()
 
+    // The lifecycle is driven by the start, end and cancel endpoints
+    meeting.Status = models.MeetingScheduled
+    meeting.ActualStart = nil
+    meeting.ActualEnd = nil
+    meeting.CancelledAt = nil
+    meeting.CancelReason = ""
+    meeting.DeletedAt = nil
+
+    // Responses come from the invitees; sequence and UID from the server
+    // and calendar imports
+    meeting.Responses = nil
+    meeting.Sequence = 0
+    meeting.ICalUID = ""
+
     // Validate required fields
     if meeting.Title == "" {
         return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Judul meeting diperlukan"})
@@ -49,6 +87,55 @@ func CreateMeeting(c *fiber.Ctx) error {
         meeting.Duration = 60 // Default 60 minutes
     }
 
+    if !validEmotionVisibility(meeting.EmotionVisibility) {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "emotionVisibility harus organizer atau aggregate"})
+    }
+
+    meeting.AgendaVersion = 0
+    if err := prepareAgenda(&meeting); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    // Occurrence fields are managed by the server
+    meeting.SeriesID = nil
+    meeting.RecurrenceID = nil
+    if meeting.Recurrence != nil {
+        if _, _, err := parseRecurrence(meeting.Recurrence); err != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+        }
+    }
+
+    // A follow-up takes over the open action items of a meeting by the same
+    // organizer
+    if meeting.FollowUpOf != nil {
+        var source models.Meeting
+        err := config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": *meeting.FollowUpOf}).Decode(&source)
+        if err == mongo.ErrNoDocuments {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Meeting asal tidak ditemukan"})
+        }
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data meeting"})
+        }
+        if source.CreatedBy != userID {
+            return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya pembuat meeting asal yang dapat membuat follow-up"})
+        }
+    }
+
+    // Check the organizer's and participants' calendars
+    conflicts, err := findConflicts(ctx, meeting)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa jadwal"})
+    }
+    if errResp := conflictResponse(c, conflicts, input.Force || c.QueryBool("force")); errResp != nil {
+        return errResp()
+    }
+
+    // Invitees outside their working time or away only produce warnings
+    warnings, err := availabilityWarnings(ctx, meeting)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa jadwal"})
+    }
+
     // Insert meeting into database
     result, err := config.MeetingCollectionRef.InsertOne(ctx, meeting)
     if err != nil {
@@ -56,14 +143,31 @@ func CreateMeeting(c *fiber.Ctx) error {
     }
 
     meeting.ID = result.InsertedID.(primitive.ObjectID)
+    var carriedOver int64
+    if meeting.FollowUpOf != nil {
+        carriedOver, err = carryOverActionItems(ctx, *meeting.FollowUpOf, meeting)
+        if err != nil {
+            log.Printf("Failed to carry over action items to meeting %s: %v", meeting.ID.Hex(), err)
+        }
+    }
+    notifyMeetingChange(notify.KindMeetingInvite, meeting, meetingRecipients{UserIDs: meeting.Participants, Guests: newGuests})
+    notifyMentions(meeting, userID, meeting.Description, "")
+    requestEmotionConsent(meeting, meeting.Participants)
+    publishMeetingEvent(realtime.EventMeetingCreated, meeting)
 
     return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-        "message": "Meeting berhasil dibuat",
-        "meeting": meeting,
+        "message":     "Meeting berhasil dibuat",
+        "meeting":     meeting,
+        "conflicts":   conflicts,
+        "warnings":    warnings,
+        "carriedOver": carriedOver,
     })
 }
 
-// GetMeetings gets all meetings with optional filtering
+// GetMeetings gets all meetings with optional filtering.
+// With a date range (?date= or ?from=&to=) recurring meetings are expanded
+// into their individual occurrences. ?status= keeps meetings in the given
+// comma-separated lifecycle statuses.
 func GetMeetings(c *fiber.Ctx) error {
     ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
     defer cancel()
@@ -80,18 +184,39 @@ func GetMeetings(c *fiber.Ctx) error {
             {"createdBy": userID},
             {"participants": userID},
         },
+        "deletedAt": bson.M{"$exists": false},
     }
 
-    // Optional date filter
-    if dateStr := c.Query("date"); dateStr != "" {
-        date, err := time.Parse("2006-01-02", dateStr)
-        if err == nil {
-            startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-            endOfDay := startOfDay.Add(24 * time.Hour)
-            filter["startTime"] = bson.M{
-                "$gte": startOfDay,
-                "$lt":  endOfDay,
-            }
+    // Optional filter on the user's own RSVP
+    if status := c.Query("response"); status != "" {
+        if !validResponseStatus(status) {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Parameter response tidak valid"})
+        }
+        filter = bson.M{"$and": []bson.M{filter, myResponseFilter(userID, status)}}
+    }
+
+    statuses := map[string]bool{}
+    for _, status := range splitList(c.Query("status")) {
+        if !validMeetingStatus(status) {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Parameter status tidak valid"})
+        }
+        statuses[status] = true
+    }
+
+    // Optional date range filter
+    from, to, hasRange, err := parseMeetingRange(c)
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+    }
+    if hasRange {
+        filter = bson.M{
+            "$and": []bson.M{
+                filter,
+                {"$or": []bson.M{
+                    {"recurrence": bson.M{"$exists": false}, "startTime": bson.M{"$gte": from, "$lt": to}},
+                    {"recurrence": bson.M{"$exists": true}, "startTime": bson.M{"$lt": to}},
+                }},
+            },
         }
     }
 
@@ -106,11 +231,69 @@ func GetMeetings(c *fiber.Ctx) error {
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memproses data meeting"})
     }
 
+    if hasRange {
+        meetings, err = expandMeetings(ctx, meetings, from, to)
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memproses data meeting"})
+        }
+    }
+
+    // Statuses depend on the time, so they are derived and filtered here
+    now := time.Now()
+    filtered := make([]models.Meeting, 0, len(meetings))
+    for _, m := range meetings {
+        m.Status = meetingStatus(m, now)
+        if len(statuses) == 0 || statuses[m.Status] {
+            filtered = append(filtered, m)
+        }
+    }
+
     return c.JSON(fiber.Map{
-        "meetings": meetings,
+        "meetings": filtered,
     })
 }
 
+// parseMeetingRange reads ?date=YYYY-MM-DD or ?from=&to= (RFC3339 or YYYY-MM-DD)
+func parseMeetingRange(c *fiber.Ctx) (time.Time, time.Time, bool, error) {
+    if dateStr := c.Query("date"); dateStr != "" {
+        date, err := time.Parse("2006-01-02", dateStr)
+        if err != nil {
+            // Invalid dates were always ignored
+            return time.Time{}, time.Time{}, false, nil
+        }
+        startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+        return startOfDay, startOfDay.Add(24 * time.Hour), true, nil
+    }
+
+    fromStr, toStr := c.Query("from"), c.Query("to")
+    if fromStr == "" && toStr == "" {
+        return time.Time{}, time.Time{}, false, nil
+    }
+    if fromStr == "" || toStr == "" {
+        return time.Time{}, time.Time{}, false, errors.New("Parameter from dan to harus diisi bersamaan")
+    }
+
+    parse := func(value string) (time.Time, error) {
+        value = strings.ReplaceAll(value, " ", "+")
+        if t, err := time.Parse(time.RFC3339, value); err == nil {
+            return t, nil
+        }
+        return time.Parse("2006-01-02", value)
+    }
+    from, err := parse(fromStr)
+    if err != nil {
+        return time.Time{}, time.Time{}, false, errors.New("Parameter from tidak valid")
+    }
+    to, err := parse(toStr)
+    if err != nil || !to.After(from) {
+        return time.Time{}, time.Time{}, false, errors.New("Parameter to tidak valid")
+    }
+    if to.Sub(from) > 366*24*time.Hour {
+        return time.Time{}, time.Time{}, false, errors.New("Rentang tanggal maksimal 1 tahun")
+    }
+    return from, to, true, nil
+}
+
 // GetMeetingById gets a specific meeting by ID
 func GetMeetingById(c *fiber.Ctx) error {
     ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -149,6 +332,11 @@ func GetMeetingById(c *fiber.Ctx) error {
     if !hasAccess {
         return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Akses ditolak"})
     }
+    // Only the organizer still sees a meeting in the trash
+    if meeting.DeletedAt != nil && meeting.CreatedBy != userID {
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Meeting tidak ditemukan"})
+    }
+    meeting.Status = meetingStatus(meeting, time.Now())
 
     return c.JSON(fiber.Map{
         "meeting": meeting,
@@ -174,7 +362,7 @@ func UpdateMeeting(c *fiber.Ctx) error {
 
     // Check if meeting exists and user is the creator
     var existingMeeting models.Meeting
-    err = config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": meetingID}).Decode(&existingMeeting)
+    err = config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": meetingID, "deletedAt": bson.M{"$exists": false}}).Decode(&existingMeeting)
     if err != nil {
         if err == mongo.ErrNoDocuments {
             return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Meeting tidak ditemukan"})
@@ -186,17 +374,22 @@ func UpdateMeeting(c *fiber.Ctx) error {
     if existingMeeting.CreatedBy != userID {
         return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya pembuat meeting yang dapat mengubah"})
     }
+    if existingMeeting.Status == models.MeetingCancelled {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Meeting sudah dibatalkan, pulihkan terlebih dahulu"})
+    }
 
-    var updateData models.Meeting
-    if err := c.BodyParser(&updateData); err != nil {
+    var input meetingInput
+    if err := c.BodyParser(&input); err != nil {
         return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
     }
+    updateData := input.Meeting
 
     // Prepare update document
     update := bson.M{
         "$set": bson.M{
             "updatedAt": time.Now(),
         },
+        "$inc": bson.M{"sequence": 1},
     }
 
     // Update fields if provided
@@ -210,12 +403,82 @@ func UpdateMeeting(c *fiber.Ctx) error {
         update["$set"].(bson.M)["startTime"] = updateData.StartTime
     }
     if updateData.Duration > 0 {
+        if total := agendaMinutes(existingMeeting.Agenda); total > updateData.Duration {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Durasi lebih pendek dari total waktu agenda (%d menit)", total)})
+        }
         update["$set"].(bson.M)["duration"] = updateData.Duration
     }
-    if updateData.Participants != nil {
-        update["$set"].(bson.M)["participants"] = updateData.Participants
+    if input.AgendaProposals != nil {
+        update["$set"].(bson.M)["agendaProposals"] = *input.AgendaProposals
+    }
+    updated := existingMeeting
+    applyMeetingUpdate(&updated, updateData, input.AgendaProposals)
+
+    if input.Participants != nil {
+        participants, err := resolveParticipants(ctx, input.Participants, existingMeeting.CreatedBy)
+        if err != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+        }
+        guests, _, err := mergeGuests(existingMeeting.Guests, participants.GuestEmails)
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengupdate meeting"})
+        }
+        update["$set"].(bson.M)["participants"] = participants.UserIDs
+        update["$set"].(bson.M)["guests"] = guests
+        update["$set"].(bson.M)["responses"] = pruneResponses(existingMeeting.Responses, participants.UserIDs, guests)
+        updated.Participants = participants.UserIDs
+        updated.Guests = guests
     }
     update["$set"].(bson.M)["emotionTracking"] = updateData.EmotionTracking
+    if updateData.EmotionVisibility != "" {
+        if !validEmotionVisibility(updateData.EmotionVisibility) {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "emotionVisibility harus organizer atau aggregate"})
+        }
+        update["$set"].(bson.M)["emotionVisibility"] = updateData.EmotionVisibility
+    }
+    if updateData.Recurrence != nil {
+        if existingMeeting.SeriesID != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Occurrence tidak dapat dijadikan berulang"})
+        }
+        if updateData.Recurrence.RRule == "" {
+            // Empty rule turns the series back into a single meeting
+            update["$unset"] = bson.M{"recurrence": ""}
+        } else {
+            if _, _, err := parseRecurrence(updateData.Recurrence); err != nil {
+                return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+            }
+            update["$set"].(bson.M)["recurrence.rrule"] = updateData.Recurrence.RRule
+            update["$set"].(bson.M)["recurrence.timezone"] = updateData.Recurrence.Timezone
+            if updateData.Recurrence.ExDates != nil {
+                update["$set"].(bson.M)["recurrence.exDates"] = updateData.Recurrence.ExDates
+            }
+        }
+        updated.Recurrence = nil
+        if updateData.Recurrence.RRule != "" {
+            recurrence := *updateData.Recurrence
+            if recurrence.ExDates == nil && existingMeeting.Recurrence != nil {
+                recurrence.ExDates = existingMeeting.Recurrence.ExDates
+            }
+            updated.Recurrence = &recurrence
+        }
+    }
+
+    // Re-check conflicts only when the schedule or attendees change
+    conflicts := []Conflict{}
+    warnings := []AvailabilityWarning{}
+    if !updateData.StartTime.IsZero() || updateData.Duration > 0 || input.Participants != nil || updateData.Recurrence != nil {
+        conflicts, err = findConflicts(ctx, updated)
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa jadwal"})
+        }
+        if errResp := conflictResponse(c, conflicts, input.Force || c.QueryBool("force")); errResp != nil {
+            return errResp()
+        }
+        warnings, err = availabilityWarnings(ctx, updated)
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa jadwal"})
+        }
+    }
 
     result, err := config.MeetingCollectionRef.UpdateOne(ctx, bson.M{"_id": meetingID}, update)
     if err != nil {
@@ -225,13 +488,35 @@ func UpdateMeeting(c *fiber.Ctx) error {
     if result.MatchedCount == 0 {
         return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Meeting tidak ditemukan"})
     }
+    // Occurrences of a series made single go to the trash
+    if existingMeeting.Recurrence != nil && updated.Recurrence == nil {
+        if err := trashOccurrences(ctx, bson.M{"seriesId": meetingID}, time.Now()); err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengupdate meeting"})
+        }
+    }
+
+    added, removed := notifyMeetingUpdate(existingMeeting, updated)
+    notifyMentions(updated, userID, updated.Description, existingMeeting.Description)
+    // Tracking never starts silently: whoever it newly applies to is asked to opt in
+    if existingMeeting.EmotionTracking {
+        requestEmotionConsent(updated, added.UserIDs)
+    } else {
+        requestEmotionConsent(updated, updated.Participants)
+    }
+    publishMeetingEvent(realtime.EventMeetingUpdated, updated)
+    realtime.Publish(realtime.EventMeetingDeleted, removed.UserIDs, &meetingID, existingMeeting)
 
     return c.JSON(fiber.Map{
-        "message": "Meeting berhasil diupdate",
+        "message":   "Meeting berhasil diupdate",
+        "conflicts": conflicts,
+        "warnings":  warnings,
     })
 }
 
-// DeleteMeeting deletes a meeting
+// DeleteMeeting moves a meeting, in any status, to the organizer's trash,
+// from where it can be restored until it is purged. Deleting a series takes
+// its occurrences with it; one occurrence is deleted with ?start=. Attendees
+// of a meeting still to come are told it is cancelled.
 func DeleteMeeting(c *fiber.Ctx) error {
     ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
     defer cancel()
@@ -248,29 +533,40 @@ func DeleteMeeting(c *fiber.Ctx) error {
         return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
     }
 
-    // Check if meeting exists and user is the creator
-    var existingMeeting models.Meeting
-    err = config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": meetingID}).Decode(&existingMeeting)
+    var meeting models.Meeting
+    err = config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": meetingID, "deletedAt": bson.M{"$exists": false}}).Decode(&meeting)
     if err != nil {
         if err == mongo.ErrNoDocuments {
             return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Meeting tidak ditemukan"})
         }
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data meeting"})
     }
-
-    // Check if user is the creator
-    if existingMeeting.CreatedBy != userID {
+    if meeting.CreatedBy != userID {
         return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya pembuat meeting yang dapat menghapus"})
     }
+    meeting, _, errResp := selectOccurrence(c, ctx, meeting, true)
+    if errResp != nil {
+        return errResp()
+    }
 
-    result, err := config.MeetingCollectionRef.DeleteOne(ctx, bson.M{"_id": meetingID})
+    now := time.Now()
+    err = trashMeeting(ctx, meeting, now)
+    if err == mongo.ErrNoDocuments {
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Meeting tidak ditemukan"})
+    }
     if err != nil {
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus meeting"})
     }
 
-    if result.DeletedCount == 0 {
-        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Meeting tidak ditemukan"})
+    switch meetingStatus(meeting, now) {
+    case models.MeetingScheduled, models.MeetingLive:
+        notifyMeetingChange(notify.KindMeetingCancelled, meeting, meetingRecipients{
+            UserIDs: meeting.Participants,
+            Guests:  meeting.Guests,
+        })
     }
+    meeting.DeletedAt = &now
+    publishMeetingEvent(realtime.EventMeetingDeleted, meeting)
 
     return c.JSON(fiber.Map{
         "message": "Meeting berhasil dihapus",
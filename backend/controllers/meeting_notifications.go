@@ -0,0 +1,228 @@
+package controllers
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/notify"
+    "pbommo/utils"
+)
+
+// meetingRecipients are the people a meeting notification goes to
+type meetingRecipients struct {
+    UserIDs []primitive.ObjectID
+    Guests  []models.Guest
+}
+
+// notifyMeetingChange tells the recipients about a created, updated or
+// cancelled meeting in the background. Registered users get an inbox entry,
+// and an email when their email notifications are on; each email carries
+// an iCalendar REQUEST or CANCEL so calendar clients update the event.
+func notifyMeetingChange(kind string, meeting models.Meeting, recipients meetingRecipients) {
+    if len(recipients.UserIDs) == 0 && len(recipients.Guests) == 0 {
+        return
+    }
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+        defer cancel()
+
+        users, err := usersByID(ctx, recipients.UserIDs)
+        if err != nil {
+            log.Printf("Failed to load recipients of meeting %s: %v", meeting.ID.Hex(), err)
+            return
+        }
+        if err := saveMeetingNotifications(ctx, kind, meeting, users, recipients.UserIDs); err != nil {
+            log.Printf("Failed to save %s notifications for meeting %s: %v", kind, meeting.ID.Hex(), err)
+        }
+        if err := sendMeetingEmails(ctx, kind, meeting, recipients, users); err != nil {
+            log.Printf("Failed to send %s emails for meeting %s: %v", kind, meeting.ID.Hex(), err)
+        }
+    }()
+}
+
+func sendMeetingEmails(ctx context.Context, kind string, meeting models.Meeting, recipients meetingRecipients, users map[primitive.ObjectID]models.User) error {
+    ch, ok := notify.Lookup("email")
+    if !ok {
+        return fmt.Errorf("email channel not registered")
+    }
+
+    event, err := meetingEvent(ctx, meeting)
+    if err != nil {
+        return err
+    }
+
+    var organizer models.User
+    _ = config.UserCollectionRef.FindOne(ctx, bson.M{"_id": meeting.CreatedBy}).Decode(&organizer)
+
+    send := func(user models.User, link string) {
+        if !ch.Accepts(user) {
+            return
+        }
+        msg, err := meetingEmailMessage(kind, meeting, event, organizer, user, link)
+        if err != nil {
+            log.Printf("Failed to render %s email: %v", kind, err)
+            return
+        }
+        if err := ch.Send(ctx, msg); err != nil {
+            log.Printf("Failed to send %s email to %s: %v", kind, user.Email, err)
+        }
+    }
+
+    for _, id := range recipients.UserIDs {
+        if user, ok := users[id]; ok && !user.Disabled {
+            send(user, utils.FrontendURL()+"/meetings")
+        }
+    }
+    for _, g := range recipients.Guests {
+        // Guests have no account; their personal link replaces the login
+        link := ""
+        if kind != notify.KindMeetingCancelled {
+            link = fmt.Sprintf("%s/guest/meetings/%s", utils.FrontendURL(), g.Token)
+        }
+        send(models.User{Nama: g.Email, Email: g.Email}, link)
+    }
+    return nil
+}
+
+// meetingEmailMessage renders the email about a meeting for one recipient,
+// with the invitation attached
+func meetingEmailMessage(kind string, meeting models.Meeting, event utils.ICalEvent, organizer, user models.User, link string) (notify.Message, error) {
+    subject, text, html, err := notify.RenderMeetingEmail(kind, meetingEmail(meeting, organizer, user, link))
+    if err != nil {
+        return notify.Message{}, err
+    }
+    return notify.Message{
+        Kind:        "meeting_" + kind,
+        User:        user,
+        Subject:     subject,
+        Text:        text,
+        HTML:        html,
+        Link:        link,
+        Meeting:     &meeting,
+        Attachments: []utils.MailAttachment{invitationAttachment(kind, event, user.Email)},
+    }, nil
+}
+
+// meetingEmail fills the template data in the recipient's language and timezone
+func meetingEmail(meeting models.Meeting, organizer, recipient models.User, link string) notify.MeetingEmail {
+    email := notify.MeetingEmail{
+        Lang:        recipient.Language,
+        Recipient:   recipient.Nama,
+        Organizer:   organizer.Nama,
+        Title:       meeting.Title,
+        Description: meeting.Description,
+        Start:       meeting.StartTime.In(userLocation(recipient)),
+        Duration:    meeting.Duration,
+        Reason:      meeting.CancelReason,
+        Link:        link,
+    }
+    if meeting.Recurrence != nil {
+        email.Recurrence = meeting.Recurrence.RRule
+    }
+    return email
+}
+
+// meetingEvent builds the iCalendar event sent with a meeting email
+func meetingEvent(ctx context.Context, meeting models.Meeting) (utils.ICalEvent, error) {
+    events, err := meetingsToICalEvents(ctx, []models.Meeting{meeting})
+    if err != nil {
+        return utils.ICalEvent{}, err
+    }
+    return events[0], nil
+}
+
+// invitationAttachment builds the iCalendar attachment for one recipient:
+// METHOD:REQUEST for invites and updates, METHOD:CANCEL for cancellations.
+// A CANCEL only lists its recipient, so people removed from a meeting do
+// not learn who still attends it.
+func invitationAttachment(kind string, event utils.ICalEvent, recipient string) utils.MailAttachment {
+    method := "REQUEST"
+    if kind == notify.KindMeetingCancelled {
+        method = "CANCEL"
+        event.Status = "CANCELLED"
+        event.Sequence++
+        event.Attendees = []string{recipient}
+    }
+    return utils.MailAttachment{
+        Filename:    "invite.ics",
+        ContentType: "text/calendar; charset=utf-8; method=" + method,
+        Data:        []byte(utils.BuildICalendar(method, []utils.ICalEvent{event})),
+    }
+}
+
+// meetingChanged reports whether an update changed anything the meeting
+// emails and invitations show
+func meetingChanged(before, after models.Meeting) bool {
+    if before.Title != after.Title || before.Description != after.Description ||
+        !before.StartTime.Equal(after.StartTime) || before.Duration != after.Duration {
+        return true
+    }
+    if (before.Recurrence == nil) != (after.Recurrence == nil) {
+        return true
+    }
+    return before.Recurrence != nil &&
+        (before.Recurrence.RRule != after.Recurrence.RRule || before.Recurrence.Timezone != after.Recurrence.Timezone)
+}
+
+// notifyMeetingUpdate tells new attendees they are invited, kept ones about
+// the change (if anything they see changed) and removed ones that the
+// meeting is cancelled for them. It returns who was added and removed.
+func notifyMeetingUpdate(before, after models.Meeting) (added, removed meetingRecipients) {
+    added, kept, removed := diffRecipients(before, after)
+    notifyMeetingChange(notify.KindMeetingInvite, after, added)
+    if meetingChanged(before, after) {
+        notifyMeetingChange(notify.KindMeetingUpdated, after, kept)
+    }
+    notifyMeetingChange(notify.KindMeetingCancelled, before, removed)
+    return added, removed
+}
+
+// diffRecipients splits the attendees of a meeting before and after an
+// update into those invited, kept and removed
+func diffRecipients(before, after models.Meeting) (added, kept, removed meetingRecipients) {
+    wasParticipant := map[primitive.ObjectID]bool{}
+    for _, id := range before.Participants {
+        wasParticipant[id] = true
+    }
+    isParticipant := map[primitive.ObjectID]bool{}
+    for _, id := range after.Participants {
+        isParticipant[id] = true
+        if wasParticipant[id] {
+            kept.UserIDs = append(kept.UserIDs, id)
+        } else {
+            added.UserIDs = append(added.UserIDs, id)
+        }
+    }
+    for _, id := range before.Participants {
+        if !isParticipant[id] {
+            removed.UserIDs = append(removed.UserIDs, id)
+        }
+    }
+
+    wasGuest := map[string]bool{}
+    for _, g := range before.Guests {
+        wasGuest[g.Email] = true
+    }
+    isGuest := map[string]bool{}
+    for _, g := range after.Guests {
+        isGuest[g.Email] = true
+        if wasGuest[g.Email] {
+            kept.Guests = append(kept.Guests, g)
+        } else {
+            added.Guests = append(added.Guests, g)
+        }
+    }
+    for _, g := range before.Guests {
+        if !isGuest[g.Email] {
+            removed.Guests = append(removed.Guests, g)
+        }
+    }
+    return added, kept, removed
+}
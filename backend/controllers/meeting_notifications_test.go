@@ -0,0 +1,256 @@
+package controllers
+
+import (
+    "bufio"
+    "context"
+    "encoding/base64"
+    "io"
+    "mime"
+    "mime/multipart"
+    "net"
+    "net/mail"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson/primitive"
+
+    "pbommo/models"
+    "pbommo/notify"
+    "pbommo/utils"
+)
+
+// smtpSink is a minimal local SMTP server keeping every message it receives
+type smtpSink struct {
+    listener net.Listener
+    mu       sync.Mutex
+    messages []string
+}
+
+func startSMTPSink(t *testing.T) *smtpSink {
+    t.Helper()
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("listen: %v", err)
+    }
+    sink := &smtpSink{listener: listener}
+    go func() {
+        for {
+            conn, err := listener.Accept()
+            if err != nil {
+                return
+            }
+            go sink.serve(conn)
+        }
+    }()
+    t.Cleanup(func() { listener.Close() })
+
+    host, port, _ := net.SplitHostPort(listener.Addr().String())
+    t.Setenv("SMTP_HOST", host)
+    t.Setenv("SMTP_PORT", port)
+    t.Setenv("SMTP_USER", "")
+    return sink
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+    defer conn.Close()
+    r := bufio.NewReader(conn)
+    reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+    reply("220 sink ready")
+    for {
+        line, err := r.ReadString('\n')
+        if err != nil {
+            return
+        }
+        switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+        case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+            reply("250 sink")
+        case cmd == "DATA":
+            reply("354 end with .")
+            var data strings.Builder
+            for {
+                line, err := r.ReadString('\n')
+                if err != nil {
+                    return
+                }
+                if line == ".\r\n" {
+                    break
+                }
+                data.WriteString(strings.TrimPrefix(line, "."))
+            }
+            s.mu.Lock()
+            s.messages = append(s.messages, data.String())
+            s.mu.Unlock()
+            reply("250 queued")
+        case cmd == "QUIT":
+            reply("221 bye")
+            return
+        default:
+            reply("250 OK")
+        }
+    }
+}
+
+func (s *smtpSink) received() []string {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]string(nil), s.messages...)
+}
+
+// mailParts returns the decoded leaf parts of a MIME message by content type
+func mailParts(t *testing.T, raw string) (*mail.Message, map[string]string) {
+    t.Helper()
+    msg, err := mail.ReadMessage(strings.NewReader(raw))
+    if err != nil {
+        t.Fatalf("parse message: %v", err)
+    }
+    parts := map[string]string{}
+    var walk func(contentType string, body io.Reader)
+    walk = func(contentType string, body io.Reader) {
+        mediaType, params, err := mime.ParseMediaType(contentType)
+        if err != nil {
+            t.Fatalf("parse content type %q: %v", contentType, err)
+        }
+        if !strings.HasPrefix(mediaType, "multipart/") {
+            data, _ := io.ReadAll(body)
+            parts[mediaType+";"+params["method"]] = string(data)
+            return
+        }
+        mr := multipart.NewReader(body, params["boundary"])
+        for {
+            part, err := mr.NextRawPart()
+            if err == io.EOF {
+                return
+            }
+            if err != nil {
+                t.Fatalf("read part: %v", err)
+            }
+            var body io.Reader = part
+            if part.Header.Get("Content-Transfer-Encoding") == "base64" {
+                body = base64.NewDecoder(base64.StdEncoding, part)
+            }
+            walk(part.Header.Get("Content-Type"), body)
+        }
+    }
+    walk(msg.Header.Get("Content-Type"), msg.Body)
+    return msg, parts
+}
+
+func testMeeting() models.Meeting {
+    return models.Meeting{
+        ID:        primitive.NewObjectID(),
+        Title:     "Sprint review",
+        StartTime: time.Date(2026, 3, 2, 3, 0, 0, 0, time.UTC),
+        Duration:  45,
+        CreatedBy: primitive.NewObjectID(),
+    }
+}
+
+func testEvent(meeting models.Meeting) utils.ICalEvent {
+    return utils.ICalEvent{
+        UID:       meeting.ID.Hex() + "@pbommo",
+        Start:     meeting.StartTime,
+        End:       meeting.StartTime.Add(45 * time.Minute),
+        Summary:   meeting.Title,
+        Organizer: "owner@example.com",
+        Attendees: []string{"stays@example.com", "removed@example.com", "guest@example.org"},
+    }
+}
+
+func TestMeetingEmailDeliveredToSMTPSink(t *testing.T) {
+    sink := startSMTPSink(t)
+    meeting := testMeeting()
+    organizer := models.User{Nama: "Owner", Email: "owner@example.com"}
+    user := models.User{Nama: "Removed", Email: "removed@example.com", Language: "en", Timezone: "Asia/Jakarta"}
+
+    msg, err := meetingEmailMessage(notify.KindMeetingCancelled, meeting, testEvent(meeting), organizer, user, "")
+    if err != nil {
+        t.Fatalf("render: %v", err)
+    }
+    if err := (notify.EmailChannel{}).Send(context.Background(), msg); err != nil {
+        t.Fatalf("send: %v", err)
+    }
+
+    messages := sink.received()
+    if len(messages) != 1 {
+        t.Fatalf("sink received %d messages, want 1", len(messages))
+    }
+    header, parts := mailParts(t, messages[0])
+    if to := header.Header.Get("To"); to != user.Email {
+        t.Errorf("To = %q, want %q", to, user.Email)
+    }
+    subject, _ := new(mime.WordDecoder).DecodeHeader(header.Header.Get("Subject"))
+    if !strings.Contains(subject, meeting.Title) {
+        t.Errorf("Subject %q does not name the meeting", subject)
+    }
+    if _, ok := parts["text/plain;"]; !ok {
+        t.Errorf("missing text part, got %v", keys(parts))
+    }
+    if _, ok := parts["text/html;"]; !ok {
+        t.Errorf("missing HTML part, got %v", keys(parts))
+    }
+
+    ics, ok := parts["text/calendar;CANCEL"]
+    if !ok {
+        t.Fatalf("missing CANCEL invitation, got %v", keys(parts))
+    }
+    for _, want := range []string{"METHOD:CANCEL", "STATUS:CANCELLED", "removed@example.com"} {
+        if !strings.Contains(ics, want) {
+            t.Errorf("invitation lacks %q:\n%s", want, ics)
+        }
+    }
+    for _, other := range []string{"stays@example.com", "guest@example.org"} {
+        if strings.Contains(ics, other) {
+            t.Errorf("CANCEL to a removed attendee lists %s:\n%s", other, ics)
+        }
+    }
+}
+
+func TestInvitationAttachmentRequestListsAttendees(t *testing.T) {
+    event := testEvent(testMeeting())
+    ics := string(invitationAttachment(notify.KindMeetingUpdated, event, "stays@example.com").Data)
+    if !strings.Contains(ics, "METHOD:REQUEST") {
+        t.Errorf("update is not a REQUEST:\n%s", ics)
+    }
+    for _, attendee := range event.Attendees {
+        if !strings.Contains(ics, attendee) {
+            t.Errorf("REQUEST lacks attendee %s", attendee)
+        }
+    }
+}
+
+func TestMeetingChanged(t *testing.T) {
+    before := testMeeting()
+    if meetingChanged(before, before) {
+        t.Error("identical meetings reported as changed")
+    }
+
+    participantsOnly := before
+    participantsOnly.Participants = []primitive.ObjectID{primitive.NewObjectID()}
+    participantsOnly.EmotionTracking = true
+    if meetingChanged(before, participantsOnly) {
+        t.Error("attendee and settings changes should not send an update email")
+    }
+
+    moved := before
+    moved.StartTime = before.StartTime.Add(time.Hour)
+    if !meetingChanged(before, moved) {
+        t.Error("moved meeting not reported as changed")
+    }
+
+    recurring := before
+    recurring.Recurrence = &models.Recurrence{RRule: "FREQ=WEEKLY"}
+    if !meetingChanged(before, recurring) {
+        t.Error("new recurrence not reported as changed")
+    }
+}
+
+func keys(m map[string]string) []string {
+    var out []string
+    for k := range m {
+        out = append(out, k)
+    }
+    return out
+}
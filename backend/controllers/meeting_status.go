@@ -0,0 +1,267 @@
+package controllers
+
+import (
+    "context"
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/notify"
+    "pbommo/realtime"
+    "pbommo/utils"
+)
+
+const (
+    // meetingEarlyStart is how long before its scheduled start a meeting may be started
+    meetingEarlyStart = 30 * time.Minute
+    maxCancelReason   = 500
+)
+
+// meetingTransitions lists the statuses each status may move to
+var meetingTransitions = map[string][]string{
+    models.MeetingScheduled: {models.MeetingLive, models.MeetingCancelled},
+    models.MeetingLive:      {models.MeetingCompleted},
+}
+
+// StartMeeting marks a scheduled meeting as live
+func StartMeeting(c *fiber.Ctx) error {
+    return transitionMeeting(c, models.MeetingLive)
+}
+
+// EndMeeting marks a live meeting as completed
+func EndMeeting(c *fiber.Ctx) error {
+    return transitionMeeting(c, models.MeetingCompleted)
+}
+
+// CancelMeeting marks a scheduled meeting as cancelled, with an optional
+// {"reason"}, and tells the attendees. Cancelling a recurring series cancels
+// all its occurrences. The organizer can restore it from the trash until
+// it is purged.
+func CancelMeeting(c *fiber.Ctx) error {
+    return transitionMeeting(c, models.MeetingCancelled)
+}
+
+// transitionMeeting moves a meeting to status (organizer only). An
+// occurrence of a recurring series is given by its start in ?start= and is
+// stored as an override; only whole series can be cancelled without it.
+func transitionMeeting(c *fiber.Ctx, status string) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meetingID, err := primitive.ObjectIDFromHex(c.Params("id"))
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID meeting tidak valid"})
+    }
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    var meeting models.Meeting
+    err = config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": meetingID, "deletedAt": bson.M{"$exists": false}}).Decode(&meeting)
+    if err != nil {
+        if err == mongo.ErrNoDocuments {
+            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Meeting tidak ditemukan"})
+        }
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data meeting"})
+    }
+
+    if meeting.CreatedBy != userID {
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya pembuat meeting yang dapat mengubah status"})
+    }
+
+    var input struct {
+        Reason string `json:"reason"`
+    }
+    if len(c.Body()) > 0 {
+        if err := c.BodyParser(&input); err != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+        }
+    }
+    reason := strings.TrimSpace(c.Query("reason", input.Reason))
+    if len(reason) > maxCancelReason {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Alasan pembatalan terlalu panjang"})
+    }
+
+    if meeting.Recurrence != nil && c.Query("start") == "" && status != models.MeetingCancelled {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Parameter start diperlukan untuk meeting berulang"})
+    }
+    meeting, _, errResp := selectOccurrence(c, ctx, meeting, true)
+    if errResp != nil {
+        return errResp()
+    }
+
+    now := time.Now()
+    current := meetingStatus(meeting, now)
+    if !canTransition(current, status) {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+            "error":  fmt.Sprintf("Status meeting tidak dapat diubah dari %s ke %s", current, status),
+            "status": current,
+        })
+    }
+    if status == models.MeetingLive && now.Before(meeting.StartTime.Add(-meetingEarlyStart)) {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Meeting belum dapat dimulai"})
+    }
+
+    set := bson.M{"status": status, "updatedAt": now}
+    switch status {
+    case models.MeetingLive:
+        set["actualStart"] = now
+        meeting.ActualStart = &now
+    case models.MeetingCompleted:
+        set["actualEnd"] = now
+        meeting.ActualEnd = &now
+    case models.MeetingCancelled:
+        set["cancelledAt"] = now
+        set["cancelReason"] = reason
+        meeting.CancelledAt = &now
+        meeting.CancelReason = reason
+    }
+    // Ended and cancelled meetings stay that way, even when two requests race
+    result, err := config.MeetingCollectionRef.UpdateOne(ctx,
+        bson.M{"_id": meeting.ID, "status": bson.M{"$nin": []string{models.MeetingCompleted, models.MeetingCancelled}}},
+        bson.M{"$set": set, "$inc": bson.M{"sequence": 1}})
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengubah status meeting"})
+    }
+    if result.MatchedCount == 0 {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Status meeting sudah berubah"})
+    }
+    meeting.Status = status
+    meeting.Sequence++
+    meeting.UpdatedAt = now
+
+    if status == models.MeetingCancelled {
+        if meeting.Recurrence != nil && meeting.SeriesID == nil {
+            _, err := config.MeetingCollectionRef.UpdateMany(ctx,
+                bson.M{"seriesId": meeting.ID, "status": bson.M{"$nin": []string{models.MeetingCompleted, models.MeetingCancelled}}},
+                bson.M{"$set": bson.M{"status": status, "cancelledAt": now, "cancelReason": reason, "updatedAt": now}, "$inc": bson.M{"sequence": 1}})
+            if err != nil {
+                return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengubah status meeting"})
+            }
+        }
+        notifyMeetingChange(notify.KindMeetingCancelled, meeting, meetingRecipients{
+            UserIDs: meeting.Participants,
+            Guests:  meeting.Guests,
+        })
+    }
+    publishMeetingEvent(realtime.EventMeetingUpdated, meeting)
+
+    message := "Status meeting berhasil diubah"
+    if status == models.MeetingCancelled {
+        message = "Meeting berhasil dibatalkan"
+    }
+    return c.JSON(fiber.Map{
+        "message": message,
+        "meeting": meeting,
+    })
+}
+
+// meetingStatus returns the status of a meeting at now. Scheduled and live
+// meetings are completed once their time is over; a recurring series as a
+// whole is only ever scheduled or cancelled.
+func meetingStatus(meeting models.Meeting, now time.Time) string {
+    switch meeting.Status {
+    case models.MeetingCompleted, models.MeetingCancelled:
+        return meeting.Status
+    }
+    if meeting.Recurrence != nil && meeting.SeriesID == nil {
+        return models.MeetingScheduled
+    }
+    if !now.Before(meetingEnd(meeting)) {
+        return models.MeetingCompleted
+    }
+    if meeting.Status == models.MeetingLive {
+        return models.MeetingLive
+    }
+    return models.MeetingScheduled
+}
+
+// meetingEnd is when a meeting is over: its duration after the scheduled
+// start, or after the actual start when it started late
+func meetingEnd(meeting models.Meeting) time.Time {
+    start := meeting.StartTime
+    if meeting.ActualStart != nil && meeting.ActualStart.After(start) {
+        start = *meeting.ActualStart
+    }
+    return start.Add(time.Duration(meeting.Duration) * time.Minute)
+}
+
+func canTransition(from, to string) bool {
+    for _, status := range meetingTransitions[from] {
+        if status == to {
+            return true
+        }
+    }
+    return false
+}
+
+func validMeetingStatus(status string) bool {
+    switch status {
+    case models.MeetingScheduled, models.MeetingLive, models.MeetingCompleted, models.MeetingCancelled:
+        return true
+    }
+    return false
+}
+
+// selectOccurrence narrows a recurring series to the occurrence starting at
+// ?start=, which is kept as an override meeting. Without ?start=, or for a
+// single meeting, the meeting itself is returned. When the occurrence has
+// no override yet, one is stored if create is set; otherwise found is false.
+// On failure it returns a function writing the error response.
+func selectOccurrence(c *fiber.Ctx, ctx context.Context, meeting models.Meeting, create bool) (models.Meeting, bool, func() error) {
+    if meeting.Recurrence == nil || c.Query("start") == "" {
+        return meeting, true, nil
+    }
+    fail := func(status int, msg string) func() error {
+        return func() error { return c.Status(status).JSON(fiber.Map{"error": msg}) }
+    }
+
+    start, err := time.Parse(time.RFC3339, strings.ReplaceAll(c.Query("start"), " ", "+"))
+    if err != nil {
+        return meeting, false, fail(fiber.StatusBadRequest, "Parameter start tidak valid (RFC3339)")
+    }
+    if !isOccurrence(meeting, start) {
+        return meeting, false, fail(fiber.StatusNotFound, "Occurrence tidak ditemukan")
+    }
+    start = start.UTC()
+
+    if create {
+        occurrence, err := materializeOccurrence(ctx, meeting, start)
+        if err != nil {
+            return meeting, false, fail(fiber.StatusInternalServerError, "Gagal mengambil data meeting")
+        }
+        return occurrence, true, nil
+    }
+    var occurrence models.Meeting
+    err = config.MeetingCollectionRef.FindOne(ctx, bson.M{"seriesId": meeting.ID, "recurrenceId": start}).Decode(&occurrence)
+    if err == mongo.ErrNoDocuments {
+        return newOccurrenceOverride(meeting, start), false, nil
+    }
+    if err != nil {
+        return meeting, false, fail(fiber.StatusInternalServerError, "Gagal mengambil data meeting")
+    }
+    return occurrence, true, nil
+}
+
+// isOccurrence reports whether the series has a non-excluded occurrence at start
+func isOccurrence(series models.Meeting, start time.Time) bool {
+    rule, loc, err := parseRecurrence(series.Recurrence)
+    if err != nil || !rule.OccursAt(series.StartTime, loc, start) {
+        return false
+    }
+    for _, ex := range series.Recurrence.ExDates {
+        if ex.Equal(start) {
+            return false
+        }
+    }
+    return true
+}
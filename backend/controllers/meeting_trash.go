@@ -0,0 +1,396 @@
+package controllers
+
+import (
+    "context"
+    "log"
+    "sort"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/notify"
+    "pbommo/realtime"
+    "pbommo/utils"
+)
+
+const (
+    meetingPurgeTick  = time.Hour
+    meetingPurgeBatch = 500
+)
+
+// TrashedMeeting is a cancelled or deleted meeting in the organizer's trash
+type TrashedMeeting struct {
+    models.Meeting
+    PurgeAt time.Time `json:"purgeAt"`
+}
+
+// GetMeetingTrash lists the cancelled and deleted meetings of the current
+// user that can still be restored, most recently trashed first. Occurrences
+// of a trashed series are restored with it and not listed on their own.
+func GetMeetingTrash(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    retention := config.MeetingTrashRetention()
+    cursor, err := config.MeetingCollectionRef.Find(ctx, bson.M{
+        "createdBy": userID,
+        "$or":       trashedBefore(bson.M{"$gte": time.Now().Add(-retention)}),
+    })
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data meeting"})
+    }
+    var meetings []models.Meeting
+    if err := cursor.All(ctx, &meetings); err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memproses data meeting"})
+    }
+
+    trashedSeries := map[primitive.ObjectID]bool{}
+    for _, m := range meetings {
+        if m.Recurrence != nil {
+            trashedSeries[m.ID] = true
+        }
+    }
+    trash := []TrashedMeeting{}
+    for _, m := range meetings {
+        if m.SeriesID != nil && trashedSeries[*m.SeriesID] {
+            continue
+        }
+        trash = append(trash, TrashedMeeting{Meeting: m, PurgeAt: trashedAt(m).Add(retention)})
+    }
+    sort.SliceStable(trash, func(i, j int) bool { return trashedAt(trash[i].Meeting).After(trashedAt(trash[j].Meeting)) })
+
+    return c.JSON(fiber.Map{
+        "meetings": trash,
+    })
+}
+
+// RestoreMeeting brings a cancelled or deleted meeting back from the trash
+// and invites its attendees again if it is still to come. Like creating a
+// meeting it reports conflicts, and refuses on blocking ones unless forced
+// (?force=true). A meeting cancelled before it was deleted comes back
+// cancelled.
+func RestoreMeeting(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meetingID, err := primitive.ObjectIDFromHex(c.Params("id"))
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID meeting tidak valid"})
+    }
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    var meeting models.Meeting
+    err = config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": meetingID}).Decode(&meeting)
+    if err != nil {
+        if err == mongo.ErrNoDocuments {
+            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Meeting tidak ditemukan"})
+        }
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data meeting"})
+    }
+
+    if meeting.CreatedBy != userID {
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya pembuat meeting yang dapat memulihkan"})
+    }
+    deleted := meeting.DeletedAt != nil
+    if !deleted && (meeting.Status != models.MeetingCancelled || meeting.CancelledAt == nil) {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Meeting tidak dibatalkan"})
+    }
+    if time.Since(trashedAt(meeting)) > config.MeetingTrashRetention() {
+        return c.Status(fiber.StatusGone).JSON(fiber.Map{"error": "Masa pemulihan meeting sudah lewat"})
+    }
+    if meeting.SeriesID != nil {
+        count, err := config.MeetingCollectionRef.CountDocuments(ctx, bson.M{
+            "_id": *meeting.SeriesID,
+            "$or": []bson.M{{"status": models.MeetingCancelled}, {"deletedAt": bson.M{"$exists": true}}},
+        })
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memulihkan meeting"})
+        }
+        if count > 0 {
+            return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Pulihkan series meeting terlebih dahulu"})
+        }
+    }
+
+    now := time.Now()
+    restored := meeting
+    restored.DeletedAt = nil
+    if !deleted {
+        restored.Status = models.MeetingScheduled
+    }
+    upcoming := meetingStatus(restored, now) == models.MeetingScheduled
+
+    conflicts := []Conflict{}
+    if upcoming {
+        conflicts, err = findConflicts(ctx, restored)
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa jadwal"})
+        }
+        if errResp := conflictResponse(c, conflicts, c.QueryBool("force")); errResp != nil {
+            return errResp()
+        }
+    }
+
+    if deleted {
+        err := restoreDeletedMeeting(ctx, meeting, now)
+        if err == mongo.ErrNoDocuments {
+            return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Meeting sudah dipulihkan"})
+        }
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memulihkan meeting"})
+        }
+        restored.Sequence++
+        restored.UpdatedAt = now
+        restored.Status = meetingStatus(restored, now)
+        if upcoming {
+            notifyMeetingChange(notify.KindMeetingInvite, restored, meetingRecipients{
+                UserIDs: restored.Participants,
+                Guests:  restored.Guests,
+            })
+        }
+        publishMeetingEvent(realtime.EventMeetingCreated, restored)
+
+        return c.JSON(fiber.Map{
+            "message":   "Meeting berhasil dipulihkan",
+            "meeting":   restored,
+            "conflicts": conflicts,
+        })
+    }
+
+    restore := bson.M{
+        "$set":   bson.M{"status": models.MeetingScheduled, "updatedAt": now},
+        "$unset": bson.M{"cancelledAt": "", "cancelReason": ""},
+        "$inc":   bson.M{"sequence": 1},
+    }
+    result, err := config.MeetingCollectionRef.UpdateOne(ctx, bson.M{"_id": meetingID, "status": models.MeetingCancelled}, restore)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memulihkan meeting"})
+    }
+    if result.MatchedCount == 0 {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Meeting tidak dibatalkan"})
+    }
+    // Occurrences cancelled along with the series come back with it
+    if meeting.Recurrence != nil {
+        _, err := config.MeetingCollectionRef.UpdateMany(ctx, bson.M{
+            "seriesId":    meetingID,
+            "status":      models.MeetingCancelled,
+            "cancelledAt": *meeting.CancelledAt,
+        }, restore)
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memulihkan meeting"})
+        }
+    }
+
+    meeting.Status = models.MeetingScheduled
+    meeting.CancelledAt = nil
+    meeting.CancelReason = ""
+    meeting.Sequence++
+    meeting.UpdatedAt = now
+    meeting.Status = meetingStatus(meeting, now)
+
+    notifyMeetingChange(notify.KindMeetingInvite, meeting, meetingRecipients{
+        UserIDs: meeting.Participants,
+        Guests:  meeting.Guests,
+    })
+    publishMeetingEvent(realtime.EventMeetingUpdated, meeting)
+
+    return c.JSON(fiber.Map{
+        "message":   "Meeting berhasil dipulihkan",
+        "meeting":   meeting,
+        "conflicts": conflicts,
+    })
+}
+
+// trashMeeting moves a meeting to the trash. A series takes its occurrences
+// with it; the series of an occurrence skips the slot while it is in the
+// trash. A meeting already in the trash gives mongo.ErrNoDocuments.
+func trashMeeting(ctx context.Context, meeting models.Meeting, now time.Time) error {
+    trash := bson.M{"$set": bson.M{"deletedAt": now, "updatedAt": now}, "$inc": bson.M{"sequence": 1}}
+    result, err := config.MeetingCollectionRef.UpdateOne(ctx, bson.M{"_id": meeting.ID, "deletedAt": bson.M{"$exists": false}}, trash)
+    if err != nil {
+        return err
+    }
+    if result.MatchedCount == 0 {
+        return mongo.ErrNoDocuments
+    }
+    switch {
+    case meeting.Recurrence != nil:
+        // Occurrences go with the series and come back with it
+        err = trashOccurrences(ctx, bson.M{"seriesId": meeting.ID}, now)
+    case meeting.SeriesID != nil && meeting.RecurrenceID != nil:
+        _, err = config.MeetingCollectionRef.UpdateOne(ctx, bson.M{"_id": *meeting.SeriesID}, bson.M{
+            "$addToSet": bson.M{"recurrence.exDates": *meeting.RecurrenceID},
+            "$set":      bson.M{"updatedAt": now},
+            "$inc":      bson.M{"sequence": 1},
+        })
+    }
+    return err
+}
+
+// trashOccurrences moves the stored occurrences matching filter to the
+// trash, where they are purged with what is recorded about them
+func trashOccurrences(ctx context.Context, filter bson.M, now time.Time) error {
+    filter["deletedAt"] = bson.M{"$exists": false}
+    _, err := config.MeetingCollectionRef.UpdateMany(ctx, filter,
+        bson.M{"$set": bson.M{"deletedAt": now, "updatedAt": now}, "$inc": bson.M{"sequence": 1}})
+    return err
+}
+
+// restoreDeletedMeeting takes a meeting, and the occurrences deleted with
+// it, out of the trash
+func restoreDeletedMeeting(ctx context.Context, meeting models.Meeting, now time.Time) error {
+    restore := bson.M{
+        "$set":   bson.M{"updatedAt": now},
+        "$unset": bson.M{"deletedAt": ""},
+        "$inc":   bson.M{"sequence": 1},
+    }
+    result, err := config.MeetingCollectionRef.UpdateOne(ctx, bson.M{"_id": meeting.ID, "deletedAt": *meeting.DeletedAt}, restore)
+    if err != nil {
+        return err
+    }
+    if result.MatchedCount == 0 {
+        return mongo.ErrNoDocuments
+    }
+    switch {
+    case meeting.Recurrence != nil:
+        _, err = config.MeetingCollectionRef.UpdateMany(ctx, bson.M{"seriesId": meeting.ID, "deletedAt": *meeting.DeletedAt}, restore)
+    case meeting.SeriesID != nil && meeting.RecurrenceID != nil:
+        _, err = config.MeetingCollectionRef.UpdateOne(ctx, bson.M{"_id": *meeting.SeriesID}, bson.M{
+            "$pull": bson.M{"recurrence.exDates": *meeting.RecurrenceID},
+            "$set":  bson.M{"updatedAt": now},
+            "$inc":  bson.M{"sequence": 1},
+        })
+    }
+    return err
+}
+
+// trashedAt is when a meeting went to the trash
+func trashedAt(meeting models.Meeting) time.Time {
+    if meeting.DeletedAt != nil {
+        return *meeting.DeletedAt
+    }
+    if meeting.CancelledAt != nil {
+        return *meeting.CancelledAt
+    }
+    return time.Time{}
+}
+
+// trashedBefore matches meetings whose time in the trash matches cond,
+// counting from their deletion or else their cancellation
+func trashedBefore(cond bson.M) []bson.M {
+    return []bson.M{
+        {"deletedAt": cond},
+        {"deletedAt": bson.M{"$exists": false}, "status": models.MeetingCancelled, "cancelledAt": cond},
+    }
+}
+
+// StartMeetingPurger removes cancelled and deleted meetings past the trash retention,
+// with everything recorded about them, in the background until ctx is
+// done. Purging is idempotent, so several instances may run it.
+func StartMeetingPurger(ctx context.Context) {
+    if !config.MeetingPurgeEnabled() {
+        log.Println("🗑️ Meeting purge disabled")
+        return
+    }
+
+    go func() {
+        ticker := time.NewTicker(meetingPurgeTick)
+        defer ticker.Stop()
+        for {
+            purged, err := purgeCancelledMeetings(ctx, time.Now().Add(-config.MeetingTrashRetention()))
+            if err != nil {
+                log.Printf("Failed to purge cancelled meetings: %v", err)
+            } else if purged > 0 {
+                log.Printf("🗑️ Purged %d cancelled meetings", purged)
+            }
+            select {
+            case <-ctx.Done():
+                return
+            case <-ticker.C:
+            }
+        }
+    }()
+}
+
+// purgeCancelledMeetings deletes meetings cancelled or deleted before
+// cutoff, the occurrences of purged series, and the data kept per meeting
+func purgeCancelledMeetings(ctx context.Context, cutoff time.Time) (int, error) {
+    total := 0
+    for {
+        cursor, err := config.MeetingCollectionRef.Find(ctx,
+            bson.M{"$or": trashedBefore(bson.M{"$lt": cutoff})},
+            options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(meetingPurgeBatch))
+        if err != nil {
+            return total, err
+        }
+        var batch []models.Meeting
+        if err := cursor.All(ctx, &batch); err != nil {
+            return total, err
+        }
+        if len(batch) == 0 {
+            return total, nil
+        }
+
+        ids := make([]primitive.ObjectID, 0, len(batch))
+        for _, m := range batch {
+            ids = append(ids, m.ID)
+        }
+        // Overrides of a purged series go with it, whatever their status
+        cursor, err = config.MeetingCollectionRef.Find(ctx,
+            bson.M{"seriesId": bson.M{"$in": ids}},
+            options.Find().SetProjection(bson.M{"_id": 1}))
+        if err != nil {
+            return total, err
+        }
+        var overrides []models.Meeting
+        if err := cursor.All(ctx, &overrides); err != nil {
+            return total, err
+        }
+        for _, m := range overrides {
+            ids = append(ids, m.ID)
+        }
+
+        if err := purgeMeetingData(ctx, ids); err != nil {
+            return total, err
+        }
+        result, err := config.MeetingCollectionRef.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+        if err != nil {
+            return total, err
+        }
+        total += int(result.DeletedCount)
+    }
+}
+
+// purgeMeetingData deletes what is stored about the given meetings outside
+// the meetings collection. Notifications are left to their own retention.
+func purgeMeetingData(ctx context.Context, ids []primitive.ObjectID) error {
+    filter := bson.M{"meetingId": bson.M{"$in": ids}}
+    for _, coll := range []*mongo.Collection{
+        config.ReminderCollectionRef,
+        config.EmotionSampleCollectionRef,
+        config.EmotionReportCollectionRef,
+        config.EmotionConsentCollectionRef,
+        config.MeetingNoteCollectionRef,
+        config.NoteRevisionCollectionRef,
+        config.ActionItemCollectionRef,
+    } {
+        if _, err := coll.DeleteMany(ctx, filter); err != nil {
+            return err
+        }
+    }
+    return nil
+}
@@ -0,0 +1,453 @@
+package controllers
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "strconv"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/notify"
+    "pbommo/utils"
+)
+
+const (
+    maxNoteContent  = 200 * 1024
+    maxRevisionPage = 100
+    // staleRevision is how old a revision without a matching note must be
+    // before a later save may take its version over; saves take less
+    staleRevision = time.Minute
+)
+
+// GetMeetingNotes returns the notes of a meeting, or of one occurrence of a
+// recurring meeting given in ?start=, with the names of their authors.
+// Meetings without notes yet return an empty note at version 0.
+func GetMeetingNotes(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meeting, _, errResp := loadAttendedMeeting(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+    meeting, found, errResp := selectOccurrence(c, ctx, meeting, false)
+    if errResp != nil {
+        return errResp()
+    }
+
+    note := models.MeetingNote{MeetingID: meeting.ID, Contributors: []primitive.ObjectID{}}
+    if found {
+        var err error
+        note, err = loadMeetingNote(ctx, meeting.ID)
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil catatan meeting"})
+        }
+    }
+    return noteResponse(c, ctx, fiber.StatusOK, fiber.Map{}, note)
+}
+
+// SaveMeetingNotes stores new notes content. {"version"} is the version the
+// edit started from; when someone saved in between, nothing is stored and
+// the current notes are returned with 409 so the client can merge. The
+// revision is written first: its unique version decides which concurrent
+// save wins, and a save never succeeds without its revision.
+func SaveMeetingNotes(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meeting, userID, errResp := loadAttendedMeeting(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+
+    var input struct {
+        Content string `json:"content"`
+        Version int    `json:"version"`
+    }
+    if err := c.BodyParser(&input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+    }
+    if len(input.Content) > maxNoteContent {
+        return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": "Catatan meeting terlalu panjang"})
+    }
+
+    meeting, _, errResp = selectOccurrence(c, ctx, meeting, true)
+    if errResp != nil {
+        return errResp()
+    }
+    if meeting.Status == models.MeetingCancelled {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Meeting sudah dibatalkan"})
+    }
+
+    current, err := loadMeetingNote(ctx, meeting.ID)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil catatan meeting"})
+    }
+    if current.Version != input.Version {
+        return noteConflict(c, ctx, current)
+    }
+    if current.Version > 0 && current.Content == input.Content {
+        return noteResponse(c, ctx, fiber.StatusOK, fiber.Map{"message": "Catatan meeting tidak berubah"}, current)
+    }
+
+    now := time.Now()
+    revision := models.NoteRevision{
+        ID:        primitive.NewObjectID(),
+        MeetingID: meeting.ID,
+        Version:   input.Version + 1,
+        Content:   input.Content,
+        AuthorID:  userID,
+        CreatedAt: now,
+    }
+    claimed, err := claimNoteRevision(ctx, &revision)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan riwayat catatan"})
+    }
+    if !claimed {
+        return reloadNoteConflict(c, ctx, meeting.ID)
+    }
+    // Without the note the revision is dropped again, so the version can
+    // be saved later
+    dropRevision := func() {
+        if _, err := config.NoteRevisionCollectionRef.DeleteOne(context.Background(), bson.M{"_id": revision.ID}); err != nil {
+            log.Printf("Failed to drop revision %d of notes for meeting %s: %v", revision.Version, meeting.ID.Hex(), err)
+        }
+    }
+
+    var note models.MeetingNote
+    if input.Version == 0 {
+        note = models.MeetingNote{
+            MeetingID:    meeting.ID,
+            Content:      input.Content,
+            Version:      1,
+            UpdatedBy:    userID,
+            Contributors: []primitive.ObjectID{userID},
+            CreatedAt:    now,
+            UpdatedAt:    now,
+        }
+        result, err := config.MeetingNoteCollectionRef.InsertOne(ctx, note)
+        if err != nil {
+            dropRevision()
+        }
+        if mongo.IsDuplicateKeyError(err) {
+            return reloadNoteConflict(c, ctx, meeting.ID)
+        }
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan catatan meeting"})
+        }
+        note.ID = result.InsertedID.(primitive.ObjectID)
+    } else {
+        err := config.MeetingNoteCollectionRef.FindOneAndUpdate(ctx,
+            bson.M{"meetingId": meeting.ID, "version": input.Version},
+            bson.M{
+                "$set":      bson.M{"content": input.Content, "updatedBy": userID, "updatedAt": now},
+                "$inc":      bson.M{"version": 1},
+                "$addToSet": bson.M{"contributors": userID},
+            },
+            options.FindOneAndUpdate().SetReturnDocument(options.After),
+        ).Decode(&note)
+        if err != nil {
+            dropRevision()
+        }
+        if err == mongo.ErrNoDocuments {
+            return reloadNoteConflict(c, ctx, meeting.ID)
+        }
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan catatan meeting"})
+        }
+    }
+
+    return noteResponse(c, ctx, fiber.StatusOK, fiber.Map{"message": "Catatan meeting berhasil disimpan"}, note)
+}
+
+// GetNoteRevisions lists the saved versions of a meeting's notes, newest
+// first, without their content. ?before=<version> continues a previous page.
+func GetNoteRevisions(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meeting, _, errResp := loadAttendedMeeting(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+    meeting, _, errResp = selectOccurrence(c, ctx, meeting, false)
+    if errResp != nil {
+        return errResp()
+    }
+
+    filter := bson.M{"meetingId": meeting.ID}
+    if before := c.QueryInt("before"); before > 0 {
+        filter["version"] = bson.M{"$lt": before}
+    }
+    limit := c.QueryInt("limit", 20)
+    if limit <= 0 || limit > maxRevisionPage {
+        limit = maxRevisionPage
+    }
+    cursor, err := config.NoteRevisionCollectionRef.Find(ctx, filter, options.Find().
+        SetSort(bson.M{"version": -1}).
+        SetLimit(int64(limit)).
+        SetProjection(bson.M{"content": 0}))
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil riwayat catatan"})
+    }
+    revisions := []models.NoteRevision{}
+    if err := cursor.All(ctx, &revisions); err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memproses riwayat catatan"})
+    }
+
+    var authorIDs []primitive.ObjectID
+    for _, r := range revisions {
+        authorIDs = append(authorIDs, r.AuthorID)
+    }
+    authors, err := userNames(ctx, authorIDs)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil riwayat catatan"})
+    }
+
+    return c.JSON(fiber.Map{
+        "revisions": revisions,
+        "authors":   authors,
+    })
+}
+
+// GetNoteRevision returns the notes of a meeting as saved at :version
+func GetNoteRevision(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meeting, _, errResp := loadAttendedMeeting(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+    meeting, _, errResp = selectOccurrence(c, ctx, meeting, false)
+    if errResp != nil {
+        return errResp()
+    }
+
+    version, err := strconv.Atoi(c.Params("version"))
+    if err != nil || version <= 0 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Versi tidak valid"})
+    }
+    var revision models.NoteRevision
+    err = config.NoteRevisionCollectionRef.FindOne(ctx, bson.M{"meetingId": meeting.ID, "version": version}).Decode(&revision)
+    if err != nil {
+        if err == mongo.ErrNoDocuments {
+            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Versi catatan tidak ditemukan"})
+        }
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil riwayat catatan"})
+    }
+
+    return c.JSON(fiber.Map{"revision": revision})
+}
+
+// PublishMeetingMinutes sends the current notes as the minutes of a
+// finished meeting to all participants and guests (organizer only)
+func PublishMeetingMinutes(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meeting, userID, errResp := loadAttendedMeeting(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+    if meeting.CreatedBy != userID {
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya pembuat meeting yang dapat membagikan notulen"})
+    }
+    meeting, found, errResp := selectOccurrence(c, ctx, meeting, false)
+    if errResp != nil {
+        return errResp()
+    }
+    now := time.Now()
+    if meetingStatus(meeting, now) != models.MeetingCompleted {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Notulen hanya dapat dibagikan setelah meeting selesai"})
+    }
+
+    note := models.MeetingNote{}
+    if found {
+        var err error
+        if note, err = loadMeetingNote(ctx, meeting.ID); err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil catatan meeting"})
+        }
+    }
+    if note.Version == 0 || note.Content == "" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Catatan meeting masih kosong"})
+    }
+
+    err := config.MeetingNoteCollectionRef.FindOneAndUpdate(ctx,
+        bson.M{"meetingId": meeting.ID, "version": note.Version},
+        bson.M{"$set": bson.M{"publishedVersion": note.Version, "publishedBy": userID, "publishedAt": now}},
+        options.FindOneAndUpdate().SetReturnDocument(options.After),
+    ).Decode(&note)
+    if err == mongo.ErrNoDocuments {
+        return reloadNoteConflict(c, ctx, meeting.ID)
+    }
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membagikan notulen"})
+    }
+
+    notifyMinutes(meeting, note)
+
+    return noteResponse(c, ctx, fiber.StatusOK, fiber.Map{"message": "Notulen berhasil dibagikan"}, note)
+}
+
+// EnsureMeetingNoteIndexes creates the indexes notes and their revisions
+// rely on for one note per meeting and one revision per version
+func EnsureMeetingNoteIndexes(ctx context.Context) error {
+    _, err := config.MeetingNoteCollectionRef.Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys:    bson.D{{Key: "meetingId", Value: 1}},
+        Options: options.Index().SetUnique(true),
+    })
+    if err != nil {
+        return err
+    }
+    _, err = config.NoteRevisionCollectionRef.Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys:    bson.D{{Key: "meetingId", Value: 1}, {Key: "version", Value: -1}},
+        Options: options.Index().SetUnique(true),
+    })
+    return err
+}
+
+// claimNoteRevision stores the revision a save is about to make. It reports
+// false when another save already holds the version. A revision left behind
+// by a save that failed half-way, still ahead of the note, is taken over
+// once it is stale, keeping its ID.
+func claimNoteRevision(ctx context.Context, revision *models.NoteRevision) (bool, error) {
+    _, err := config.NoteRevisionCollectionRef.InsertOne(ctx, revision)
+    if err == nil {
+        return true, nil
+    }
+    if !mongo.IsDuplicateKeyError(err) {
+        return false, err
+    }
+
+    current, err := loadMeetingNote(ctx, revision.MeetingID)
+    if err != nil || current.Version >= revision.Version {
+        return false, err
+    }
+    var stale models.NoteRevision
+    err = config.NoteRevisionCollectionRef.FindOneAndUpdate(ctx, bson.M{
+        "meetingId": revision.MeetingID,
+        "version":   revision.Version,
+        "createdAt": bson.M{"$lt": revision.CreatedAt.Add(-staleRevision)},
+    }, bson.M{"$set": bson.M{
+        "content":   revision.Content,
+        "authorId":  revision.AuthorID,
+        "createdAt": revision.CreatedAt,
+    }}).Decode(&stale)
+    if err == mongo.ErrNoDocuments {
+        return false, nil
+    }
+    if err != nil {
+        return false, err
+    }
+    revision.ID = stale.ID
+    return true, nil
+}
+
+// loadMeetingNote returns the notes of a meeting, or an empty note at
+// version 0 when there are none yet
+func loadMeetingNote(ctx context.Context, meetingID primitive.ObjectID) (models.MeetingNote, error) {
+    var note models.MeetingNote
+    err := config.MeetingNoteCollectionRef.FindOne(ctx, bson.M{"meetingId": meetingID}).Decode(&note)
+    if err == mongo.ErrNoDocuments {
+        return models.MeetingNote{MeetingID: meetingID, Contributors: []primitive.ObjectID{}}, nil
+    }
+    return note, err
+}
+
+func reloadNoteConflict(c *fiber.Ctx, ctx context.Context, meetingID primitive.ObjectID) error {
+    current, err := loadMeetingNote(ctx, meetingID)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil catatan meeting"})
+    }
+    return noteConflict(c, ctx, current)
+}
+
+func noteConflict(c *fiber.Ctx, ctx context.Context, current models.MeetingNote) error {
+    return noteResponse(c, ctx, fiber.StatusConflict, fiber.Map{
+        "error": fmt.Sprintf("Catatan sudah diubah orang lain (versi %d), gabungkan perubahan Anda lalu simpan lagi", current.Version),
+    }, current)
+}
+
+// noteResponse adds the note and the names of its contributors to body
+func noteResponse(c *fiber.Ctx, ctx context.Context, status int, body fiber.Map, note models.MeetingNote) error {
+    ids := append([]primitive.ObjectID{}, note.Contributors...)
+    if note.PublishedBy != nil {
+        ids = append(ids, *note.PublishedBy)
+    }
+    authors, err := userNames(ctx, ids)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil catatan meeting"})
+    }
+    body["note"] = note
+    body["authors"] = authors
+    return c.Status(status).JSON(body)
+}
+
+// notifyMinutes puts the published minutes in the inbox of every
+// participant and emails them to participants and guests in the background
+func notifyMinutes(meeting models.Meeting, note models.MeetingNote) {
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+        defer cancel()
+
+        users, err := usersByID(ctx, append([]primitive.ObjectID{meeting.CreatedBy}, meeting.Participants...))
+        if err != nil {
+            log.Printf("Failed to load recipients of minutes for meeting %s: %v", meeting.ID.Hex(), err)
+            return
+        }
+        organizer := users[meeting.CreatedBy]
+
+        var notifications []models.Notification
+        for _, id := range meeting.Participants {
+            user, ok := users[id]
+            if !ok || user.Disabled {
+                continue
+            }
+            n := newMeetingNotification(models.NotificationMinutes, meeting, user,
+                notify.T(user.Language, "minutes.published", organizer.Nama, meeting.Title))
+            n.ActorID = &meeting.CreatedBy
+            notifications = append(notifications, n)
+        }
+        if err := notify.SaveInApp(ctx, notifications...); err != nil {
+            log.Printf("Failed to save minutes notifications for meeting %s: %v", meeting.ID.Hex(), err)
+        }
+
+        ch, ok := notify.Lookup("email")
+        if !ok {
+            return
+        }
+        send := func(user models.User, link string) {
+            if !ch.Accepts(user) {
+                return
+            }
+            msg := notify.Message{
+                Kind:    "minutes",
+                User:    user,
+                Subject: notify.T(user.Language, "minutes.subject", meeting.Title),
+                Text:    note.Content,
+                Link:    link,
+                Meeting: &meeting,
+            }
+            if err := ch.Send(ctx, msg); err != nil {
+                log.Printf("Failed to send minutes to %s: %v", user.Email, err)
+            }
+        }
+        for _, id := range meeting.Participants {
+            if user, ok := users[id]; ok && !user.Disabled {
+                send(user, utils.FrontendURL()+"/meetings")
+            }
+        }
+        for _, g := range meeting.Guests {
+            send(models.User{Nama: g.Email, Email: g.Email}, fmt.Sprintf("%s/guest/meetings/%s", utils.FrontendURL(), g.Token))
+        }
+    }()
+}
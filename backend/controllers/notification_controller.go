@@ -0,0 +1,282 @@
+package controllers
+
+import (
+    "context"
+    "log"
+    "regexp"
+    "strings"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo/options"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/notify"
+    "pbommo/utils"
+)
+
+const maxNotificationPage = 100
+
+// mentionPattern matches mention markup as written by the frontend,
+// e.g. @[Budi Santoso](64b7f0c2a1e4d3b2c1a0f9e8)
+var mentionPattern = regexp.MustCompile(`@\[[^\]]+\]\(([0-9a-fA-F]{24})\)`)
+
+// GetNotifications lists the current user's notifications, newest first.
+// ?unread=true limits to unread ones; ?before=<id> continues a previous page.
+func GetNotifications(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    filter := inboxFilter(userID)
+    if c.QueryBool("unread") {
+        filter["read"] = false
+    }
+    if before := c.Query("before"); before != "" {
+        id, err := primitive.ObjectIDFromHex(before)
+        if err != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Parameter before tidak valid"})
+        }
+        filter["_id"] = bson.M{"$lt": id}
+    }
+    limit := c.QueryInt("limit", 20)
+    if limit <= 0 || limit > maxNotificationPage {
+        limit = maxNotificationPage
+    }
+
+    cursor, err := config.NotificationCollectionRef.Find(ctx, filter,
+        options.Find().SetSort(bson.M{"_id": -1}).SetLimit(int64(limit)))
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil notifikasi"})
+    }
+    notifications := []models.Notification{}
+    if err := cursor.All(ctx, &notifications); err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memproses notifikasi"})
+    }
+
+    unread, err := countUnread(ctx, userID)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil notifikasi"})
+    }
+
+    response := fiber.Map{
+        "notifications": notifications,
+        "unreadCount":   unread,
+    }
+    if len(notifications) == limit {
+        response["nextBefore"] = notifications[len(notifications)-1].ID
+    }
+    return c.JSON(response)
+}
+
+// GetUnreadNotificationCount returns the number of unread notifications
+func GetUnreadNotificationCount(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    unread, err := countUnread(ctx, userID)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil notifikasi"})
+    }
+    return c.JSON(fiber.Map{"unreadCount": unread})
+}
+
+// MarkNotificationRead marks one of the current user's notifications as read
+func MarkNotificationRead(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    notificationID, err := primitive.ObjectIDFromHex(c.Params("id"))
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID notifikasi tidak valid"})
+    }
+
+    result, err := config.NotificationCollectionRef.UpdateOne(ctx,
+        bson.M{"_id": notificationID, "userId": userID},
+        bson.M{"$set": bson.M{"read": true, "readAt": time.Now()}})
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memperbarui notifikasi"})
+    }
+    if result.MatchedCount == 0 {
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Notifikasi tidak ditemukan"})
+    }
+
+    return c.JSON(fiber.Map{"message": "Notifikasi ditandai sudah dibaca"})
+}
+
+// MarkAllNotificationsRead marks every unread notification of the current user as read
+func MarkAllNotificationsRead(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    result, err := config.NotificationCollectionRef.UpdateMany(ctx,
+        bson.M{"userId": userID, "read": false},
+        bson.M{"$set": bson.M{"read": true, "readAt": time.Now()}})
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memperbarui notifikasi"})
+    }
+
+    return c.JSON(fiber.Map{
+        "message": "Semua notifikasi ditandai sudah dibaca",
+        "updated": result.ModifiedCount,
+    })
+}
+
+// inboxFilter hides notifications past the retention period that the TTL
+// monitor has not removed yet
+func inboxFilter(userID primitive.ObjectID) bson.M {
+    return bson.M{
+        "userId":    userID,
+        "createdAt": bson.M{"$gte": time.Now().Add(-config.NotificationRetention())},
+    }
+}
+
+func countUnread(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+    filter := inboxFilter(userID)
+    filter["read"] = false
+    return config.NotificationCollectionRef.CountDocuments(ctx, filter)
+}
+
+// saveMeetingNotifications adds an invite, update or cancellation entry to
+// the inbox of each user, localized to their language
+func saveMeetingNotifications(ctx context.Context, kind string, meeting models.Meeting, users map[primitive.ObjectID]models.User, userIDs []primitive.ObjectID) error {
+    types := map[string]string{
+        notify.KindMeetingInvite:    models.NotificationInvite,
+        notify.KindMeetingUpdated:   models.NotificationMeetingUpdated,
+        notify.KindMeetingCancelled: models.NotificationMeetingCancelled,
+    }
+
+    var notifications []models.Notification
+    for _, id := range userIDs {
+        user, ok := users[id]
+        if !ok || user.Disabled {
+            continue
+        }
+        notifications = append(notifications, newMeetingNotification(types[kind], meeting, user,
+            notify.T(user.Language, kind+".subject", meeting.Title)))
+    }
+    return notify.SaveInApp(ctx, notifications...)
+}
+
+// notifyRSVP tells the organizer that someone answered their invitation
+func notifyRSVP(meeting models.Meeting, responder string, actorID *primitive.ObjectID, status string) {
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        defer cancel()
+
+        var organizer models.User
+        if err := config.UserCollectionRef.FindOne(ctx, bson.M{"_id": meeting.CreatedBy}).Decode(&organizer); err != nil {
+            return
+        }
+        n := newMeetingNotification(models.NotificationRSVP, meeting, organizer,
+            notify.T(organizer.Language, "rsvp."+status, responder, meeting.Title))
+        n.ActorID = actorID
+        if err := notify.SaveInApp(ctx, n); err != nil {
+            log.Printf("Failed to save RSVP notification: %v", err)
+        }
+    }()
+}
+
+// notifyMentions tells users mentioned in text that did not appear in
+// previous (the text before an edit) about the mention. Only people on the
+// meeting are told; mentions of anyone else are ignored.
+func notifyMentions(meeting models.Meeting, actorID primitive.ObjectID, text, previous string) {
+    already := map[primitive.ObjectID]bool{actorID: true}
+    for _, id := range parseMentions(previous) {
+        already[id] = true
+    }
+    var mentioned []primitive.ObjectID
+    for _, id := range parseMentions(text) {
+        if !already[id] {
+            already[id] = true
+            mentioned = append(mentioned, id)
+        }
+    }
+    if len(mentioned) == 0 {
+        return
+    }
+
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        defer cancel()
+
+        users, err := usersByID(ctx, append(mentioned, actorID))
+        if err != nil {
+            log.Printf("Failed to load mentioned users: %v", err)
+            return
+        }
+        var notifications []models.Notification
+        for _, id := range mentioned {
+            user, ok := users[id]
+            if !ok || user.Disabled || !onMeeting(meeting, user) {
+                continue
+            }
+            n := newMeetingNotification(models.NotificationMention, meeting, user,
+                notify.T(user.Language, "mention.title", users[actorID].Nama, meeting.Title))
+            n.ActorID = &actorID
+            notifications = append(notifications, n)
+        }
+        if err := notify.SaveInApp(ctx, notifications...); err != nil {
+            log.Printf("Failed to save mention notifications: %v", err)
+        }
+    }()
+}
+
+// onMeeting reports whether the user organizes, participates in or is
+// invited as a guest (by email) to the meeting
+func onMeeting(meeting models.Meeting, user models.User) bool {
+    if user.ID == meeting.CreatedBy || isParticipant(meeting, user.ID) {
+        return true
+    }
+    for _, g := range meeting.Guests {
+        if strings.EqualFold(g.Email, user.Email) {
+            return true
+        }
+    }
+    return false
+}
+
+// parseMentions returns the user ids mentioned in text
+func parseMentions(text string) []primitive.ObjectID {
+    var ids []primitive.ObjectID
+    for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+        if id, err := primitive.ObjectIDFromHex(match[1]); err == nil {
+            ids = append(ids, id)
+        }
+    }
+    return ids
+}
+
+func newMeetingNotification(kind string, meeting models.Meeting, user models.User, title string) models.Notification {
+    meetingID := meeting.ID
+    return models.Notification{
+        UserID:    user.ID,
+        Type:      kind,
+        Title:     title,
+        Body:      notify.FormatDateTime(user.Language, meeting.StartTime.In(userLocation(user))),
+        MeetingID: &meetingID,
+        Link:      utils.FrontendURL() + "/meetings",
+    }
+}
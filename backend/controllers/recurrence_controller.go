@@ -0,0 +1,559 @@
+package controllers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/notify"
+    "pbommo/realtime"
+    "pbommo/utils"
+)
+
+// Occurrence scopes for editing/cancelling part of a recurring series
+const (
+    scopeThis      = "this"
+    scopeFollowing = "following"
+)
+
+// UpdateOccurrence updates a single occurrence of a recurring meeting
+// (?scope=this) or the occurrence and all following ones (?scope=following).
+// The occurrence is identified by its original start in ?start= (RFC3339).
+// The change is checked and announced like UpdateMeeting.
+func UpdateOccurrence(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    series, start, scope, errResp := loadSeriesOccurrence(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+
+    var input meetingInput
+    if err := c.BodyParser(&input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+    }
+    updateData := input.Meeting
+    updateData.Participants = nil
+    updateData.Guests = nil
+    if !validEmotionVisibility(updateData.EmotionVisibility) {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "emotionVisibility harus organizer atau aggregate"})
+    }
+
+    // The meeting as it is before the change: the stored override of the
+    // occurrence, or the series from it on
+    before := series
+    if scope == scopeThis {
+        occurrence, err := materializeOccurrence(ctx, series, start)
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengupdate occurrence"})
+        }
+        if occurrence.Status == models.MeetingCancelled {
+            return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Occurrence sudah dibatalkan"})
+        }
+        before = occurrence
+    }
+
+    if input.Participants != nil {
+        participants, err := resolveParticipants(ctx, input.Participants, series.CreatedBy)
+        if err != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+        }
+        updateData.Participants = participants.UserIDs
+        updateData.Guests, _, err = mergeGuests(before.Guests, participants.GuestEmails)
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengupdate occurrence"})
+        }
+    }
+
+    var updated models.Meeting
+    if scope == scopeThis {
+        updated = before
+        applyMeetingUpdate(&updated, updateData, input.AgendaProposals)
+    } else {
+        var err error
+        if updated, err = followingSeries(series, start, updateData, input.AgendaProposals); err != nil {
+            if errors.Is(err, errInvalidRecurrence) {
+                return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+            }
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengupdate series meeting"})
+        }
+    }
+
+    rescheduled := !updateData.StartTime.IsZero() || updateData.Duration > 0 || input.Participants != nil || updateData.Recurrence != nil
+    conflicts, warnings, errResp := checkOccurrenceUpdate(c, ctx, series, updated, rescheduled, input.Force || c.QueryBool("force"))
+    if errResp != nil {
+        return errResp()
+    }
+
+    message := "Occurrence berhasil diupdate"
+    if scope == scopeThis {
+        if _, err := config.MeetingCollectionRef.ReplaceOne(ctx, bson.M{"_id": updated.ID}, updated); err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengupdate occurrence"})
+        }
+        publishMeetingEvent(realtime.EventMeetingUpdated, updated)
+    } else {
+        if err := splitSeries(ctx, series, start, updated); err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengupdate series meeting"})
+        }
+        if updated.ID == series.ID {
+            publishMeetingEvent(realtime.EventMeetingUpdated, updated)
+        } else {
+            publishMeetingEvent(realtime.EventMeetingUpdated, series)
+            publishMeetingEvent(realtime.EventMeetingCreated, updated)
+        }
+        message = "Occurrence dan selanjutnya berhasil diupdate"
+    }
+    notifyMeetingUpdate(before, updated)
+
+    return c.JSON(fiber.Map{
+        "message":   message,
+        "meeting":   updated,
+        "conflicts": conflicts,
+        "warnings":  warnings,
+    })
+}
+
+// CancelOccurrence cancels a single occurrence (?scope=this) or the
+// occurrence and all following ones (?scope=following), telling the
+// attendees. What is stored about cancelled occurrences goes to the trash.
+func CancelOccurrence(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    series, start, scope, errResp := loadSeriesOccurrence(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+    now := time.Now()
+
+    if scope == scopeThis {
+        occurrence, stored, errResp := selectOccurrence(c, ctx, series, false)
+        if errResp != nil {
+            return errResp()
+        }
+        if err := excludeOccurrence(ctx, series.ID, start); err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membatalkan occurrence"})
+        }
+        if meetingStatus(occurrence, now) == models.MeetingScheduled {
+            notifyMeetingChange(notify.KindMeetingCancelled, occurrence, meetingRecipients{
+                UserIDs: occurrence.Participants,
+                Guests:  occurrence.Guests,
+            })
+        }
+        if stored {
+            publishMeetingEvent(realtime.EventMeetingDeleted, occurrence)
+        }
+        publishMeetingEvent(realtime.EventMeetingUpdated, series)
+        return c.JSON(fiber.Map{"message": "Occurrence berhasil dibatalkan"})
+    }
+
+    // Cancelling from the first occurrence moves the whole series to the trash
+    if start.Equal(series.StartTime) {
+        err := trashMeeting(ctx, series, now)
+        if err != nil && err != mongo.ErrNoDocuments {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus meeting"})
+        }
+        notifyMeetingChange(notify.KindMeetingCancelled, series, meetingRecipients{
+            UserIDs: series.Participants,
+            Guests:  series.Guests,
+        })
+        series.DeletedAt = &now
+        publishMeetingEvent(realtime.EventMeetingDeleted, series)
+        return c.JSON(fiber.Map{"message": "Series meeting berhasil dihapus"})
+    }
+
+    if err := truncateSeries(ctx, series, start); err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membatalkan occurrence"})
+    }
+    err := trashOccurrences(ctx, bson.M{"seriesId": series.ID, "recurrenceId": bson.M{"$gte": start}}, now)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membatalkan occurrence"})
+    }
+
+    // Attendees get the series with its new end
+    var truncated models.Meeting
+    if err := config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": series.ID}).Decode(&truncated); err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data meeting"})
+    }
+    notifyMeetingChange(notify.KindMeetingUpdated, truncated, meetingRecipients{
+        UserIDs: truncated.Participants,
+        Guests:  truncated.Guests,
+    })
+    publishMeetingEvent(realtime.EventMeetingUpdated, truncated)
+    return c.JSON(fiber.Map{"message": "Occurrence dan selanjutnya berhasil dibatalkan"})
+}
+
+// checkOccurrenceUpdate runs the checks of UpdateMeeting on an updated
+// occurrence or series: the agenda must still fit, and when the schedule
+// or attendees changed, conflicts are handled per policy and availability
+// warnings collected. On failure it returns a function writing the error
+// response.
+func checkOccurrenceUpdate(c *fiber.Ctx, ctx context.Context, series, updated models.Meeting, rescheduled, force bool) ([]Conflict, []AvailabilityWarning, func() error) {
+    conflicts := []Conflict{}
+    warnings := []AvailabilityWarning{}
+    fail := func(status int, msg string) func() error {
+        return func() error { return c.Status(status).JSON(fiber.Map{"error": msg}) }
+    }
+
+    if total := agendaMinutes(updated.Agenda); total > updated.Duration {
+        return conflicts, warnings, fail(fiber.StatusBadRequest, fmt.Sprintf("Durasi lebih pendek dari total waktu agenda (%d menit)", total))
+    }
+    if !rescheduled {
+        return conflicts, warnings, nil
+    }
+
+    // The occurrences a new series takes over from the old one are moved,
+    // not in its way
+    checked := updated
+    if checked.Recurrence != nil && checked.ID != series.ID {
+        checked.SeriesID = &series.ID
+    }
+    conflicts, err := findConflicts(ctx, checked)
+    if err != nil {
+        return conflicts, warnings, fail(fiber.StatusInternalServerError, "Gagal memeriksa jadwal")
+    }
+    if errResp := conflictResponse(c, conflicts, force); errResp != nil {
+        return conflicts, warnings, errResp
+    }
+    warnings, err = availabilityWarnings(ctx, updated)
+    if err != nil {
+        return conflicts, warnings, fail(fiber.StatusInternalServerError, "Gagal memeriksa jadwal")
+    }
+    return conflicts, warnings, nil
+}
+
+var errInvalidRecurrence = errors.New("recurrence tidak valid")
+
+// parseRecurrence validates a recurrence and normalizes its RRULE
+func parseRecurrence(rec *models.Recurrence) (*utils.RRule, *time.Location, error) {
+    rule, err := utils.ParseRRule(rec.RRule)
+    if err != nil {
+        return nil, nil, fmt.Errorf("%w: %v", errInvalidRecurrence, err)
+    }
+    loc := time.UTC
+    if rec.Timezone != "" {
+        loc, err = time.LoadLocation(rec.Timezone)
+        if err != nil {
+            return nil, nil, fmt.Errorf("%w: %v", errInvalidRecurrence, err)
+        }
+    }
+    rec.RRule = rule.String()
+    return rule, loc, nil
+}
+
+// expandMeetings replaces recurring series with their occurrences in
+// [from, to). Occurrences overridden by a separate meeting are skipped,
+// since that meeting is matched on its own.
+func expandMeetings(ctx context.Context, meetings []models.Meeting, from, to time.Time) ([]models.Meeting, error) {
+    var seriesIDs []primitive.ObjectID
+    for _, m := range meetings {
+        if m.Recurrence != nil {
+            seriesIDs = append(seriesIDs, m.ID)
+        }
+    }
+
+    overridden := map[primitive.ObjectID][]time.Time{}
+    if len(seriesIDs) > 0 {
+        cursor, err := config.MeetingCollectionRef.Find(ctx, bson.M{"seriesId": bson.M{"$in": seriesIDs}})
+        if err != nil {
+            return nil, err
+        }
+        var exceptions []models.Meeting
+        if err := cursor.All(ctx, &exceptions); err != nil {
+            return nil, err
+        }
+        for _, e := range exceptions {
+            if e.SeriesID != nil && e.RecurrenceID != nil {
+                overridden[*e.SeriesID] = append(overridden[*e.SeriesID], *e.RecurrenceID)
+            }
+        }
+    }
+
+    result := make([]models.Meeting, 0, len(meetings))
+    for _, m := range meetings {
+        if m.Recurrence == nil {
+            result = append(result, m)
+            continue
+        }
+        rule, loc, err := parseRecurrence(m.Recurrence)
+        if err != nil {
+            continue
+        }
+        skip := append(append([]time.Time{}, m.Recurrence.ExDates...), overridden[m.ID]...)
+        for _, occ := range rule.Between(m.StartTime, loc, from, to, skip) {
+            occurrence := m
+            seriesID := m.ID
+            recurrenceID := occ
+            occurrence.StartTime = occ
+            occurrence.SeriesID = &seriesID
+            occurrence.RecurrenceID = &recurrenceID
+            result = append(result, occurrence)
+        }
+    }
+
+    sort.SliceStable(result, func(i, j int) bool { return result[i].StartTime.Before(result[j].StartTime) })
+    return result, nil
+}
+
+// loadSeriesOccurrence loads the series from :id and validates ?start= and
+// ?scope=. On failure it returns a function writing the error response.
+func loadSeriesOccurrence(c *fiber.Ctx, ctx context.Context) (models.Meeting, time.Time, string, func() error) {
+    var series models.Meeting
+    fail := func(status int, msg string) func() error {
+        return func() error { return c.Status(status).JSON(fiber.Map{"error": msg}) }
+    }
+
+    meetingID, err := primitive.ObjectIDFromHex(c.Params("id"))
+    if err != nil {
+        return series, time.Time{}, "", fail(fiber.StatusBadRequest, "ID meeting tidak valid")
+    }
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return series, time.Time{}, "", fail(fiber.StatusUnauthorized, "Token tidak valid")
+    }
+
+    // A "+" offset arrives as a space when the query is not encoded
+    start, err := time.Parse(time.RFC3339, strings.ReplaceAll(c.Query("start"), " ", "+"))
+    if err != nil {
+        return series, time.Time{}, "", fail(fiber.StatusBadRequest, "Parameter start tidak valid (RFC3339)")
+    }
+
+    scope := c.Query("scope", scopeThis)
+    if scope != scopeThis && scope != scopeFollowing {
+        return series, time.Time{}, "", fail(fiber.StatusBadRequest, "Parameter scope harus 'this' atau 'following'")
+    }
+
+    err = config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": meetingID, "deletedAt": bson.M{"$exists": false}}).Decode(&series)
+    if err != nil {
+        if err == mongo.ErrNoDocuments {
+            return series, time.Time{}, "", fail(fiber.StatusNotFound, "Meeting tidak ditemukan")
+        }
+        return series, time.Time{}, "", fail(fiber.StatusInternalServerError, "Gagal mengambil data meeting")
+    }
+
+    if series.CreatedBy != userID {
+        return series, time.Time{}, "", fail(fiber.StatusForbidden, "Hanya pembuat meeting yang dapat mengubah")
+    }
+
+    if series.Recurrence == nil {
+        return series, time.Time{}, "", fail(fiber.StatusBadRequest, "Meeting ini tidak berulang")
+    }
+
+    if series.Status == models.MeetingCancelled {
+        return series, time.Time{}, "", fail(fiber.StatusConflict, "Meeting sudah dibatalkan, pulihkan terlebih dahulu")
+    }
+
+    rule, loc, err := parseRecurrence(series.Recurrence)
+    if err != nil || !rule.OccursAt(series.StartTime, loc, start) {
+        return series, time.Time{}, "", fail(fiber.StatusNotFound, "Occurrence tidak ditemukan")
+    }
+
+    return series, start.UTC(), scope, nil
+}
+
+// materializeOccurrence returns the stored override of an occurrence,
+// storing an unchanged copy of the occurrence when there is none. The copy
+// gets its own guest tokens, so each link leads to one meeting. When two
+// requests store it at once, the unique index keeps one and the other
+// reads it back.
+func materializeOccurrence(ctx context.Context, series models.Meeting, start time.Time) (models.Meeting, error) {
+    var exception models.Meeting
+    err := config.MeetingCollectionRef.FindOne(ctx, bson.M{"seriesId": series.ID, "recurrenceId": start}).Decode(&exception)
+    if err != mongo.ErrNoDocuments {
+        return exception, err
+    }
+    exception = newOccurrenceOverride(series, start)
+    if exception.Guests, err = reissueGuestTokens(exception.Guests); err != nil {
+        return exception, err
+    }
+    _, err = config.MeetingCollectionRef.InsertOne(ctx, exception)
+    if mongo.IsDuplicateKeyError(err) {
+        err = config.MeetingCollectionRef.FindOne(ctx, bson.M{"seriesId": series.ID, "recurrenceId": start}).Decode(&exception)
+    }
+    return exception, err
+}
+
+// EnsureOccurrenceIndex allows one override per occurrence of a series
+func EnsureOccurrenceIndex(ctx context.Context) error {
+    _, err := config.MeetingCollectionRef.Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys: bson.D{{Key: "seriesId", Value: 1}, {Key: "recurrenceId", Value: 1}},
+        Options: options.Index().SetUnique(true).
+            SetPartialFilterExpression(bson.M{"seriesId": bson.M{"$exists": true}}),
+    })
+    return err
+}
+
+// newOccurrenceOverride copies the series into a meeting for one occurrence
+func newOccurrenceOverride(series models.Meeting, start time.Time) models.Meeting {
+    seriesID := series.ID
+    recurrenceID := start
+    exception := series
+    exception.ID = primitive.NewObjectID()
+    exception.Recurrence = nil
+    exception.SeriesID = &seriesID
+    exception.RecurrenceID = &recurrenceID
+    exception.StartTime = start
+    exception.CreatedAt = time.Now()
+    return exception
+}
+
+// excludeOccurrence cancels one occurrence, moving any override of it to
+// the trash
+func excludeOccurrence(ctx context.Context, seriesID primitive.ObjectID, start time.Time) error {
+    now := time.Now()
+    _, err := config.MeetingCollectionRef.UpdateOne(ctx, bson.M{"_id": seriesID}, bson.M{
+        "$addToSet": bson.M{"recurrence.exDates": start},
+        "$set":      bson.M{"updatedAt": now},
+        "$inc":      bson.M{"sequence": 1},
+    })
+    if err != nil {
+        return err
+    }
+    return trashOccurrences(ctx, bson.M{"seriesId": seriesID, "recurrenceId": start}, now)
+}
+
+// followingSeries builds the series of the occurrences from start on with
+// the update applied ("this and following"). From the first occurrence it
+// is the whole series, otherwise a new one.
+func followingSeries(series models.Meeting, start time.Time, updateData models.Meeting, agendaProposals *bool) (models.Meeting, error) {
+    rule, loc, err := parseRecurrence(series.Recurrence)
+    if err != nil {
+        return series, err
+    }
+
+    newSeries := series
+    newSeries.Recurrence = &models.Recurrence{
+        RRule:    series.Recurrence.RRule,
+        Timezone: series.Recurrence.Timezone,
+    }
+    for _, d := range series.Recurrence.ExDates {
+        if !d.Before(start) {
+            newSeries.Recurrence.ExDates = append(newSeries.Recurrence.ExDates, d)
+        }
+    }
+
+    if !start.Equal(series.StartTime) {
+        if rule.Count > 0 {
+            remaining := *rule
+            remaining.Count = rule.Count - rule.CountBefore(series.StartTime, loc, start)
+            newSeries.Recurrence.RRule = remaining.String()
+        }
+        newSeries.ID = primitive.NewObjectID()
+        newSeries.StartTime = start
+        newSeries.CreatedAt = time.Now()
+    }
+    applyMeetingUpdate(&newSeries, updateData, agendaProposals)
+    // Guests of a new series are sent links of their own to it
+    if newSeries.ID != series.ID {
+        if newSeries.Guests, err = reissueGuestTokens(newSeries.Guests); err != nil {
+            return newSeries, err
+        }
+    }
+    if _, _, err := parseRecurrence(newSeries.Recurrence); err != nil {
+        return newSeries, err
+    }
+    return newSeries, nil
+}
+
+// splitSeries stores the series built by followingSeries: in place when it
+// starts with the series, otherwise ending the series before start
+func splitSeries(ctx context.Context, series models.Meeting, start time.Time, newSeries models.Meeting) error {
+    if newSeries.ID == series.ID {
+        _, err := config.MeetingCollectionRef.ReplaceOne(ctx, bson.M{"_id": series.ID}, newSeries)
+        return err
+    }
+
+    if err := truncateSeries(ctx, series, start); err != nil {
+        return err
+    }
+    if _, err := config.MeetingCollectionRef.InsertOne(ctx, newSeries); err != nil {
+        return err
+    }
+
+    // Overrides of moved occurrences now belong to the new series
+    _, err := config.MeetingCollectionRef.UpdateMany(ctx,
+        bson.M{"seriesId": series.ID, "recurrenceId": bson.M{"$gte": start}},
+        bson.M{"$set": bson.M{"seriesId": newSeries.ID}},
+    )
+    return err
+}
+
+// truncateSeries makes the series end right before start
+func truncateSeries(ctx context.Context, series models.Meeting, start time.Time) error {
+    rule, loc, err := parseRecurrence(series.Recurrence)
+    if err != nil {
+        return err
+    }
+
+    if rule.Count > 0 {
+        rule.Count = rule.CountBefore(series.StartTime, loc, start)
+    } else {
+        rule.Until = start.Add(-time.Second)
+    }
+
+    exDates := []time.Time{}
+    for _, d := range series.Recurrence.ExDates {
+        if d.Before(start) {
+            exDates = append(exDates, d)
+        }
+    }
+
+    _, err = config.MeetingCollectionRef.UpdateOne(ctx, bson.M{"_id": series.ID}, bson.M{
+        "$set": bson.M{
+            "recurrence.rrule":   rule.String(),
+            "recurrence.exDates": exDates,
+            "updatedAt":          time.Now(),
+        },
+        "$inc": bson.M{"sequence": 1},
+    })
+    return err
+}
+
+// applyMeetingUpdate applies the same partial update rules as UpdateMeeting
+func applyMeetingUpdate(meeting *models.Meeting, updateData models.Meeting, agendaProposals *bool) {
+    if updateData.Title != "" {
+        meeting.Title = updateData.Title
+    }
+    if updateData.Description != "" {
+        meeting.Description = updateData.Description
+    }
+    if !updateData.StartTime.IsZero() {
+        meeting.StartTime = updateData.StartTime
+    }
+    if updateData.Duration > 0 {
+        meeting.Duration = updateData.Duration
+    }
+    if updateData.Participants != nil {
+        meeting.Participants = updateData.Participants
+        meeting.Guests = updateData.Guests
+        meeting.Responses = pruneResponses(meeting.Responses, meeting.Participants, meeting.Guests)
+    }
+    if updateData.Recurrence != nil && meeting.Recurrence != nil && updateData.Recurrence.RRule != "" {
+        meeting.Recurrence.RRule = updateData.Recurrence.RRule
+        if updateData.Recurrence.Timezone != "" {
+            meeting.Recurrence.Timezone = updateData.Recurrence.Timezone
+        }
+    }
+    meeting.EmotionTracking = updateData.EmotionTracking
+    if agendaProposals != nil {
+        meeting.AgendaProposals = *agendaProposals
+    }
+    if updateData.EmotionVisibility != "" && validEmotionVisibility(updateData.EmotionVisibility) {
+        meeting.EmotionVisibility = updateData.EmotionVisibility
+    }
+    meeting.Sequence++
+    meeting.UpdatedAt = time.Now()
+}
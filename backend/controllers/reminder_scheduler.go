@@ -0,0 +1,409 @@
+package controllers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/notify"
+    "pbommo/utils"
+)
+
+const (
+    reminderTick = 30 * time.Second
+    // reminderPlanInterval is how often meetings are expanded into jobs
+    reminderPlanInterval = 5 * time.Minute
+    // reminderLease is how long an instance owns a job it is sending; a
+    // send never takes longer than half of it
+    reminderLease       = 2 * time.Minute
+    reminderMaxAttempts = 5
+    reminderBatchSize   = 100
+    // reminderRetention is how long finished jobs are kept. It must exceed
+    // MaxReminderOffset, or expired jobs would be planned again.
+    reminderRetention = 7 * 24 * time.Hour
+)
+
+var errReminderLeaseLost = errors.New("reminder lease lost")
+
+// StartReminderScheduler runs the reminder loop in the background until ctx
+// is done. Every tick it plans jobs for reminders that are due soon and
+// sends the due ones. Several instances may run it against the same
+// database: jobs are unique per user, occurrence, offset and channel, and
+// each job is leased before it is sent.
+func StartReminderScheduler(ctx context.Context) {
+    if !config.ReminderSchedulerEnabled() {
+        log.Println("⏰ Reminder scheduler disabled")
+        return
+    }
+
+    if err := ensureReminderIndexes(ctx); err != nil {
+        log.Printf("Failed to create reminder indexes: %v", err)
+    }
+
+    owner := schedulerInstanceID()
+    log.Printf("⏰ Reminder scheduler started as %s", owner)
+
+    go func() {
+        ticker := time.NewTicker(reminderTick)
+        defer ticker.Stop()
+        var planned time.Time
+        for {
+            planned = runReminderTick(ctx, owner, planned)
+            select {
+            case <-ctx.Done():
+                return
+            case <-ticker.C:
+            }
+        }
+    }()
+}
+
+// runReminderTick sends the due jobs, planning new ones first when the last
+// planning was at least reminderPlanInterval ago. It returns when planning
+// last succeeded.
+func runReminderTick(ctx context.Context, owner string, planned time.Time) time.Time {
+    tickCtx, cancel := context.WithTimeout(ctx, reminderTick)
+    defer cancel()
+
+    now := time.Now()
+    if now.Sub(planned) >= reminderPlanInterval {
+        if err := planReminders(tickCtx, now); err != nil {
+            log.Printf("Failed to plan reminders: %v", err)
+        } else {
+            planned = now
+        }
+    }
+    if err := dispatchReminders(tickCtx, owner); err != nil {
+        log.Printf("Failed to dispatch reminders: %v", err)
+    }
+    return planned
+}
+
+func ensureReminderIndexes(ctx context.Context) error {
+    _, err := config.ReminderCollectionRef.Indexes().CreateMany(ctx, []mongo.IndexModel{
+        {
+            Keys: bson.D{
+                {Key: "meetingId", Value: 1},
+                {Key: "userId", Value: 1},
+                {Key: "occurrenceStart", Value: 1},
+                {Key: "offsetMinutes", Value: 1},
+                {Key: "channel", Value: 1},
+            },
+            Options: options.Index().SetUnique(true),
+        },
+        {Keys: bson.D{{Key: "status", Value: 1}, {Key: "fireAt", Value: 1}}},
+    })
+    if err != nil {
+        return err
+    }
+    return config.EnsureTTLIndex(ctx, config.ReminderCollectionRef, "finishedAt", reminderRetention)
+}
+
+// planReminders creates jobs for reminders firing before the next planning
+// for meetings that have not started yet. Reminders whose time passed while
+// no scheduler was running are still planned, and sent late. Only
+// occurrences within the longest reminder offset are expanded.
+func planReminders(ctx context.Context, now time.Time) error {
+    horizon := now.Add(reminderPlanInterval + 2*reminderTick)
+    to := horizon.Add(time.Duration(config.MaxReminderOffset) * time.Minute)
+
+    cursor, err := config.MeetingCollectionRef.Find(ctx, bson.M{
+        "status":    bson.M{"$ne": models.MeetingCancelled},
+        "deletedAt": bson.M{"$exists": false},
+        "$or": []bson.M{
+            {"recurrence": bson.M{"$exists": false}, "startTime": bson.M{"$gt": now, "$lt": to}},
+            {"recurrence": bson.M{"$exists": true}, "startTime": bson.M{"$lt": to}},
+        },
+    })
+    if err != nil {
+        return err
+    }
+    var meetings []models.Meeting
+    if err := cursor.All(ctx, &meetings); err != nil {
+        return err
+    }
+    occurrences, err := expandMeetings(ctx, meetings, now.Add(time.Second), to)
+    if err != nil {
+        return err
+    }
+    if len(occurrences) == 0 {
+        return nil
+    }
+
+    var ids []primitive.ObjectID
+    for _, m := range occurrences {
+        ids = append(ids, m.CreatedBy)
+        ids = append(ids, m.Participants...)
+    }
+    users, err := usersByID(ctx, ids)
+    if err != nil {
+        return err
+    }
+
+    channels := config.ReminderChannels()
+    for _, m := range occurrences {
+        for _, id := range append([]primitive.ObjectID{m.CreatedBy}, m.Participants...) {
+            user, ok := users[id]
+            if !ok || user.Disabled || !isBusyIn(m, id) {
+                continue
+            }
+            prefs := user.NotificationPrefs()
+            if !prefs.MeetingReminders {
+                continue
+            }
+            offsets := prefs.ReminderOffsets
+            if len(offsets) == 0 {
+                offsets = config.ReminderOffsets()
+            }
+            for _, offset := range offsets {
+                fireAt := m.StartTime.Add(-time.Duration(offset) * time.Minute)
+                if fireAt.After(horizon) {
+                    continue
+                }
+                for _, name := range channels {
+                    if ch, ok := notify.Lookup(name); !ok || !ch.Accepts(user) {
+                        continue
+                    }
+                    if err := planReminder(ctx, m, id, name, offset, fireAt); err != nil {
+                        return err
+                    }
+                }
+            }
+        }
+    }
+    return nil
+}
+
+// planReminder inserts the job unless it already exists
+func planReminder(ctx context.Context, m models.Meeting, userID primitive.ObjectID, channel string, offset int, fireAt time.Time) error {
+    key := bson.M{
+        "meetingId":       m.ID,
+        "userId":          userID,
+        "occurrenceStart": m.StartTime,
+        "offsetMinutes":   offset,
+        "channel":         channel,
+    }
+    now := time.Now()
+    _, err := config.ReminderCollectionRef.UpdateOne(ctx, key, bson.M{"$setOnInsert": bson.M{
+        "fireAt":    fireAt,
+        "status":    models.ReminderPending,
+        "attempts":  0,
+        "createdAt": now,
+        "updatedAt": now,
+    }}, options.Update().SetUpsert(true))
+    // Another instance planning the same job at the same moment wins the race
+    if mongo.IsDuplicateKeyError(err) {
+        return nil
+    }
+    return err
+}
+
+// dispatchReminders leases and sends due jobs one at a time, so each job is
+// sent by exactly one instance
+func dispatchReminders(ctx context.Context, owner string) error {
+    for i := 0; i < reminderBatchSize; i++ {
+        now := time.Now()
+        var job models.ReminderJob
+        err := config.ReminderCollectionRef.FindOneAndUpdate(ctx,
+            bson.M{
+                "status": models.ReminderPending,
+                "fireAt": bson.M{"$lte": now},
+                "$or": []bson.M{
+                    {"leaseUntil": bson.M{"$exists": false}},
+                    {"leaseUntil": bson.M{"$lt": now}},
+                },
+            },
+            bson.M{
+                "$set": bson.M{"leaseOwner": owner, "leaseUntil": now.Add(reminderLease), "updatedAt": now},
+                "$inc": bson.M{"attempts": 1},
+            },
+            options.FindOneAndUpdate().SetSort(bson.M{"fireAt": 1}).SetReturnDocument(options.After),
+        ).Decode(&job)
+        if err == mongo.ErrNoDocuments {
+            return nil
+        }
+        if err != nil {
+            return err
+        }
+
+        status, sendErr := sendReminder(ctx, owner, job)
+        if sendErr == errReminderLeaseLost {
+            // Another instance took the job over and sends it
+            continue
+        }
+        if err := finishReminder(ctx, owner, job, status, sendErr); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// sendReminder re-checks that the reminder still applies, then renews the
+// lease and sends it. It returns the resulting status, or an error for a
+// failed attempt.
+func sendReminder(ctx context.Context, owner string, job models.ReminderJob) (string, error) {
+    var meeting models.Meeting
+    if err := config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": job.MeetingID}).Decode(&meeting); err != nil {
+        if err == mongo.ErrNoDocuments {
+            return models.ReminderSkipped, fmt.Errorf("meeting deleted")
+        }
+        return "", err
+    }
+
+    if meeting.Status == models.MeetingCancelled {
+        return models.ReminderSkipped, fmt.Errorf("meeting cancelled")
+    }
+    if meeting.DeletedAt != nil {
+        return models.ReminderSkipped, fmt.Errorf("meeting deleted")
+    }
+    if !time.Now().Before(job.OccurrenceStart) {
+        return models.ReminderSkipped, fmt.Errorf("meeting already started")
+    }
+    current, err := occursAt(ctx, meeting, job.OccurrenceStart)
+    if err != nil {
+        return "", err
+    }
+    if !current {
+        return models.ReminderSkipped, fmt.Errorf("meeting rescheduled")
+    }
+
+    var user models.User
+    if err := config.UserCollectionRef.FindOne(ctx, bson.M{"_id": job.UserID}).Decode(&user); err != nil {
+        return models.ReminderSkipped, fmt.Errorf("user not found")
+    }
+    if user.Disabled || !user.NotificationPrefs().MeetingReminders || !isBusyIn(meeting, user.ID) {
+        return models.ReminderSkipped, fmt.Errorf("reminder no longer wanted")
+    }
+
+    ch, ok := notify.Lookup(job.Channel)
+    if !ok || !ch.Accepts(user) {
+        return models.ReminderSkipped, fmt.Errorf("channel %s unavailable", job.Channel)
+    }
+
+    meeting.StartTime = job.OccurrenceStart
+    msg := notify.Message{
+        Kind:    "reminder",
+        User:    user,
+        Subject: "Pengingat: " + meeting.Title,
+        Text: fmt.Sprintf("Halo %s,\n\nMeeting \"%s\" akan dimulai dalam %d menit, pada %s (%d menit).",
+            user.Nama, meeting.Title, job.OffsetMinutes,
+            job.OccurrenceStart.In(userLocation(user)).Format("02 Jan 2006 15:04 MST"), meeting.Duration),
+        Link:    utils.FrontendURL() + "/meetings",
+        Meeting: &meeting,
+    }
+    if err := renewReminderLease(ctx, owner, job); err != nil {
+        return "", err
+    }
+    sendCtx, cancel := context.WithTimeout(ctx, reminderLease/2)
+    defer cancel()
+    if err := ch.Send(sendCtx, msg); err != nil {
+        return "", err
+    }
+    return models.ReminderSent, nil
+}
+
+// renewReminderLease gives the owner a full lease for sending, so the job
+// cannot be taken over mid-send
+func renewReminderLease(ctx context.Context, owner string, job models.ReminderJob) error {
+    now := time.Now()
+    result, err := config.ReminderCollectionRef.UpdateOne(ctx,
+        bson.M{"_id": job.ID, "leaseOwner": owner, "leaseUntil": bson.M{"$gt": now}},
+        bson.M{"$set": bson.M{"leaseUntil": now.Add(reminderLease), "updatedAt": now}})
+    if err != nil {
+        return err
+    }
+    if result.MatchedCount == 0 {
+        return errReminderLeaseLost
+    }
+    return nil
+}
+
+// occursAt reports whether the meeting still takes place at start. An
+// occurrence overridden by a separate meeting gets that meeting's jobs.
+func occursAt(ctx context.Context, meeting models.Meeting, start time.Time) (bool, error) {
+    if meeting.Recurrence == nil {
+        return meeting.StartTime.Equal(start), nil
+    }
+    rule, loc, err := parseRecurrence(meeting.Recurrence)
+    if err != nil {
+        return false, nil
+    }
+    if !rule.OccursAt(meeting.StartTime, loc, start) {
+        return false, nil
+    }
+    for _, ex := range meeting.Recurrence.ExDates {
+        if ex.Equal(start) {
+            return false, nil
+        }
+    }
+    count, err := config.MeetingCollectionRef.CountDocuments(ctx, bson.M{"seriesId": meeting.ID, "recurrenceId": start})
+    return count == 0, err
+}
+
+// finishReminder records the outcome of a leased job. Failed attempts are
+// retried with exponential backoff until reminderMaxAttempts.
+func finishReminder(ctx context.Context, owner string, job models.ReminderJob, status string, sendErr error) error {
+    now := time.Now()
+    set := bson.M{"status": status, "updatedAt": now}
+    if sendErr != nil {
+        set["lastError"] = sendErr.Error()
+    }
+    switch status {
+    case models.ReminderSent:
+        set["sentAt"] = now
+        set["finishedAt"] = now
+    case models.ReminderSkipped:
+        set["finishedAt"] = now
+    case "":
+        if job.Attempts >= reminderMaxAttempts {
+            set["status"] = models.ReminderFailed
+            set["finishedAt"] = now
+            log.Printf("Reminder %s failed after %d attempts: %v", job.ID.Hex(), job.Attempts, sendErr)
+        } else {
+            set["status"] = models.ReminderPending
+            set["fireAt"] = now.Add(reminderTick << uint(job.Attempts-1))
+        }
+    }
+
+    _, err := config.ReminderCollectionRef.UpdateOne(ctx,
+        bson.M{"_id": job.ID, "leaseOwner": owner},
+        bson.M{"$set": set, "$unset": bson.M{"leaseOwner": "", "leaseUntil": ""}})
+    return err
+}
+
+// usersByID loads the given users keyed by id
+func usersByID(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]models.User, error) {
+    users := map[primitive.ObjectID]models.User{}
+    if len(ids) == 0 {
+        return users, nil
+    }
+    cursor, err := config.UserCollectionRef.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+    if err != nil {
+        return nil, err
+    }
+    var list []models.User
+    if err := cursor.All(ctx, &list); err != nil {
+        return nil, err
+    }
+    for _, u := range list {
+        users[u.ID] = u
+    }
+    return users, nil
+}
+
+// schedulerInstanceID identifies this process as a lease owner
+func schedulerInstanceID() string {
+    host, _ := os.Hostname()
+    suffix, _ := utils.GenerateRandomToken(4)
+    return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), suffix)
+}
@@ -0,0 +1,314 @@
+package controllers
+
+import (
+    "context"
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/utils"
+)
+
+// maxRSVPComment is the longest comment kept with a response
+const maxRSVPComment = 500
+
+// rsvpInput is the body of the respond endpoints
+type rsvpInput struct {
+    Status            string     `json:"status"`
+    Comment           string     `json:"comment"`
+    ProposedStartTime *time.Time `json:"proposedStartTime"`
+}
+
+// RespondToMeeting records the current user's RSVP for a meeting
+func RespondToMeeting(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meetingID, err := primitive.ObjectIDFromHex(c.Params("id"))
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID meeting tidak valid"})
+    }
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    var input rsvpInput
+    if err := c.BodyParser(&input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+    }
+    if !validResponseStatus(input.Status) {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Status harus pending, accepted, declined atau tentative"})
+    }
+    input.Comment = strings.TrimSpace(input.Comment)
+    if len(input.Comment) > maxRSVPComment {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Komentar maksimal %d karakter", maxRSVPComment)})
+    }
+
+    var meeting models.Meeting
+    err = config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": meetingID, "deletedAt": bson.M{"$exists": false}}).Decode(&meeting)
+    if err != nil {
+        if err == mongo.ErrNoDocuments {
+            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Meeting tidak ditemukan"})
+        }
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data meeting"})
+    }
+
+    if !isParticipant(meeting, userID) {
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya peserta meeting yang dapat merespons"})
+    }
+
+    response := models.Response{
+        UserID:            &userID,
+        Status:            input.Status,
+        Comment:           input.Comment,
+        ProposedStartTime: input.ProposedStartTime,
+        RespondedAt:       time.Now(),
+    }
+    if err := saveResponse(ctx, meeting, response); err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan respons"})
+    }
+    if findResponse(meeting, &userID, "").Status != response.Status {
+        var user models.User
+        _ = config.UserCollectionRef.FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+        notifyRSVP(meeting, user.Nama, &userID, response.Status)
+    }
+    publishRSVPEvent(meeting, response)
+
+    return c.JSON(fiber.Map{
+        "message":  "Respons berhasil disimpan",
+        "response": response,
+    })
+}
+
+// RespondAsGuest records an external guest's RSVP through their access token
+func RespondAsGuest(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    token := c.Params("token")
+    var meeting models.Meeting
+    err := config.MeetingCollectionRef.FindOne(ctx, bson.M{"guests.token": token, "deletedAt": bson.M{"$exists": false}}).Decode(&meeting)
+    if token == "" || err != nil {
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Meeting tidak ditemukan"})
+    }
+
+    var input rsvpInput
+    if err := c.BodyParser(&input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+    }
+    if !validResponseStatus(input.Status) {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Status harus pending, accepted, declined atau tentative"})
+    }
+    input.Comment = strings.TrimSpace(input.Comment)
+    if len(input.Comment) > maxRSVPComment {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Komentar maksimal %d karakter", maxRSVPComment)})
+    }
+
+    var email string
+    for _, g := range meeting.Guests {
+        if g.Token == token {
+            email = g.Email
+        }
+    }
+
+    response := models.Response{
+        GuestEmail:        email,
+        Status:            input.Status,
+        Comment:           input.Comment,
+        ProposedStartTime: input.ProposedStartTime,
+        RespondedAt:       time.Now(),
+    }
+    if err := saveResponse(ctx, meeting, response); err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan respons"})
+    }
+    if findResponse(meeting, nil, email).Status != response.Status {
+        notifyRSVP(meeting, email, nil, response.Status)
+    }
+    publishRSVPEvent(meeting, response)
+
+    return c.JSON(fiber.Map{
+        "message":  "Respons berhasil disimpan",
+        "response": response,
+    })
+}
+
+// GetMeetingResponses returns the RSVP summary of a meeting to its organizer
+func GetMeetingResponses(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    meetingID, err := primitive.ObjectIDFromHex(c.Params("id"))
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID meeting tidak valid"})
+    }
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    var meeting models.Meeting
+    err = config.MeetingCollectionRef.FindOne(ctx, bson.M{"_id": meetingID, "deletedAt": bson.M{"$exists": false}}).Decode(&meeting)
+    if err != nil {
+        if err == mongo.ErrNoDocuments {
+            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Meeting tidak ditemukan"})
+        }
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data meeting"})
+    }
+
+    if meeting.CreatedBy != userID {
+        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya pembuat meeting yang dapat melihat respons"})
+    }
+
+    cursor, err := config.UserCollectionRef.Find(ctx, bson.M{"_id": bson.M{"$in": meeting.Participants}})
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data peserta"})
+    }
+    var users []models.User
+    if err := cursor.All(ctx, &users); err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data peserta"})
+    }
+    usersByID := map[primitive.ObjectID]models.User{}
+    for _, u := range users {
+        usersByID[u.ID] = u
+    }
+
+    counts := fiber.Map{
+        models.ResponsePending:   0,
+        models.ResponseAccepted:  0,
+        models.ResponseDeclined:  0,
+        models.ResponseTentative: 0,
+    }
+    var proposals []fiber.Map
+    entries := []fiber.Map{}
+    add := func(entry fiber.Map, r models.Response) {
+        entry["status"] = r.Status
+        entry["comment"] = r.Comment
+        entry["proposedStartTime"] = r.ProposedStartTime
+        if !r.RespondedAt.IsZero() {
+            entry["respondedAt"] = r.RespondedAt
+        }
+        if validResponseStatus(r.Status) {
+            counts[r.Status] = counts[r.Status].(int) + 1
+        }
+        if r.ProposedStartTime != nil {
+            proposals = append(proposals, fiber.Map{"by": entry["nama"], "proposedStartTime": r.ProposedStartTime, "comment": r.Comment})
+        }
+        entries = append(entries, entry)
+    }
+
+    for _, id := range meeting.Participants {
+        u := usersByID[id]
+        add(fiber.Map{"userId": id, "nama": u.Nama, "email": u.Email, "guest": false}, findResponse(meeting, &id, ""))
+    }
+    for _, g := range meeting.Guests {
+        add(fiber.Map{"nama": g.Email, "email": g.Email, "guest": true}, findResponse(meeting, nil, g.Email))
+    }
+
+    return c.JSON(fiber.Map{
+        "meetingId": meeting.ID,
+        "total":     len(entries),
+        "counts":    counts,
+        "proposals": proposals,
+        "responses": entries,
+    })
+}
+
+// myResponseFilter builds the GetMeetings filter for ?response=: meetings
+// the user participates in with the given RSVP state
+func myResponseFilter(userID primitive.ObjectID, status string) bson.M {
+    if status == models.ResponsePending {
+        answered := []string{models.ResponseAccepted, models.ResponseDeclined, models.ResponseTentative}
+        return bson.M{
+            "participants": userID,
+            "responses": bson.M{"$not": bson.M{"$elemMatch": bson.M{
+                "userId": userID,
+                "status": bson.M{"$in": answered},
+            }}},
+        }
+    }
+    return bson.M{
+        "participants": userID,
+        "responses":    bson.M{"$elemMatch": bson.M{"userId": userID, "status": status}},
+    }
+}
+
+// findResponse returns the stored response of a user or guest, or pending
+func findResponse(meeting models.Meeting, userID *primitive.ObjectID, guestEmail string) models.Response {
+    for _, r := range meeting.Responses {
+        if userID != nil && r.UserID != nil && *r.UserID == *userID {
+            return r
+        }
+        if guestEmail != "" && r.GuestEmail == guestEmail {
+            return r
+        }
+    }
+    return models.Response{UserID: userID, GuestEmail: guestEmail, Status: models.ResponsePending}
+}
+
+// saveResponse replaces any earlier response of the same user or guest.
+// It runs as a single pipeline update on the stored array, so concurrent
+// responses of other attendees are kept.
+func saveResponse(ctx context.Context, meeting models.Meeting, response models.Response) error {
+    other := bson.M{"$ne": bson.A{"$$r.guestEmail", response.GuestEmail}}
+    if response.UserID != nil {
+        other = bson.M{"$ne": bson.A{"$$r.userId", *response.UserID}}
+    }
+    _, err := config.MeetingCollectionRef.UpdateOne(ctx, bson.M{"_id": meeting.ID}, mongo.Pipeline{
+        {{Key: "$set", Value: bson.M{"responses": bson.M{"$concatArrays": bson.A{
+            bson.M{"$filter": bson.M{
+                "input": bson.M{"$ifNull": bson.A{"$responses", bson.A{}}},
+                "as":    "r",
+                "cond":  other,
+            }},
+            bson.A{bson.M{"$literal": response}},
+        }}}}},
+    })
+    return err
+}
+
+// pruneResponses drops responses of people no longer invited
+func pruneResponses(responses []models.Response, participants []primitive.ObjectID, guests []models.Guest) []models.Response {
+    invited := map[primitive.ObjectID]bool{}
+    for _, id := range participants {
+        invited[id] = true
+    }
+    invitedGuests := map[string]bool{}
+    for _, g := range guests {
+        invitedGuests[g.Email] = true
+    }
+
+    kept := []models.Response{}
+    for _, r := range responses {
+        if r.UserID != nil && invited[*r.UserID] || r.GuestEmail != "" && invitedGuests[r.GuestEmail] {
+            kept = append(kept, r)
+        }
+    }
+    return kept
+}
+
+func isParticipant(meeting models.Meeting, userID primitive.ObjectID) bool {
+    for _, p := range meeting.Participants {
+        if p == userID {
+            return true
+        }
+    }
+    return false
+}
+
+func validResponseStatus(status string) bool {
+    switch status {
+    case models.ResponsePending, models.ResponseAccepted, models.ResponseDeclined, models.ResponseTentative:
+        return true
+    }
+    return false
+}
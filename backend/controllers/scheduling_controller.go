@@ -0,0 +1,369 @@
+package controllers
+
+import (
+    "context"
+    "sort"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/utils"
+)
+
+const (
+    maxSchedulingWindow = 31 * 24 * time.Hour
+    maxSuggestions      = 50
+)
+
+// timeRange is a half-open interval [Start, End)
+type timeRange struct {
+    Start time.Time `json:"start"`
+    End   time.Time `json:"end"`
+}
+
+func (r timeRange) overlaps(o timeRange) bool {
+    return r.Start.Before(o.End) && o.Start.Before(r.End)
+}
+
+func (r timeRange) contains(o timeRange) bool {
+    return !o.Start.Before(r.Start) && !o.End.After(r.End)
+}
+
+// schedulingInput is the body of the free/busy and suggestion endpoints.
+// The requesting user is always included in the lookup.
+type schedulingInput struct {
+    Participants  []string  `json:"participants"`
+    WindowStart   time.Time `json:"windowStart"`
+    WindowEnd     time.Time `json:"windowEnd"`
+    Duration      int       `json:"duration"`      // minutes
+    BufferMinutes int       `json:"bufferMinutes"` // free time required around the slot
+    StepMinutes   int       `json:"stepMinutes"`   // granularity of candidate start times
+    Limit         int       `json:"limit"`
+    AllowPartial  bool      `json:"allowPartial"` // also suggest slots not everyone can attend
+}
+
+// SlotSuggestion is one candidate time for a meeting
+type SlotSuggestion struct {
+    Start       time.Time            `json:"start"`
+    End         time.Time            `json:"end"`
+    Score       float64              `json:"score"`
+    Available   []primitive.ObjectID `json:"available"`
+    Unavailable []primitive.ObjectID `json:"unavailable"`
+}
+
+// schedule is what the lookup knows about one user in the search window
+type schedule struct {
+    User    models.User
+    Busy    []timeRange // meetings (widened by the buffer) and absences
+    Working []timeRange // excluding holidays
+}
+
+// GetFreeBusy returns the busy times and working hours of the requested
+// users without revealing meeting details
+func GetFreeBusy(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    var input schedulingInput
+    if err := c.BodyParser(&input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+    }
+    if msg := validateSchedulingWindow(input); msg != "" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+    }
+    input.BufferMinutes = 0
+
+    schedules, errMsg, err := loadSchedules(ctx, userID, input)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil jadwal"})
+    }
+    if errMsg != "" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": errMsg})
+    }
+
+    result := []fiber.Map{}
+    for _, s := range schedules {
+        result = append(result, fiber.Map{
+            "userId":   s.User.ID,
+            "nama":     s.User.Nama,
+            "timezone": userLocation(s.User).String(),
+            "busy":     mergeRanges(s.Busy),
+            "working":  s.Working,
+        })
+    }
+
+    return c.JSON(fiber.Map{
+        "windowStart": input.WindowStart,
+        "windowEnd":   input.WindowEnd,
+        "users":       result,
+    })
+}
+
+// SuggestSlots ranks free time slots in the search window for the
+// requesting user and the given participants
+func SuggestSlots(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    var input schedulingInput
+    if err := c.BodyParser(&input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+    }
+    if msg := validateSchedulingWindow(input); msg != "" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+    }
+    if input.Duration <= 0 || time.Duration(input.Duration)*time.Minute > maxMeetingLength {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Durasi meeting tidak valid"})
+    }
+    if input.BufferMinutes < 0 || input.BufferMinutes > 240 {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Buffer harus antara 0 dan 240 menit"})
+    }
+    if input.StepMinutes <= 0 {
+        input.StepMinutes = 15
+    } else if input.StepMinutes < 5 {
+        input.StepMinutes = 5
+    }
+    if input.Limit <= 0 {
+        input.Limit = 10
+    } else if input.Limit > maxSuggestions {
+        input.Limit = maxSuggestions
+    }
+
+    schedules, errMsg, err := loadSchedules(ctx, userID, input)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil jadwal"})
+    }
+    if errMsg != "" {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": errMsg})
+    }
+
+    return c.JSON(fiber.Map{
+        "duration":      input.Duration,
+        "bufferMinutes": input.BufferMinutes,
+        "slots":         suggestSlots(schedules, input),
+    })
+}
+
+func validateSchedulingWindow(input schedulingInput) string {
+    if input.WindowStart.IsZero() || input.WindowEnd.IsZero() || !input.WindowEnd.After(input.WindowStart) {
+        return "Rentang waktu pencarian tidak valid"
+    }
+    if input.WindowEnd.Sub(input.WindowStart) > maxSchedulingWindow {
+        return "Rentang waktu pencarian maksimal 31 hari"
+    }
+    return ""
+}
+
+// loadSchedules collects busy times and working hours for the requester and
+// the participants. A non-empty message reports invalid participants.
+func loadSchedules(ctx context.Context, requesterID primitive.ObjectID, input schedulingInput) ([]schedule, string, error) {
+    ids := []primitive.ObjectID{requesterID}
+    seen := map[primitive.ObjectID]bool{requesterID: true}
+    for _, entry := range input.Participants {
+        id, err := primitive.ObjectIDFromHex(entry)
+        if err != nil {
+            return nil, "ID peserta tidak valid: " + entry, nil
+        }
+        if !seen[id] {
+            seen[id] = true
+            ids = append(ids, id)
+        }
+    }
+
+    cursor, err := config.UserCollectionRef.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+    if err != nil {
+        return nil, "", err
+    }
+    var users []models.User
+    if err := cursor.All(ctx, &users); err != nil {
+        return nil, "", err
+    }
+    byID := map[primitive.ObjectID]models.User{}
+    for _, u := range users {
+        byID[u.ID] = u
+    }
+    for _, id := range ids {
+        if _, ok := byID[id]; !ok {
+            return nil, "Peserta tidak ditemukan: " + id.Hex(), nil
+        }
+    }
+
+    buffer := time.Duration(input.BufferMinutes) * time.Minute
+    meetings, err := busyMeetings(ctx, ids, input.WindowStart.Add(-buffer), input.WindowEnd.Add(buffer))
+    if err != nil {
+        return nil, "", err
+    }
+
+    schedules := make([]schedule, 0, len(ids))
+    for _, id := range ids {
+        s := schedule{User: byID[id], Busy: []timeRange{}}
+        for _, m := range meetings {
+            if !isBusyIn(m, id) {
+                continue
+            }
+            end := m.StartTime.Add(time.Duration(m.Duration) * time.Minute)
+            s.Busy = append(s.Busy, timeRange{Start: m.StartTime.Add(-buffer), End: end.Add(buffer)})
+        }
+        for _, a := range s.User.Absences {
+            s.Busy = append(s.Busy, timeRange{Start: a.Start, End: a.End})
+        }
+        sort.Slice(s.Busy, func(i, j int) bool { return s.Busy[i].Start.Before(s.Busy[j].Start) })
+        s.Working = loadAvailability(s.User, input.WindowStart, input.WindowEnd).Working
+        schedules = append(schedules, s)
+    }
+    return schedules, "", nil
+}
+
+// suggestSlots scores every candidate start time and picks the best
+// non-overlapping ones. Slots everyone can attend always rank first.
+func suggestSlots(schedules []schedule, input schedulingInput) []SlotSuggestion {
+    length := time.Duration(input.Duration) * time.Minute
+    step := time.Duration(input.StepMinutes) * time.Minute
+    window := input.WindowEnd.Sub(input.WindowStart)
+
+    var candidates []SlotSuggestion
+    start := input.WindowStart.Truncate(step)
+    if start.Before(input.WindowStart) {
+        start = start.Add(step)
+    }
+    for ; !start.Add(length).After(input.WindowEnd); start = start.Add(step) {
+        slot := timeRange{Start: start, End: start.Add(length)}
+        suggestion := SlotSuggestion{
+            Start:       slot.Start,
+            End:         slot.End,
+            Available:   []primitive.ObjectID{},
+            Unavailable: []primitive.ObjectID{},
+        }
+        var slack, comfort float64
+        for _, s := range schedules {
+            if !s.isFree(slot) {
+                suggestion.Unavailable = append(suggestion.Unavailable, s.User.ID)
+                continue
+            }
+            suggestion.Available = append(suggestion.Available, s.User.ID)
+            slack += s.slack(slot)
+            comfort += s.comfort(slot)
+        }
+        if len(suggestion.Available) == 0 || len(suggestion.Unavailable) > 0 && !input.AllowPartial {
+            continue
+        }
+
+        // Up to 50 points for free time around the slot, 30 for staying
+        // clear of the edges of the working day and 20 for being early
+        n := float64(len(suggestion.Available))
+        attendance := n / float64(len(schedules))
+        earliness := 1 - float64(start.Sub(input.WindowStart))/float64(window)
+        score := attendance * (50*slack/n + 30*comfort/n + 20*earliness)
+        suggestion.Score = float64(int(score*10+0.5)) / 10
+        candidates = append(candidates, suggestion)
+    }
+
+    sort.SliceStable(candidates, func(i, j int) bool {
+        if len(candidates[i].Unavailable) != len(candidates[j].Unavailable) {
+            return len(candidates[i].Unavailable) < len(candidates[j].Unavailable)
+        }
+        return candidates[i].Score > candidates[j].Score
+    })
+
+    slots := []SlotSuggestion{}
+    for _, candidate := range candidates {
+        if len(slots) == input.Limit {
+            break
+        }
+        taken := false
+        for _, s := range slots {
+            if (timeRange{s.Start, s.End}).overlaps(timeRange{candidate.Start, candidate.End}) {
+                taken = true
+                break
+            }
+        }
+        if !taken {
+            slots = append(slots, candidate)
+        }
+    }
+    return slots
+}
+
+// isFree reports whether the slot lies within working hours and clear of
+// every (buffered) meeting
+func (s schedule) isFree(slot timeRange) bool {
+    inWorkingHours := false
+    for _, w := range s.Working {
+        if w.contains(slot) {
+            inWorkingHours = true
+            break
+        }
+    }
+    if !inWorkingHours {
+        return false
+    }
+    for _, b := range s.Busy {
+        if b.overlaps(slot) {
+            return false
+        }
+    }
+    return true
+}
+
+// slack rates the free time on both sides of the slot from 0 (back to back)
+// to 1 (an hour or more)
+func (s schedule) slack(slot timeRange) float64 {
+    gap := time.Hour
+    for _, b := range s.Busy {
+        if !b.End.After(slot.Start) && slot.Start.Sub(b.End) < gap {
+            gap = slot.Start.Sub(b.End)
+        }
+        if !b.Start.Before(slot.End) && b.Start.Sub(slot.End) < gap {
+            gap = b.Start.Sub(slot.End)
+        }
+    }
+    return float64(gap) / float64(time.Hour)
+}
+
+// comfort rates the distance from the start and end of the working day from
+// 0 to 1 (an hour or more)
+func (s schedule) comfort(slot timeRange) float64 {
+    for _, w := range s.Working {
+        if !w.contains(slot) {
+            continue
+        }
+        gap := slot.Start.Sub(w.Start)
+        if d := w.End.Sub(slot.End); d < gap {
+            gap = d
+        }
+        if gap > time.Hour {
+            gap = time.Hour
+        }
+        return float64(gap) / float64(time.Hour)
+    }
+    return 0
+}
+
+// mergeRanges joins overlapping and adjacent ranges of a sorted list
+func mergeRanges(ranges []timeRange) []timeRange {
+    merged := []timeRange{}
+    for _, r := range ranges {
+        if n := len(merged); n > 0 && !r.Start.After(merged[n-1].End) {
+            if r.End.After(merged[n-1].End) {
+                merged[n-1].End = r.End
+            }
+            continue
+        }
+        merged = append(merged, r)
+    }
+    return merged
+}
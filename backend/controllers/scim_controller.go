@@ -0,0 +1,1106 @@
+package controllers
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+
+    "pbommo/config"
+    "pbommo/models"
+)
+
+// SCIM 2.0 provisioning (RFC 7643 / RFC 7644).
+// Users map onto models.User (userName = email) and Groups map onto teams;
+// a user belongs to at most one team, stored by name on User.Team.
+
+const (
+    scimContentType  = "application/scim+json"
+    scimUserSchema   = "urn:ietf:params:scim:schemas:core:2.0:User"
+    scimGroupSchema  = "urn:ietf:params:scim:schemas:core:2.0:Group"
+    scimListSchema   = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+    scimPatchSchema  = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+    scimErrorSchema  = "urn:ietf:params:scim:api:messages:2.0:Error"
+    scimDefaultCount = 100
+    scimMaxCount     = 200
+)
+
+type scimName struct {
+    Formatted  string `json:"formatted,omitempty"`
+    GivenName  string `json:"givenName,omitempty"`
+    FamilyName string `json:"familyName,omitempty"`
+}
+
+type scimMultiValue struct {
+    Value   string `json:"value"`
+    Display string `json:"display,omitempty"`
+    Type    string `json:"type,omitempty"`
+    Primary bool   `json:"primary,omitempty"`
+}
+
+type scimMeta struct {
+    ResourceType string    `json:"resourceType"`
+    Created      time.Time `json:"created"`
+    LastModified time.Time `json:"lastModified"`
+    Location     string    `json:"location"`
+}
+
+type scimUser struct {
+    Schemas     []string         `json:"schemas"`
+    ID          string           `json:"id,omitempty"`
+    ExternalID  string           `json:"externalId,omitempty"`
+    UserName    string           `json:"userName"`
+    Name        *scimName        `json:"name,omitempty"`
+    DisplayName string           `json:"displayName,omitempty"`
+    Emails      []scimMultiValue `json:"emails,omitempty"`
+    Active      *bool            `json:"active,omitempty"`
+    Roles       []scimMultiValue `json:"roles,omitempty"`
+    Groups      []scimMultiValue `json:"groups,omitempty"`
+    Meta        *scimMeta        `json:"meta,omitempty"`
+}
+
+type scimGroup struct {
+    Schemas     []string         `json:"schemas"`
+    ID          string           `json:"id,omitempty"`
+    ExternalID  string           `json:"externalId,omitempty"`
+    DisplayName string           `json:"displayName"`
+    Members     []scimMultiValue `json:"members,omitempty"`
+    Meta        *scimMeta        `json:"meta,omitempty"`
+}
+
+type scimPatchRequest struct {
+    Schemas    []string `json:"schemas"`
+    Operations []struct {
+        Op    string      `json:"op"`
+        Path  string      `json:"path"`
+        Value interface{} `json:"value"`
+    } `json:"Operations"`
+}
+
+// ---------- Users ----------
+
+// ScimListUsers handles GET /scim/v2/Users
+func ScimListUsers(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    filter, err := parseScimFilter(c.Query("filter"), scimUserAttributes)
+    if err != nil {
+        return scimError(c, fiber.StatusBadRequest, "invalidFilter", err.Error())
+    }
+
+    startIndex, count := scimPagination(c)
+    total, err := config.UserCollectionRef.CountDocuments(ctx, filter)
+    if err != nil {
+        return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+    }
+
+    // count=0 asks for the total only; a limit of 0 would mean no limit
+    var users []models.User
+    if count > 0 {
+        opts := options.Find().SetSort(bson.M{"_id": 1}).SetSkip(int64(startIndex - 1)).SetLimit(int64(count))
+        cursor, err := config.UserCollectionRef.Find(ctx, filter, opts)
+        if err != nil {
+            return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+        }
+        if err := cursor.All(ctx, &users); err != nil {
+            return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+        }
+    }
+
+    teams, err := teamIDsByName(ctx)
+    if err != nil {
+        return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+    }
+
+    resources := make([]scimUser, 0, len(users))
+    for _, user := range users {
+        resources = append(resources, toScimUser(c, user, teams))
+    }
+
+    return scimList(c, total, startIndex, resources)
+}
+
+// ScimGetUser handles GET /scim/v2/Users/:id
+func ScimGetUser(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    user, err := findScimUser(ctx, c.Params("id"))
+    if err != nil {
+        return scimLookupError(c, err, "User")
+    }
+    return scimUserResponse(c, ctx, fiber.StatusOK, user)
+}
+
+// ScimCreateUser handles POST /scim/v2/Users
+func ScimCreateUser(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    var input scimUser
+    if err := c.BodyParser(&input); err != nil {
+        return scimError(c, fiber.StatusBadRequest, "invalidSyntax", "Invalid request body")
+    }
+
+    email := scimEmail(input)
+    if email == "" {
+        return scimError(c, fiber.StatusBadRequest, "invalidValue", "userName is required")
+    }
+
+    count, err := config.UserCollectionRef.CountDocuments(ctx, bson.M{"email": email},
+        options.Count().SetCollation(emailCollation))
+    if err != nil {
+        return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+    }
+    if count > 0 {
+        return scimError(c, fiber.StatusConflict, "uniqueness", "User with this userName already exists")
+    }
+
+    user, err := newInvitedUser(scimDisplayName(input, email), email, scimRole(input), "")
+    if err != nil {
+        return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+    }
+    user.ExternalID = input.ExternalID
+    user.Disabled = input.Active != nil && !*input.Active
+
+    if _, err := config.UserCollectionRef.InsertOne(ctx, user); err != nil {
+        return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+    }
+
+    log.Printf("SCIM provisioned user: %s", user.Email)
+    if !user.Disabled {
+        sendInviteEmail(user)
+    }
+
+    return scimUserResponse(c, ctx, fiber.StatusCreated, user)
+}
+
+// ScimReplaceUser handles PUT /scim/v2/Users/:id
+func ScimReplaceUser(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    user, err := findScimUser(ctx, c.Params("id"))
+    if err != nil {
+        return scimLookupError(c, err, "User")
+    }
+
+    var input scimUser
+    if err := c.BodyParser(&input); err != nil {
+        return scimError(c, fiber.StatusBadRequest, "invalidSyntax", "Invalid request body")
+    }
+
+    email := scimEmail(input)
+    if email == "" {
+        return scimError(c, fiber.StatusBadRequest, "invalidValue", "userName is required")
+    }
+
+    set := bson.M{
+        "email":      email,
+        "nama":       scimDisplayName(input, email),
+        "externalId": input.ExternalID,
+        "disabled":   input.Active != nil && !*input.Active,
+    }
+    if len(input.Roles) > 0 {
+        set["role"] = scimRole(input)
+    }
+
+    if err := updateScimUser(ctx, user.ID, set); err != nil {
+        return scimWriteError(c, err)
+    }
+
+    user, _ = findScimUser(ctx, user.ID.Hex())
+    return scimUserResponse(c, ctx, fiber.StatusOK, user)
+}
+
+// ScimPatchUser handles PATCH /scim/v2/Users/:id
+func ScimPatchUser(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    user, err := findScimUser(ctx, c.Params("id"))
+    if err != nil {
+        return scimLookupError(c, err, "User")
+    }
+
+    var input scimPatchRequest
+    if err := c.BodyParser(&input); err != nil || len(input.Operations) == 0 {
+        return scimError(c, fiber.StatusBadRequest, "invalidSyntax", "Invalid PatchOp request")
+    }
+
+    set := bson.M{}
+    for _, op := range input.Operations {
+        operation := strings.ToLower(op.Op)
+        if operation != "add" && operation != "replace" && operation != "remove" {
+            return scimError(c, fiber.StatusBadRequest, "invalidSyntax", fmt.Sprintf("Unsupported op '%s'", op.Op))
+        }
+
+        // Path-less operations carry a map of attribute -> value
+        values := map[string]interface{}{}
+        if op.Path == "" {
+            m, ok := op.Value.(map[string]interface{})
+            if !ok {
+                return scimError(c, fiber.StatusBadRequest, "invalidValue", "Value must be an object when path is omitted")
+            }
+            values = m
+        } else {
+            values[op.Path] = op.Value
+        }
+
+        for path, value := range values {
+            if err := applyScimUserPatch(set, operation, path, value); err != nil {
+                return scimError(c, fiber.StatusBadRequest, "invalidPath", err.Error())
+            }
+        }
+    }
+
+    if len(set) > 0 {
+        if err := updateScimUser(ctx, user.ID, set); err != nil {
+            return scimWriteError(c, err)
+        }
+        if disabled, ok := set["disabled"].(bool); ok && disabled != user.Disabled {
+            log.Printf("SCIM set disabled=%v for user: %s", disabled, user.Email)
+        }
+    }
+
+    user, _ = findScimUser(ctx, user.ID.Hex())
+    return scimUserResponse(c, ctx, fiber.StatusOK, user)
+}
+
+// ScimDeleteUser handles DELETE /scim/v2/Users/:id
+func ScimDeleteUser(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    user, err := findScimUser(ctx, c.Params("id"))
+    if err != nil {
+        return scimLookupError(c, err, "User")
+    }
+
+    if _, err := config.UserCollectionRef.DeleteOne(ctx, bson.M{"_id": user.ID}); err != nil {
+        return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+    }
+
+    log.Printf("SCIM deprovisioned user: %s", user.Email)
+    return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ---------- Groups ----------
+
+// ScimListGroups handles GET /scim/v2/Groups
+func ScimListGroups(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    filter, err := parseScimFilter(c.Query("filter"), scimGroupAttributes)
+    if err != nil {
+        return scimError(c, fiber.StatusBadRequest, "invalidFilter", err.Error())
+    }
+
+    startIndex, count := scimPagination(c)
+    total, err := config.TeamCollectionRef.CountDocuments(ctx, filter)
+    if err != nil {
+        return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+    }
+
+    // count=0 asks for the total only; a limit of 0 would mean no limit
+    var teams []models.Team
+    if count > 0 {
+        opts := options.Find().SetSort(bson.M{"_id": 1}).SetSkip(int64(startIndex - 1)).SetLimit(int64(count))
+        cursor, err := config.TeamCollectionRef.Find(ctx, filter, opts)
+        if err != nil {
+            return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+        }
+        if err := cursor.All(ctx, &teams); err != nil {
+            return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+        }
+    }
+
+    excludeMembers := strings.Contains(c.Query("excludedAttributes"), "members")
+    resources := make([]scimGroup, 0, len(teams))
+    for _, team := range teams {
+        group, err := toScimGroup(c, ctx, team, !excludeMembers)
+        if err != nil {
+            return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+        }
+        resources = append(resources, group)
+    }
+
+    return scimList(c, total, startIndex, resources)
+}
+
+// ScimGetGroup handles GET /scim/v2/Groups/:id
+func ScimGetGroup(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    team, err := findScimTeam(ctx, c.Params("id"))
+    if err != nil {
+        return scimLookupError(c, err, "Group")
+    }
+    return scimGroupResponse(c, ctx, fiber.StatusOK, team)
+}
+
+// ScimCreateGroup handles POST /scim/v2/Groups
+func ScimCreateGroup(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    var input scimGroup
+    if err := c.BodyParser(&input); err != nil {
+        return scimError(c, fiber.StatusBadRequest, "invalidSyntax", "Invalid request body")
+    }
+    input.DisplayName = strings.TrimSpace(input.DisplayName)
+    if input.DisplayName == "" {
+        return scimError(c, fiber.StatusBadRequest, "invalidValue", "displayName is required")
+    }
+
+    count, err := config.TeamCollectionRef.CountDocuments(ctx, bson.M{"name": input.DisplayName})
+    if err != nil {
+        return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+    }
+    if count > 0 {
+        return scimError(c, fiber.StatusConflict, "uniqueness", "Group with this displayName already exists")
+    }
+
+    now := time.Now()
+    team := models.Team{
+        ID:         primitive.NewObjectID(),
+        Name:       input.DisplayName,
+        ExternalID: input.ExternalID,
+        CreatedAt:  now,
+        UpdatedAt:  now,
+    }
+    if _, err := config.TeamCollectionRef.InsertOne(ctx, team); err != nil {
+        return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+    }
+
+    if err := setTeamMembers(ctx, team.Name, scimMemberIDs(input.Members), true); err != nil {
+        return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+    }
+
+    return scimGroupResponse(c, ctx, fiber.StatusCreated, team)
+}
+
+// ScimReplaceGroup handles PUT /scim/v2/Groups/:id
+func ScimReplaceGroup(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    team, err := findScimTeam(ctx, c.Params("id"))
+    if err != nil {
+        return scimLookupError(c, err, "Group")
+    }
+
+    var input scimGroup
+    if err := c.BodyParser(&input); err != nil {
+        return scimError(c, fiber.StatusBadRequest, "invalidSyntax", "Invalid request body")
+    }
+    input.DisplayName = strings.TrimSpace(input.DisplayName)
+    if input.DisplayName == "" {
+        return scimError(c, fiber.StatusBadRequest, "invalidValue", "displayName is required")
+    }
+
+    if input.DisplayName != team.Name {
+        if err := renameTeam(ctx, &team, input.DisplayName); err != nil {
+            return scimWriteError(c, err)
+        }
+    }
+    if input.ExternalID != team.ExternalID {
+        team.ExternalID = input.ExternalID
+        _, err = config.TeamCollectionRef.UpdateOne(ctx, bson.M{"_id": team.ID},
+            bson.M{"$set": bson.M{"externalId": team.ExternalID, "updatedAt": time.Now()}})
+        if err != nil {
+            return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+        }
+    }
+
+    if err := setTeamMembers(ctx, team.Name, scimMemberIDs(input.Members), true); err != nil {
+        return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+    }
+
+    return scimGroupResponse(c, ctx, fiber.StatusOK, team)
+}
+
+// ScimPatchGroup handles PATCH /scim/v2/Groups/:id
+func ScimPatchGroup(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    team, err := findScimTeam(ctx, c.Params("id"))
+    if err != nil {
+        return scimLookupError(c, err, "Group")
+    }
+
+    var input scimPatchRequest
+    if err := c.BodyParser(&input); err != nil || len(input.Operations) == 0 {
+        return scimError(c, fiber.StatusBadRequest, "invalidSyntax", "Invalid PatchOp request")
+    }
+
+    for _, op := range input.Operations {
+        operation := strings.ToLower(op.Op)
+        path := strings.ToLower(op.Path)
+
+        switch {
+        case path == "" && (operation == "add" || operation == "replace"):
+            m, ok := op.Value.(map[string]interface{})
+            if !ok {
+                return scimError(c, fiber.StatusBadRequest, "invalidValue", "Value must be an object when path is omitted")
+            }
+            if name, ok := m["displayName"].(string); ok && strings.TrimSpace(name) != "" && name != team.Name {
+                if err := renameTeam(ctx, &team, strings.TrimSpace(name)); err != nil {
+                    return scimWriteError(c, err)
+                }
+            }
+            if members, ok := m["members"]; ok {
+                if err := setTeamMembers(ctx, team.Name, scimValueIDs(members), operation == "replace"); err != nil {
+                    return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+                }
+            }
+
+        case path == "displayname" && operation != "remove":
+            name, _ := op.Value.(string)
+            name = strings.TrimSpace(name)
+            if name == "" {
+                return scimError(c, fiber.StatusBadRequest, "invalidValue", "displayName must be a non-empty string")
+            }
+            if name != team.Name {
+                if err := renameTeam(ctx, &team, name); err != nil {
+                    return scimWriteError(c, err)
+                }
+            }
+
+        case path == "members" && operation != "remove":
+            if err := setTeamMembers(ctx, team.Name, scimValueIDs(op.Value), operation == "replace"); err != nil {
+                return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+            }
+
+        case path == "members" && operation == "remove":
+            // Without a value every member is removed
+            ids := scimValueIDs(op.Value)
+            filter := bson.M{"team": team.Name}
+            if op.Value != nil {
+                filter["_id"] = bson.M{"$in": ids}
+            }
+            if _, err := config.UserCollectionRef.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"team": "", "updatedAt": time.Now()}}); err != nil {
+                return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+            }
+
+        case strings.HasPrefix(path, "members[") && operation == "remove":
+            // Azure AD style: members[value eq "<id>"]
+            id := scimMemberPathID.FindStringSubmatch(op.Path)
+            if id == nil {
+                return scimError(c, fiber.StatusBadRequest, "invalidFilter", "Unsupported members filter")
+            }
+            userID, err := primitive.ObjectIDFromHex(id[1])
+            if err != nil {
+                return scimError(c, fiber.StatusBadRequest, "invalidValue", "Invalid member id")
+            }
+            _, err = config.UserCollectionRef.UpdateOne(ctx, bson.M{"_id": userID, "team": team.Name},
+                bson.M{"$set": bson.M{"team": "", "updatedAt": time.Now()}})
+            if err != nil {
+                return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+            }
+
+        default:
+            return scimError(c, fiber.StatusBadRequest, "invalidPath", fmt.Sprintf("Unsupported %s on path '%s'", op.Op, op.Path))
+        }
+    }
+
+    return scimGroupResponse(c, ctx, fiber.StatusOK, team)
+}
+
+// ScimDeleteGroup handles DELETE /scim/v2/Groups/:id
+func ScimDeleteGroup(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    team, err := findScimTeam(ctx, c.Params("id"))
+    if err != nil {
+        return scimLookupError(c, err, "Group")
+    }
+
+    if _, err := config.TeamCollectionRef.DeleteOne(ctx, bson.M{"_id": team.ID}); err != nil {
+        return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+    }
+    _, err = config.UserCollectionRef.UpdateMany(ctx, bson.M{"team": team.Name},
+        bson.M{"$set": bson.M{"team": "", "updatedAt": time.Now()}})
+    if err != nil {
+        return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+    }
+
+    return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ---------- Helpers ----------
+
+var (
+    scimUserAttributes = map[string]string{
+        "id":             "_id",
+        "username":       "email",
+        "emails":         "email",
+        "emails.value":   "email",
+        "externalid":     "externalId",
+        "displayname":    "nama",
+        "name.formatted": "nama",
+        "active":         "disabled",
+    }
+    scimGroupAttributes = map[string]string{
+        "id":          "_id",
+        "displayname": "name",
+        "externalid":  "externalId",
+    }
+
+    scimComparison   = regexp.MustCompile(`(?i)^([a-z.]+)\s+(eq|ne|co|sw|ew)\s+(.+)$`)
+    scimPresence     = regexp.MustCompile(`(?i)^([a-z.]+)\s+pr$`)
+    scimAnd          = regexp.MustCompile(`(?i)^\s+and\s+`)
+    scimMemberPathID = regexp.MustCompile(`(?i)^members\[value eq "([0-9a-f]{24})"\]$`)
+)
+
+// parseScimFilter translates a subset of the SCIM filter grammar into a
+// Mongo filter: "attr op value" comparisons and "attr pr", joined by "and"
+func parseScimFilter(filter string, attributes map[string]string) (bson.M, error) {
+    filter = strings.TrimSpace(filter)
+    if filter == "" {
+        return bson.M{}, nil
+    }
+
+    var clauses []bson.M
+    for _, expr := range splitScimAnd(filter) {
+        expr = strings.TrimSpace(expr)
+
+        if m := scimPresence.FindStringSubmatch(expr); m != nil {
+            field, ok := attributes[strings.ToLower(m[1])]
+            if !ok {
+                return nil, fmt.Errorf("unsupported filter attribute '%s'", m[1])
+            }
+            clauses = append(clauses, bson.M{field: bson.M{"$exists": true, "$nin": []interface{}{"", nil}}})
+            continue
+        }
+
+        m := scimComparison.FindStringSubmatch(expr)
+        if m == nil {
+            return nil, fmt.Errorf("unsupported filter expression '%s'", expr)
+        }
+        field, ok := attributes[strings.ToLower(m[1])]
+        if !ok {
+            return nil, fmt.Errorf("unsupported filter attribute '%s'", m[1])
+        }
+        op := strings.ToLower(m[2])
+        raw := strings.TrimSpace(m[3])
+
+        // active is stored inverted as "disabled"
+        if field == "disabled" {
+            active, err := strconv.ParseBool(raw)
+            if err != nil || (op != "eq" && op != "ne") {
+                return nil, fmt.Errorf("active only supports eq/ne with a boolean")
+            }
+            if op == "ne" {
+                active = !active
+            }
+            if active {
+                clauses = append(clauses, bson.M{"disabled": bson.M{"$ne": true}})
+            } else {
+                clauses = append(clauses, bson.M{"disabled": true})
+            }
+            continue
+        }
+
+        value, err := strconv.Unquote(raw)
+        if err != nil {
+            return nil, fmt.Errorf("filter value must be a quoted string")
+        }
+
+        if field == "_id" {
+            id, err := primitive.ObjectIDFromHex(value)
+            if err != nil || (op != "eq" && op != "ne") {
+                return nil, fmt.Errorf("id only supports eq/ne with a valid id")
+            }
+            if op == "eq" {
+                clauses = append(clauses, bson.M{"_id": id})
+            } else {
+                clauses = append(clauses, bson.M{"_id": bson.M{"$ne": id}})
+            }
+            continue
+        }
+
+        // String comparisons are case-insensitive (caseExact=false)
+        quoted := regexp.QuoteMeta(value)
+        var pattern string
+        switch op {
+        case "eq", "ne":
+            pattern = "^" + quoted + "$"
+        case "co":
+            pattern = quoted
+        case "sw":
+            pattern = "^" + quoted
+        case "ew":
+            pattern = quoted + "$"
+        }
+        regex := primitive.Regex{Pattern: pattern, Options: "i"}
+        if op == "ne" {
+            clauses = append(clauses, bson.M{field: bson.M{"$not": regex}})
+        } else {
+            clauses = append(clauses, bson.M{field: regex})
+        }
+    }
+
+    if len(clauses) == 1 {
+        return clauses[0], nil
+    }
+    return bson.M{"$and": clauses}, nil
+}
+
+// splitScimAnd splits a filter on the "and" keyword, leaving quoted values
+// such as "R and D" intact
+func splitScimAnd(filter string) []string {
+    var exprs []string
+    start, inQuote := 0, false
+    for i := 0; i < len(filter); i++ {
+        switch {
+        case filter[i] == '\\' && inQuote:
+            i++
+        case filter[i] == '"':
+            inQuote = !inQuote
+        case !inQuote:
+            if m := scimAnd.FindString(filter[i:]); m != "" {
+                exprs = append(exprs, filter[start:i])
+                start = i + len(m)
+                i = start - 1
+            }
+        }
+    }
+    return append(exprs, filter[start:])
+}
+
+// applyScimUserPatch records a single PATCH operation into the $set document
+func applyScimUserPatch(set bson.M, op, path string, value interface{}) error {
+    switch strings.ToLower(path) {
+    case "active":
+        if op == "remove" {
+            return fmt.Errorf("active cannot be removed")
+        }
+        active, ok := scimBool(value)
+        if !ok {
+            return fmt.Errorf("active must be a boolean")
+        }
+        set["disabled"] = !active
+    case "username":
+        email, _ := value.(string)
+        if op == "remove" || email == "" {
+            return fmt.Errorf("userName cannot be removed")
+        }
+        set["email"] = strings.ToLower(strings.TrimSpace(email))
+    case "displayname", "name.formatted":
+        name, _ := value.(string)
+        if op == "remove" || name == "" {
+            return fmt.Errorf("%s cannot be removed", path)
+        }
+        set["nama"] = name
+    case "name":
+        m, _ := value.(map[string]interface{})
+        if formatted, ok := m["formatted"].(string); ok && formatted != "" {
+            set["nama"] = formatted
+        }
+    case "externalid":
+        if op == "remove" {
+            set["externalId"] = ""
+        } else {
+            id, _ := value.(string)
+            set["externalId"] = id
+        }
+    case "roles":
+        if op == "remove" {
+            set["role"] = models.RoleUser
+        } else {
+            set["role"] = scimRole(scimUser{Roles: scimValueRoles(value)})
+        }
+    case "emails", `emails[type eq "work"].value`:
+        if op == "remove" {
+            return fmt.Errorf("emails cannot be removed")
+        }
+        if email := scimValueEmail(value); email != "" {
+            set["email"] = email
+        }
+    default:
+        // Attributes we do not store (phone numbers, addresses, ...) are ignored
+        log.Printf("SCIM patch ignored unsupported user path: %s", path)
+    }
+    return nil
+}
+
+func updateScimUser(ctx context.Context, id primitive.ObjectID, set bson.M) error {
+    if email, ok := set["email"].(string); ok {
+        count, err := config.UserCollectionRef.CountDocuments(ctx, bson.M{"email": email, "_id": bson.M{"$ne": id}},
+            options.Count().SetCollation(emailCollation))
+        if err != nil {
+            return err
+        }
+        if count > 0 {
+            return errScimConflict
+        }
+    }
+    set["updatedAt"] = time.Now()
+    _, err := config.UserCollectionRef.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+    return err
+}
+
+// renameTeam renames a team and moves its members along with it
+func renameTeam(ctx context.Context, team *models.Team, name string) error {
+    count, err := config.TeamCollectionRef.CountDocuments(ctx, bson.M{"name": name, "_id": bson.M{"$ne": team.ID}})
+    if err != nil {
+        return err
+    }
+    if count > 0 {
+        return errScimConflict
+    }
+
+    now := time.Now()
+    if _, err := config.TeamCollectionRef.UpdateOne(ctx, bson.M{"_id": team.ID},
+        bson.M{"$set": bson.M{"name": name, "updatedAt": now}}); err != nil {
+        return err
+    }
+    if _, err := config.UserCollectionRef.UpdateMany(ctx, bson.M{"team": team.Name},
+        bson.M{"$set": bson.M{"team": name, "updatedAt": now}}); err != nil {
+        return err
+    }
+    team.Name = name
+    return nil
+}
+
+// setTeamMembers assigns users to the team. With replace, users not listed
+// are removed from the team first.
+func setTeamMembers(ctx context.Context, teamName string, ids []primitive.ObjectID, replace bool) error {
+    now := time.Now()
+    if replace {
+        _, err := config.UserCollectionRef.UpdateMany(ctx,
+            bson.M{"team": teamName, "_id": bson.M{"$nin": ids}},
+            bson.M{"$set": bson.M{"team": "", "updatedAt": now}})
+        if err != nil {
+            return err
+        }
+    }
+    if len(ids) == 0 {
+        return nil
+    }
+    _, err := config.UserCollectionRef.UpdateMany(ctx,
+        bson.M{"_id": bson.M{"$in": ids}},
+        bson.M{"$set": bson.M{"team": teamName, "updatedAt": now}})
+    return err
+}
+
+// ensureTeam returns the team with the given name, creating it if needed
+func ensureTeam(ctx context.Context, name string) (models.Team, error) {
+    now := time.Now()
+    opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+    var team models.Team
+    err := config.TeamCollectionRef.FindOneAndUpdate(ctx,
+        bson.M{"name": name},
+        bson.M{"$setOnInsert": bson.M{"name": name, "createdAt": now, "updatedAt": now}},
+        opts,
+    ).Decode(&team)
+    return team, err
+}
+
+// teamIDsByName maps team names to their ids for rendering user groups
+func teamIDsByName(ctx context.Context) (map[string]primitive.ObjectID, error) {
+    cursor, err := config.TeamCollectionRef.Find(ctx, bson.M{})
+    if err != nil {
+        return nil, err
+    }
+    var teams []models.Team
+    if err := cursor.All(ctx, &teams); err != nil {
+        return nil, err
+    }
+    ids := make(map[string]primitive.ObjectID, len(teams))
+    for _, team := range teams {
+        ids[team.Name] = team.ID
+    }
+    return ids, nil
+}
+
+func findScimUser(ctx context.Context, idHex string) (models.User, error) {
+    var user models.User
+    id, err := primitive.ObjectIDFromHex(idHex)
+    if err != nil {
+        return user, mongo.ErrNoDocuments
+    }
+    err = config.UserCollectionRef.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
+    return user, err
+}
+
+func findScimTeam(ctx context.Context, idHex string) (models.Team, error) {
+    var team models.Team
+    id, err := primitive.ObjectIDFromHex(idHex)
+    if err != nil {
+        return team, mongo.ErrNoDocuments
+    }
+    err = config.TeamCollectionRef.FindOne(ctx, bson.M{"_id": id}).Decode(&team)
+    return team, err
+}
+
+func toScimUser(c *fiber.Ctx, user models.User, teams map[string]primitive.ObjectID) scimUser {
+    active := !user.Disabled
+    resource := scimUser{
+        Schemas:     []string{scimUserSchema},
+        ID:          user.ID.Hex(),
+        ExternalID:  user.ExternalID,
+        UserName:    user.Email,
+        Name:        &scimName{Formatted: user.Nama},
+        DisplayName: user.Nama,
+        Emails:      []scimMultiValue{{Value: user.Email, Type: "work", Primary: true}},
+        Active:      &active,
+        Meta: &scimMeta{
+            ResourceType: "User",
+            Created:      user.CreatedAt,
+            LastModified: user.UpdatedAt,
+            Location:     c.BaseURL() + "/scim/v2/Users/" + user.ID.Hex(),
+        },
+    }
+    if user.Role != "" {
+        resource.Roles = []scimMultiValue{{Value: user.Role, Primary: true}}
+    }
+    if id, ok := teams[user.Team]; ok && user.Team != "" {
+        resource.Groups = []scimMultiValue{{Value: id.Hex(), Display: user.Team}}
+    }
+    return resource
+}
+
+func toScimGroup(c *fiber.Ctx, ctx context.Context, team models.Team, withMembers bool) (scimGroup, error) {
+    group := scimGroup{
+        Schemas:     []string{scimGroupSchema},
+        ID:          team.ID.Hex(),
+        ExternalID:  team.ExternalID,
+        DisplayName: team.Name,
+        Members:     []scimMultiValue{},
+        Meta: &scimMeta{
+            ResourceType: "Group",
+            Created:      team.CreatedAt,
+            LastModified: team.UpdatedAt,
+            Location:     c.BaseURL() + "/scim/v2/Groups/" + team.ID.Hex(),
+        },
+    }
+    if !withMembers {
+        return group, nil
+    }
+
+    cursor, err := config.UserCollectionRef.Find(ctx, bson.M{"team": team.Name})
+    if err != nil {
+        return group, err
+    }
+    var users []models.User
+    if err := cursor.All(ctx, &users); err != nil {
+        return group, err
+    }
+    for _, user := range users {
+        group.Members = append(group.Members, scimMultiValue{Value: user.ID.Hex(), Display: user.Nama})
+    }
+    return group, nil
+}
+
+func scimUserResponse(c *fiber.Ctx, ctx context.Context, status int, user models.User) error {
+    teams, err := teamIDsByName(ctx)
+    if err != nil {
+        return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+    }
+    resource := toScimUser(c, user, teams)
+    c.Set(fiber.HeaderLocation, resource.Meta.Location)
+    return c.Status(status).JSON(resource, scimContentType)
+}
+
+func scimGroupResponse(c *fiber.Ctx, ctx context.Context, status int, team models.Team) error {
+    var current models.Team
+    if err := config.TeamCollectionRef.FindOne(ctx, bson.M{"_id": team.ID}).Decode(&current); err == nil {
+        team = current
+    }
+    group, err := toScimGroup(c, ctx, team, true)
+    if err != nil {
+        return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+    }
+    c.Set(fiber.HeaderLocation, group.Meta.Location)
+    return c.Status(status).JSON(group, scimContentType)
+}
+
+func scimList(c *fiber.Ctx, total int64, startIndex int, resources interface{}) error {
+    return c.JSON(fiber.Map{
+        "schemas":      []string{scimListSchema},
+        "totalResults": total,
+        "startIndex":   startIndex,
+        "itemsPerPage": scimLen(resources),
+        "Resources":    resources,
+    }, scimContentType)
+}
+
+func scimLen(resources interface{}) int {
+    switch r := resources.(type) {
+    case []scimUser:
+        return len(r)
+    case []scimGroup:
+        return len(r)
+    }
+    return 0
+}
+
+func scimPagination(c *fiber.Ctx) (int, int) {
+    startIndex := c.QueryInt("startIndex", 1)
+    if startIndex < 1 {
+        startIndex = 1
+    }
+    count := c.QueryInt("count", scimDefaultCount)
+    if count < 0 {
+        count = 0
+    }
+    if count > scimMaxCount {
+        count = scimMaxCount
+    }
+    return startIndex, count
+}
+
+var errScimConflict = fmt.Errorf("resource with this unique attribute already exists")
+
+func scimError(c *fiber.Ctx, status int, scimType, detail string) error {
+    body := fiber.Map{
+        "schemas": []string{scimErrorSchema},
+        "status":  strconv.Itoa(status),
+        "detail":  detail,
+    }
+    if scimType != "" {
+        body["scimType"] = scimType
+    }
+    return c.Status(status).JSON(body, scimContentType)
+}
+
+func scimLookupError(c *fiber.Ctx, err error, resource string) error {
+    if err == mongo.ErrNoDocuments {
+        return scimError(c, fiber.StatusNotFound, "", resource+" not found")
+    }
+    return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+}
+
+func scimWriteError(c *fiber.Ctx, err error) error {
+    if err == errScimConflict {
+        return scimError(c, fiber.StatusConflict, "uniqueness", err.Error())
+    }
+    return scimError(c, fiber.StatusInternalServerError, "", err.Error())
+}
+
+// scimEmail picks userName, falling back to the primary email
+func scimEmail(input scimUser) string {
+    email := input.UserName
+    if email == "" {
+        for _, e := range input.Emails {
+            if e.Primary || email == "" {
+                email = e.Value
+            }
+        }
+    }
+    return strings.ToLower(strings.TrimSpace(email))
+}
+
+func scimDisplayName(input scimUser, fallback string) string {
+    switch {
+    case input.DisplayName != "":
+        return input.DisplayName
+    case input.Name != nil && input.Name.Formatted != "":
+        return input.Name.Formatted
+    case input.Name != nil && (input.Name.GivenName != "" || input.Name.FamilyName != ""):
+        return strings.TrimSpace(input.Name.GivenName + " " + input.Name.FamilyName)
+    }
+    return fallback
+}
+
+func scimRole(input scimUser) string {
+    for _, role := range input.Roles {
+        if strings.EqualFold(role.Value, models.RoleAdmin) {
+            return models.RoleAdmin
+        }
+    }
+    return models.RoleUser
+}
+
+// scimValueRoles reads a roles PATCH value: a list of {"value"} objects or
+// plain strings, or a single one of them
+func scimValueRoles(value interface{}) []scimMultiValue {
+    items, ok := value.([]interface{})
+    if !ok {
+        items = []interface{}{value}
+    }
+    var roles []scimMultiValue
+    for _, item := range items {
+        switch v := item.(type) {
+        case string:
+            roles = append(roles, scimMultiValue{Value: v})
+        case map[string]interface{}:
+            if role, ok := v["value"].(string); ok {
+                roles = append(roles, scimMultiValue{Value: role})
+            }
+        }
+    }
+    return roles
+}
+
+// scimBool accepts JSON booleans as well as "True"/"False" strings sent by some IdPs
+func scimBool(value interface{}) (bool, bool) {
+    switch v := value.(type) {
+    case bool:
+        return v, true
+    case string:
+        b, err := strconv.ParseBool(v)
+        return b, err == nil
+    }
+    return false, false
+}
+
+func scimValueEmail(value interface{}) string {
+    switch v := value.(type) {
+    case string:
+        return strings.ToLower(strings.TrimSpace(v))
+    case []interface{}:
+        for _, item := range v {
+            if m, ok := item.(map[string]interface{}); ok {
+                if email, ok := m["value"].(string); ok && email != "" {
+                    return strings.ToLower(strings.TrimSpace(email))
+                }
+            }
+        }
+    }
+    return ""
+}
+
+func scimMemberIDs(members []scimMultiValue) []primitive.ObjectID {
+    ids := []primitive.ObjectID{}
+    for _, member := range members {
+        if id, err := primitive.ObjectIDFromHex(member.Value); err == nil {
+            ids = append(ids, id)
+        }
+    }
+    return ids
+}
+
+// scimValueIDs extracts member ids from a PATCH value ([{ "value": "<id>" }])
+func scimValueIDs(value interface{}) []primitive.ObjectID {
+    ids := []primitive.ObjectID{}
+    items, _ := value.([]interface{})
+    for _, item := range items {
+        m, ok := item.(map[string]interface{})
+        if !ok {
+            continue
+        }
+        if hex, ok := m["value"].(string); ok {
+            if id, err := primitive.ObjectIDFromHex(hex); err == nil {
+                ids = append(ids, id)
+            }
+        }
+    }
+    return ids
+}
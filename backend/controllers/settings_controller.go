@@ -0,0 +1,105 @@
+package controllers
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/utils"
+)
+
+const maxReminderOffsets = 5
+
+// GetNotificationSettings returns the current user's notification preferences
+func GetNotificationSettings(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    var user models.User
+    if err := config.UserCollectionRef.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User tidak ditemukan"})
+    }
+
+    return c.JSON(notificationSettingsResponse(user.NotificationPrefs()))
+}
+
+// UpdateNotificationSettings replaces the current user's notification
+// preferences. Reminder offsets are minutes before the meeting starts.
+func UpdateNotificationSettings(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    var prefs models.NotificationPreferences
+    if err := c.BodyParser(&prefs); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+    }
+    offsets, err := normalizeReminderOffsets(prefs.ReminderOffsets)
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+    }
+    prefs.ReminderOffsets = offsets
+
+    result, err := config.UserCollectionRef.UpdateOne(ctx, bson.M{"_id": userID},
+        bson.M{"$set": bson.M{"notifications": prefs, "updatedAt": time.Now()}})
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan pengaturan notifikasi"})
+    }
+    if result.MatchedCount == 0 {
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User tidak ditemukan"})
+    }
+
+    response := notificationSettingsResponse(prefs)
+    response["message"] = "Pengaturan notifikasi berhasil disimpan"
+    return c.JSON(response)
+}
+
+func notificationSettingsResponse(prefs models.NotificationPreferences) fiber.Map {
+    offsets := prefs.ReminderOffsets
+    if len(offsets) == 0 {
+        offsets = config.ReminderOffsets()
+    }
+    return fiber.Map{
+        "emailNotifications": prefs.EmailNotifications,
+        "pushNotifications":  prefs.PushNotifications,
+        "meetingReminders":   prefs.MeetingReminders,
+        "weeklyDigest":       prefs.WeeklyDigest,
+        "reminderOffsets":    offsets,
+        "defaultOffsets":     len(prefs.ReminderOffsets) == 0,
+    }
+}
+
+// normalizeReminderOffsets validates, de-duplicates and sorts offsets
+func normalizeReminderOffsets(offsets []int) ([]int, error) {
+    if len(offsets) > maxReminderOffsets {
+        return nil, fmt.Errorf("Maksimal %d waktu pengingat", maxReminderOffsets)
+    }
+    seen := map[int]bool{}
+    var result []int
+    for _, o := range offsets {
+        if o <= 0 || o > config.MaxReminderOffset {
+            return nil, fmt.Errorf("Waktu pengingat harus antara 1 dan %d menit", config.MaxReminderOffset)
+        }
+        if !seen[o] {
+            seen[o] = true
+            result = append(result, o)
+        }
+    }
+    sort.Ints(result)
+    return result, nil
+}
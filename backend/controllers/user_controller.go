@@ -3,16 +3,21 @@ package controllers
 import (
     "context"
     "fmt"
+    "net/mail"
     "os"
+    "strings"
     "time"
 
     "github.com/gofiber/fiber/v2"
     "go.mongodb.org/mongo-driver/bson"
     "go.mongodb.org/mongo-driver/bson/primitive"
     "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
 
     "pbommo/config"
     "pbommo/models"
+    "pbommo/notify"
+    "pbommo/utils"
 )
 
 // GetUsers returns all users
@@ -39,6 +44,9 @@ func GetUsers(c *fiber.Ctx) error {
             Nama:         user.Nama,
             Email:        user.Email,
             Role:         user.Role,
+            Team:         user.Team,
+            Timezone:     user.Timezone,
+            Language:     user.Language,
             Bio:          user.Bio,
             ProfileImage: user.ProfileImage,
             Status:       "Online", // Placeholder
@@ -77,6 +85,9 @@ func GetUserById(c *fiber.Ctx) error {
         Nama:         user.Nama,
         Email:        user.Email,
         Role:         user.Role,
+        Team:         user.Team,
+        Timezone:     user.Timezone,
+        Language:     user.Language,
         Bio:          user.Bio,
         ProfileImage: user.ProfileImage,
     }
@@ -84,7 +95,19 @@ func GetUserById(c *fiber.Ctx) error {
     return c.Status(200).JSON(userResponse)
 }
 
-// UpdateUser updates user information
+// editableUserFields are the profile fields UpdateUser accepts. Roles,
+// provisioning state and tokens have their own endpoints; teams are managed
+// through SCIM groups and the user import.
+var editableUserFields = map[string]bool{
+    "nama":     true,
+    "email":    true,
+    "bio":      true,
+    "timezone": true,
+    "language": true,
+}
+
+// UpdateUser updates user information. Users may only update themselves,
+// unless they are an admin.
 func UpdateUser(c *fiber.Ctx) error {
     idParam := c.Params("id")
     id, err := primitive.ObjectIDFromHex(idParam)
@@ -92,20 +115,57 @@ func UpdateUser(c *fiber.Ctx) error {
         return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
     }
 
-    var updateData map[string]interface{}
-    if err := c.BodyParser(&updateData); err != nil {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    if errResp := checkUserAccess(c, ctx, id); errResp != nil {
+        return errResp()
+    }
+
+    var body map[string]interface{}
+    if err := c.BodyParser(&body); err != nil {
         return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
     }
 
-    // Remove password from update data if present (should be updated separately)
-    delete(updateData, "password")
-    
+    // Only profile fields; password, role, team, disabled and tokens are ignored
+    updateData := bson.M{}
+    for field, value := range body {
+        if editableUserFields[field] {
+            updateData[field] = value
+        }
+    }
+
+    if tz, ok := updateData["timezone"]; ok {
+        name, _ := tz.(string)
+        if _, err := time.LoadLocation(name); err != nil {
+            return c.Status(400).JSON(fiber.Map{"error": "Invalid timezone"})
+        }
+    }
+    if lang, ok := updateData["language"]; ok {
+        if name, _ := lang.(string); name != "" && notify.Language(name) != name {
+            return c.Status(400).JSON(fiber.Map{"error": "Invalid language"})
+        }
+    }
+    if value, ok := updateData["email"]; ok {
+        email, _ := value.(string)
+        email = strings.ToLower(strings.TrimSpace(email))
+        if _, err := mail.ParseAddress(email); err != nil {
+            return c.Status(400).JSON(fiber.Map{"error": "Invalid email"})
+        }
+        count, err := config.UserCollectionRef.CountDocuments(ctx, bson.M{"email": email, "_id": bson.M{"$ne": id}},
+            options.Count().SetCollation(emailCollation))
+        if err != nil {
+            return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+        }
+        if count > 0 {
+            return c.Status(409).JSON(fiber.Map{"error": "Email already registered"})
+        }
+        updateData["email"] = email
+    }
+
     // Set updated timestamp
     updateData["updatedAt"] = time.Now()
 
-    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-    defer cancel()
-
     result, err := config.UserCollectionRef.UpdateOne(
         ctx,
         bson.M{"_id": id},
@@ -123,6 +183,25 @@ func UpdateUser(c *fiber.Ctx) error {
     return c.Status(200).JSON(fiber.Map{"message": "User updated successfully"})
 }
 
+// checkUserAccess allows the user in the token to change the user id: itself,
+// or anyone when it is an admin. On failure it returns a function writing
+// the error response.
+func checkUserAccess(c *fiber.Ctx, ctx context.Context, id primitive.ObjectID) func() error {
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return func() error { return c.Status(401).JSON(fiber.Map{"error": "Invalid token"}) }
+    }
+    if userID == id {
+        return nil
+    }
+    var caller models.User
+    err = config.UserCollectionRef.FindOne(ctx, bson.M{"_id": userID}).Decode(&caller)
+    if err != nil || caller.Role != models.RoleAdmin {
+        return func() error { return c.Status(403).JSON(fiber.Map{"error": "Forbidden: you can only update your own profile"}) }
+    }
+    return nil
+}
+
 // UploadProfileImage uploads and saves a profile image
 func UploadProfileImage(c *fiber.Ctx) error {
     // Get user ID from params
@@ -135,6 +214,10 @@ func UploadProfileImage(c *fiber.Ctx) error {
     // Check if the user exists
     ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
     defer cancel()
+
+    if errResp := checkUserAccess(c, ctx, id); errResp != nil {
+        return errResp()
+    }
     
     var user models.User
     err = config.UserCollectionRef.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
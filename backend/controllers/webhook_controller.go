@@ -0,0 +1,423 @@
+package controllers
+
+import (
+    "context"
+    "encoding/json"
+    "log"
+    "net"
+    "net/url"
+    "strings"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/realtime"
+    "pbommo/utils"
+)
+
+// webhookEvents are the events webhooks can subscribe to
+var webhookEvents = map[string]bool{
+    realtime.EventMeetingCreated: true,
+    realtime.EventMeetingUpdated: true,
+    realtime.EventMeetingDeleted: true,
+    realtime.EventRSVP:           true,
+}
+
+type webhookInput struct {
+    URL          *string   `json:"url"`
+    Description  *string   `json:"description"`
+    Events       *[]string `json:"events"`
+    Active       *bool     `json:"active"`
+    RotateSecret bool      `json:"rotateSecret"`
+}
+
+// webhookPayload is the JSON body POSTed to webhooks
+type webhookPayload struct {
+    ID        string      `json:"id"`
+    Type      string      `json:"type"`
+    CreatedAt time.Time   `json:"createdAt"`
+    Data      interface{} `json:"data"`
+}
+
+// GetWebhooks lists all webhooks
+func GetWebhooks(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    cursor, err := config.WebhookCollectionRef.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"createdAt": 1}))
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil webhook"})
+    }
+    webhooks := []models.Webhook{}
+    if err := cursor.All(ctx, &webhooks); err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memproses webhook"})
+    }
+    return c.JSON(fiber.Map{"webhooks": webhooks})
+}
+
+// CreateWebhook registers an endpoint. The signing secret is only returned
+// here and when it is rotated.
+func CreateWebhook(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    userID, err := utils.GetUserIDFromToken(c)
+    if err != nil {
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+    }
+
+    var input webhookInput
+    if err := c.BodyParser(&input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+    }
+    if input.URL == nil || !validWebhookURL(*input.URL) {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "URL webhook harus berupa URL http(s) yang valid"})
+    }
+
+    secret, err := utils.GenerateRandomToken(32)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat secret webhook"})
+    }
+    now := time.Now()
+    webhook := models.Webhook{
+        URL:       *input.URL,
+        Events:    []string{},
+        Secret:    secret,
+        Active:    true,
+        CreatedBy: userID,
+        CreatedAt: now,
+        UpdatedAt: now,
+    }
+    if input.Description != nil {
+        webhook.Description = *input.Description
+    }
+    if input.Events != nil {
+        if bad, ok := validWebhookEvents(*input.Events); !ok {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tipe event tidak dikenal: " + bad})
+        }
+        webhook.Events = *input.Events
+    }
+
+    result, err := config.WebhookCollectionRef.InsertOne(ctx, webhook)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat webhook"})
+    }
+    webhook.ID = result.InsertedID.(primitive.ObjectID)
+
+    return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+        "message": "Webhook berhasil dibuat",
+        "webhook": webhook,
+        "secret":  secret,
+    })
+}
+
+// GetWebhookById returns one webhook
+func GetWebhookById(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    webhook, errResp := loadWebhook(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+    return c.JSON(fiber.Map{"webhook": webhook})
+}
+
+// UpdateWebhook changes a webhook. Re-activating a disabled webhook resets
+// its failure count; rotateSecret issues a new signing secret.
+func UpdateWebhook(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    webhook, errResp := loadWebhook(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+
+    var input webhookInput
+    if err := c.BodyParser(&input); err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+    }
+
+    set := bson.M{"updatedAt": time.Now()}
+    unset := bson.M{}
+    if input.URL != nil {
+        if !validWebhookURL(*input.URL) {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "URL webhook harus berupa URL http(s) yang valid"})
+        }
+        set["url"] = *input.URL
+    }
+    if input.Description != nil {
+        set["description"] = *input.Description
+    }
+    if input.Events != nil {
+        if bad, ok := validWebhookEvents(*input.Events); !ok {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tipe event tidak dikenal: " + bad})
+        }
+        set["events"] = *input.Events
+    }
+    if input.Active != nil {
+        set["active"] = *input.Active
+        if *input.Active && !webhook.Active {
+            set["consecutiveFailures"] = 0
+            unset["disabledAt"] = ""
+            unset["disabledReason"] = ""
+        }
+    }
+    var secret string
+    if input.RotateSecret {
+        var err error
+        if secret, err = utils.GenerateRandomToken(32); err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat secret webhook"})
+        }
+        set["secret"] = secret
+    }
+
+    update := bson.M{"$set": set}
+    if len(unset) > 0 {
+        update["$unset"] = unset
+    }
+    err := config.WebhookCollectionRef.FindOneAndUpdate(ctx, bson.M{"_id": webhook.ID}, update,
+        options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&webhook)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengupdate webhook"})
+    }
+
+    response := fiber.Map{
+        "message": "Webhook berhasil diupdate",
+        "webhook": webhook,
+    }
+    if secret != "" {
+        response["secret"] = secret
+    }
+    return c.JSON(response)
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func DeleteWebhook(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    webhook, errResp := loadWebhook(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+
+    if _, err := config.WebhookCollectionRef.DeleteOne(ctx, bson.M{"_id": webhook.ID}); err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus webhook"})
+    }
+    if _, err := config.WebhookDeliveryCollectionRef.DeleteMany(ctx, bson.M{"webhookId": webhook.ID}); err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus log webhook"})
+    }
+
+    return c.JSON(fiber.Map{"message": "Webhook berhasil dihapus"})
+}
+
+// GetWebhookDeliveries lists the delivery log of a webhook, newest first.
+// ?status= filters by state; ?before=<id> continues a previous page.
+func GetWebhookDeliveries(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    webhook, errResp := loadWebhook(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+
+    filter := bson.M{"webhookId": webhook.ID}
+    if status := c.Query("status"); status != "" {
+        filter["status"] = status
+    }
+    if before := c.Query("before"); before != "" {
+        id, err := primitive.ObjectIDFromHex(before)
+        if err != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Parameter before tidak valid"})
+        }
+        filter["_id"] = bson.M{"$lt": id}
+    }
+    limit := c.QueryInt("limit", 20)
+    if limit <= 0 || limit > 100 {
+        limit = 100
+    }
+
+    cursor, err := config.WebhookDeliveryCollectionRef.Find(ctx, filter,
+        options.Find().SetSort(bson.M{"_id": -1}).SetLimit(int64(limit)))
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil log webhook"})
+    }
+    deliveries := []models.WebhookDelivery{}
+    if err := cursor.All(ctx, &deliveries); err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memproses log webhook"})
+    }
+
+    response := fiber.Map{"deliveries": deliveries}
+    if len(deliveries) == limit {
+        response["nextBefore"] = deliveries[len(deliveries)-1].ID
+    }
+    return c.JSON(response)
+}
+
+// RedeliverWebhook queues a past delivery again with the same payload
+func RedeliverWebhook(c *fiber.Ctx) error {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    webhook, errResp := loadWebhook(c, ctx)
+    if errResp != nil {
+        return errResp()
+    }
+    if !webhook.Active {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Webhook sedang nonaktif"})
+    }
+
+    deliveryID, err := primitive.ObjectIDFromHex(c.Params("deliveryId"))
+    if err != nil {
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID delivery tidak valid"})
+    }
+    var original models.WebhookDelivery
+    err = config.WebhookDeliveryCollectionRef.FindOne(ctx, bson.M{"_id": deliveryID, "webhookId": webhook.ID}).Decode(&original)
+    if err != nil {
+        if err == mongo.ErrNoDocuments {
+            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Delivery tidak ditemukan"})
+        }
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil delivery"})
+    }
+
+    delivery := newWebhookDelivery(webhook.ID, original.EventID, original.Event, original.Payload)
+    delivery.RedeliveryOf = &original.ID
+    result, err := config.WebhookDeliveryCollectionRef.InsertOne(ctx, delivery)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menjadwalkan ulang delivery"})
+    }
+    delivery.ID = result.InsertedID.(primitive.ObjectID)
+
+    return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+        "message":  "Delivery dijadwalkan ulang",
+        "delivery": delivery,
+    })
+}
+
+// loadWebhook finds the webhook in :id; on failure it returns a function
+// writing the error response
+func loadWebhook(c *fiber.Ctx, ctx context.Context) (models.Webhook, func() error) {
+    var webhook models.Webhook
+    webhookID, err := primitive.ObjectIDFromHex(c.Params("id"))
+    if err != nil {
+        return webhook, func() error {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID webhook tidak valid"})
+        }
+    }
+    err = config.WebhookCollectionRef.FindOne(ctx, bson.M{"_id": webhookID}).Decode(&webhook)
+    if err == mongo.ErrNoDocuments {
+        return webhook, func() error {
+            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Webhook tidak ditemukan"})
+        }
+    }
+    if err != nil {
+        return webhook, func() error {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil webhook"})
+        }
+    }
+    return webhook, nil
+}
+
+// validWebhookURL accepts http(s) URLs whose host is not obviously internal.
+// Names are checked again after resolution when delivering, see
+// webhookDialer.
+func validWebhookURL(raw string) bool {
+    u, err := url.Parse(raw)
+    if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+        return false
+    }
+    host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+    if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+        return false
+    }
+    if ip := net.ParseIP(host); ip != nil && !publicIP(ip) {
+        return false
+    }
+    return true
+}
+
+// validWebhookEvents returns the first unknown event, if any
+func validWebhookEvents(events []string) (string, bool) {
+    for _, e := range events {
+        if !webhookEvents[e] {
+            return e, false
+        }
+    }
+    return "", true
+}
+
+// enqueueWebhooks queues an event for every active webhook subscribed to
+// it; the webhook worker sends them
+func enqueueWebhooks(eventType string, data interface{}) {
+    if !webhookEvents[eventType] {
+        return
+    }
+    payload := webhookPayload{
+        ID:        primitive.NewObjectID().Hex(),
+        Type:      eventType,
+        CreatedAt: time.Now(),
+        Data:      data,
+    }
+    body, err := json.Marshal(payload)
+    if err != nil {
+        log.Printf("Failed to encode %s webhook payload: %v", eventType, err)
+        return
+    }
+
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        defer cancel()
+
+        cursor, err := config.WebhookCollectionRef.Find(ctx, bson.M{
+            "active": true,
+            "$or": []bson.M{
+                {"events": bson.M{"$size": 0}},
+                {"events": eventType},
+            },
+        })
+        if err != nil {
+            log.Printf("Failed to load webhooks: %v", err)
+            return
+        }
+        var webhooks []models.Webhook
+        if err := cursor.All(ctx, &webhooks); err != nil {
+            log.Printf("Failed to load webhooks: %v", err)
+            return
+        }
+        if len(webhooks) == 0 {
+            return
+        }
+
+        docs := make([]interface{}, 0, len(webhooks))
+        for _, w := range webhooks {
+            docs = append(docs, newWebhookDelivery(w.ID, payload.ID, eventType, string(body)))
+        }
+        if _, err := config.WebhookDeliveryCollectionRef.InsertMany(ctx, docs); err != nil {
+            log.Printf("Failed to queue %s webhooks: %v", eventType, err)
+        }
+    }()
+}
+
+func newWebhookDelivery(webhookID primitive.ObjectID, eventID, eventType, payload string) models.WebhookDelivery {
+    now := time.Now()
+    return models.WebhookDelivery{
+        WebhookID:     webhookID,
+        EventID:       eventID,
+        Event:         eventType,
+        Payload:       payload,
+        Status:        models.DeliveryPending,
+        Attempts:      []models.WebhookAttempt{},
+        NextAttemptAt: now,
+        CreatedAt:     now,
+        UpdatedAt:     now,
+    }
+}
@@ -0,0 +1,298 @@
+package controllers
+
+import (
+    "bytes"
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "net"
+    "net/http"
+    "strconv"
+    "sync"
+    "syscall"
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+
+    "pbommo/config"
+    "pbommo/models"
+)
+
+const (
+    webhookTick        = 10 * time.Second
+    webhookLease       = time.Minute
+    webhookTimeout     = 10 * time.Second
+    webhookRetryBase   = 30 * time.Second
+    webhookMaxAttempts = 8 // about an hour of retries
+    webhookConcurrency = 4
+    // webhookLogRetention is how long delivery logs are kept
+    webhookLogRetention = 30 * 24 * time.Hour
+    webhookBodyLimit    = 1024
+)
+
+// webhookClient only connects to public addresses and does not follow
+// redirects, so a webhook cannot be pointed at internal services
+var webhookClient = &http.Client{
+    Timeout: webhookTimeout,
+    Transport: &http.Transport{
+        Proxy:               nil,
+        DialContext:         webhookDialer.DialContext,
+        TLSHandshakeTimeout: webhookTimeout,
+        MaxIdleConnsPerHost: webhookConcurrency,
+    },
+    CheckRedirect: func(*http.Request, []*http.Request) error {
+        return errWebhookRedirect
+    },
+}
+
+var errWebhookRedirect = errors.New("redirects are not followed")
+
+// webhookDialer checks every address after DNS resolution, which also
+// covers names that resolve to internal addresses
+var webhookDialer = &net.Dialer{
+    Timeout: webhookTimeout,
+    Control: func(network, address string, _ syscall.RawConn) error {
+        host, _, err := net.SplitHostPort(address)
+        if err != nil {
+            return err
+        }
+        if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+            return fmt.Errorf("address %s is not allowed", host)
+        }
+        return nil
+    },
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, 100.64.0.0/10
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// publicIP reports whether ip is routable on the internet: not loopback,
+// private, link-local (including 169.254.169.254), multicast or unspecified
+func publicIP(ip net.IP) bool {
+    return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+        ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+        ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+        sharedAddressSpace.Contains(ip))
+}
+
+// StartWebhookWorker sends queued webhook deliveries in the background
+// until ctx is done. Deliveries are leased like reminder jobs, so several
+// instances may run the worker.
+func StartWebhookWorker(ctx context.Context) {
+    if !config.WebhookWorkerEnabled() {
+        log.Println("🪝 Webhook worker disabled")
+        return
+    }
+
+    if err := ensureWebhookIndexes(ctx); err != nil {
+        log.Printf("Failed to create webhook indexes: %v", err)
+    }
+
+    owner := schedulerInstanceID()
+    go func() {
+        ticker := time.NewTicker(webhookTick)
+        defer ticker.Stop()
+        for {
+            runWebhookTick(ctx, owner)
+            select {
+            case <-ctx.Done():
+                return
+            case <-ticker.C:
+            }
+        }
+    }()
+}
+
+func ensureWebhookIndexes(ctx context.Context) error {
+    _, err := config.WebhookDeliveryCollectionRef.Indexes().CreateMany(ctx, []mongo.IndexModel{
+        {Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}}},
+        {Keys: bson.D{{Key: "webhookId", Value: 1}, {Key: "_id", Value: -1}}},
+        {
+            Keys:    bson.D{{Key: "createdAt", Value: 1}},
+            Options: options.Index().SetExpireAfterSeconds(int32(webhookLogRetention.Seconds())),
+        },
+    })
+    return err
+}
+
+// runWebhookTick drains the due deliveries with a few parallel senders, so
+// one slow endpoint does not hold up the others
+func runWebhookTick(ctx context.Context, owner string) {
+    var wg sync.WaitGroup
+    for i := 0; i < webhookConcurrency; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for ctx.Err() == nil {
+                delivery, ok, err := leaseWebhookDelivery(ctx, owner)
+                if err != nil {
+                    log.Printf("Failed to lease webhook delivery: %v", err)
+                    return
+                }
+                if !ok {
+                    return
+                }
+                if err := deliverWebhook(ctx, owner, delivery); err != nil {
+                    log.Printf("Failed to record webhook delivery %s: %v", delivery.ID.Hex(), err)
+                }
+            }
+        }()
+    }
+    wg.Wait()
+}
+
+func leaseWebhookDelivery(ctx context.Context, owner string) (models.WebhookDelivery, bool, error) {
+    now := time.Now()
+    var delivery models.WebhookDelivery
+    err := config.WebhookDeliveryCollectionRef.FindOneAndUpdate(ctx,
+        bson.M{
+            "status":        models.DeliveryPending,
+            "nextAttemptAt": bson.M{"$lte": now},
+            "$or": []bson.M{
+                {"leaseUntil": bson.M{"$exists": false}},
+                {"leaseUntil": bson.M{"$lt": now}},
+            },
+        },
+        bson.M{"$set": bson.M{"leaseOwner": owner, "leaseUntil": now.Add(webhookLease), "updatedAt": now}},
+        options.FindOneAndUpdate().SetSort(bson.M{"nextAttemptAt": 1}).SetReturnDocument(options.After),
+    ).Decode(&delivery)
+    if err == mongo.ErrNoDocuments {
+        return delivery, false, nil
+    }
+    return delivery, err == nil, err
+}
+
+// deliverWebhook makes one attempt and records it. Failed attempts are
+// retried with exponential backoff; the webhook is disabled after too many
+// failures in a row.
+func deliverWebhook(ctx context.Context, owner string, delivery models.WebhookDelivery) error {
+    var webhook models.Webhook
+    err := config.WebhookCollectionRef.FindOne(ctx, bson.M{"_id": delivery.WebhookID}).Decode(&webhook)
+    if err != nil && err != mongo.ErrNoDocuments {
+        return err
+    }
+    if err == mongo.ErrNoDocuments || !webhook.Active {
+        return finishWebhookDelivery(ctx, owner, delivery, models.DeliveryFailed, models.WebhookAttempt{
+            At:    time.Now(),
+            Error: "webhook disabled or deleted",
+        })
+    }
+
+    attempt := postWebhook(ctx, webhook, delivery)
+    now := time.Now()
+    if attempt.Error == "" {
+        if _, err := config.WebhookCollectionRef.UpdateOne(ctx, bson.M{"_id": webhook.ID},
+            bson.M{"$set": bson.M{"consecutiveFailures": 0}}); err != nil {
+            return err
+        }
+        return finishWebhookDelivery(ctx, owner, delivery, models.DeliverySuccess, attempt)
+    }
+
+    if err := recordWebhookFailure(ctx, webhook, now); err != nil {
+        return err
+    }
+    status := models.DeliveryPending
+    if len(delivery.Attempts)+1 >= webhookMaxAttempts {
+        status = models.DeliveryFailed
+    }
+    return finishWebhookDelivery(ctx, owner, delivery, status, attempt)
+}
+
+// postWebhook POSTs the payload signed with the webhook secret. Receivers
+// verify X-Webhook-Signature, the hex HMAC-SHA256 of
+// "<X-Webhook-Timestamp>.<body>", and reject stale timestamps.
+func postWebhook(ctx context.Context, webhook models.Webhook, delivery models.WebhookDelivery) models.WebhookAttempt {
+    start := time.Now()
+    attempt := models.WebhookAttempt{At: start}
+
+    timestamp := strconv.FormatInt(start.Unix(), 10)
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewBufferString(delivery.Payload))
+    if err != nil {
+        attempt.Error = err.Error()
+        return attempt
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("User-Agent", "pbommo-webhooks/1.0")
+    req.Header.Set("X-Webhook-Id", webhook.ID.Hex())
+    req.Header.Set("X-Webhook-Event", delivery.Event)
+    req.Header.Set("X-Webhook-Delivery", delivery.ID.Hex())
+    req.Header.Set("X-Webhook-Timestamp", timestamp)
+    req.Header.Set("X-Webhook-Signature", "sha256="+signWebhook(webhook.Secret, timestamp, delivery.Payload))
+
+    resp, err := webhookClient.Do(req)
+    attempt.DurationMs = time.Since(start).Milliseconds()
+    if err != nil {
+        attempt.Error = err.Error()
+        return attempt
+    }
+    defer resp.Body.Close()
+
+    // The body is drained so the connection can be reused, but not kept
+    io.Copy(io.Discard, io.LimitReader(resp.Body, webhookBodyLimit))
+    attempt.ResponseCode = resp.StatusCode
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        attempt.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+    }
+    return attempt
+}
+
+func signWebhook(secret, timestamp, payload string) string {
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write([]byte(timestamp + "." + payload))
+    return hex.EncodeToString(mac.Sum(nil))
+}
+
+// recordWebhookFailure counts a failed attempt and disables the webhook,
+// failing its queued deliveries, once the limit is reached
+func recordWebhookFailure(ctx context.Context, webhook models.Webhook, now time.Time) error {
+    var updated models.Webhook
+    err := config.WebhookCollectionRef.FindOneAndUpdate(ctx, bson.M{"_id": webhook.ID},
+        bson.M{"$inc": bson.M{"consecutiveFailures": 1}},
+        options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+    if err != nil {
+        return err
+    }
+    limit := config.WebhookDisableAfter()
+    if !updated.Active || updated.ConsecutiveFailures < limit {
+        return nil
+    }
+
+    reason := fmt.Sprintf("%d consecutive failed deliveries", updated.ConsecutiveFailures)
+    if _, err := config.WebhookCollectionRef.UpdateOne(ctx, bson.M{"_id": webhook.ID, "active": true},
+        bson.M{"$set": bson.M{"active": false, "disabledAt": now, "disabledReason": reason, "updatedAt": now}}); err != nil {
+        return err
+    }
+    log.Printf("🪝 Webhook %s disabled after %s", webhook.ID.Hex(), reason)
+
+    _, err = config.WebhookDeliveryCollectionRef.UpdateMany(ctx,
+        bson.M{"webhookId": webhook.ID, "status": models.DeliveryPending, "leaseUntil": bson.M{"$exists": false}},
+        bson.M{"$set": bson.M{"status": models.DeliveryFailed, "updatedAt": now}})
+    return err
+}
+
+func finishWebhookDelivery(ctx context.Context, owner string, delivery models.WebhookDelivery, status string, attempt models.WebhookAttempt) error {
+    now := time.Now()
+    set := bson.M{"status": status, "updatedAt": now}
+    switch status {
+    case models.DeliverySuccess:
+        set["deliveredAt"] = now
+    case models.DeliveryPending:
+        set["nextAttemptAt"] = now.Add(webhookRetryBase << uint(len(delivery.Attempts)))
+    }
+
+    _, err := config.WebhookDeliveryCollectionRef.UpdateOne(ctx,
+        bson.M{"_id": delivery.ID, "leaseOwner": owner},
+        bson.M{
+            "$set":   set,
+            "$push":  bson.M{"attempts": attempt},
+            "$unset": bson.M{"leaseOwner": "", "leaseUntil": ""},
+        })
+    return err
+}
@@ -0,0 +1,103 @@
+// Package emotion classifies emotions from face frames or landmark vectors
+// on the server. Classifiers register themselves by name and the one in
+// config.EmotionClassifier is used; none is registered by default.
+package emotion
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "image"
+    _ "image/jpeg"
+    _ "image/png"
+    "sort"
+    "sync"
+)
+
+const (
+    MaxFrameBytes     = 512 * 1024
+    MaxFrameDimension = 1920
+    MaxLandmarks      = 1500 // e.g. 478 points x 3 coordinates
+)
+
+// ErrNoFace means the input contains no face to classify
+var ErrNoFace = errors.New("no face detected")
+
+// Input is one frame or one landmark vector; exactly one is set. Inputs
+// only live for the duration of Classify and are never stored.
+type Input struct {
+    Image     []byte // JPEG or PNG
+    Landmarks []float64
+}
+
+// Result holds a probability per emotion label
+type Result struct {
+    Scores     map[string]float64
+    Confidence float64 // face detection confidence, 0..1
+}
+
+// Classifier turns an input into emotion scores
+type Classifier interface {
+    Name() string
+    Classify(ctx context.Context, in Input) (Result, error)
+}
+
+var (
+    mu          sync.RWMutex
+    classifiers = map[string]Classifier{}
+)
+
+// Register adds a classifier, replacing any classifier with the same name
+func Register(c Classifier) {
+    mu.Lock()
+    defer mu.Unlock()
+    classifiers[c.Name()] = c
+}
+
+// Lookup returns the classifier registered under name
+func Lookup(name string) (Classifier, bool) {
+    mu.RLock()
+    defer mu.RUnlock()
+    c, ok := classifiers[name]
+    return c, ok
+}
+
+// Names lists the registered classifiers
+func Names() []string {
+    mu.RLock()
+    defer mu.RUnlock()
+    names := make([]string, 0, len(classifiers))
+    for name := range classifiers {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// Validate checks the input size, and that a frame is a JPEG or PNG image
+// of bounded dimensions, without decoding the pixels
+func Validate(in Input) error {
+    switch {
+    case len(in.Image) > 0 && len(in.Landmarks) > 0:
+        return fmt.Errorf("Kirim frame atau landmarks, tidak keduanya")
+    case len(in.Image) > 0:
+        if len(in.Image) > MaxFrameBytes {
+            return fmt.Errorf("Ukuran frame maksimal %d KB", MaxFrameBytes/1024)
+        }
+        cfg, format, err := image.DecodeConfig(bytes.NewReader(in.Image))
+        if err != nil || (format != "jpeg" && format != "png") {
+            return fmt.Errorf("Frame harus berupa gambar JPEG atau PNG")
+        }
+        if cfg.Width > MaxFrameDimension || cfg.Height > MaxFrameDimension {
+            return fmt.Errorf("Dimensi frame maksimal %dx%d", MaxFrameDimension, MaxFrameDimension)
+        }
+    case len(in.Landmarks) > 0:
+        if len(in.Landmarks) > MaxLandmarks {
+            return fmt.Errorf("Maksimal %d nilai landmark", MaxLandmarks)
+        }
+    default:
+        return fmt.Errorf("Frame atau landmarks wajib diisi")
+    }
+    return nil
+}
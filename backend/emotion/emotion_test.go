@@ -0,0 +1,168 @@
+package emotion
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "image"
+    "image/color"
+    "image/gif"
+    "image/jpeg"
+    "image/png"
+    "math"
+    "strings"
+    "testing"
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson/primitive"
+
+    "pbommo/models"
+)
+
+func encodeFrame(t *testing.T, format string, width, height int) []byte {
+    t.Helper()
+    img := image.NewRGBA(image.Rect(0, 0, width, height))
+    img.Set(0, 0, color.White)
+    var buf bytes.Buffer
+    var err error
+    switch format {
+    case "png":
+        err = png.Encode(&buf, img)
+    case "jpeg":
+        err = jpeg.Encode(&buf, img, nil)
+    case "gif":
+        err = gif.Encode(&buf, img, nil)
+    }
+    if err != nil {
+        t.Fatalf("encode %s: %v", format, err)
+    }
+    return buf.Bytes()
+}
+
+func TestValidate(t *testing.T) {
+    tests := []struct {
+        name string
+        in   Input
+        want string // part of the error, empty when valid
+    }{
+        {"png frame", Input{Image: encodeFrame(t, "png", 64, 48)}, ""},
+        {"jpeg frame", Input{Image: encodeFrame(t, "jpeg", 64, 48)}, ""},
+        {"landmarks", Input{Landmarks: make([]float64, MaxLandmarks)}, ""},
+        {"nothing", Input{}, "wajib diisi"},
+        {"both", Input{Image: encodeFrame(t, "png", 8, 8), Landmarks: []float64{1}}, "tidak keduanya"},
+        {"gif frame", Input{Image: encodeFrame(t, "gif", 8, 8)}, "JPEG atau PNG"},
+        {"not an image", Input{Image: []byte("hello")}, "JPEG atau PNG"},
+        {"too wide", Input{Image: encodeFrame(t, "png", MaxFrameDimension+1, 1)}, "Dimensi frame"},
+        {"too heavy", Input{Image: make([]byte, MaxFrameBytes+1)}, "Ukuran frame"},
+        {"too many landmarks", Input{Landmarks: make([]float64, MaxLandmarks+1)}, "landmark"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            err := Validate(tt.in)
+            switch {
+            case tt.want == "" && err != nil:
+                t.Errorf("Validate() = %v, want nil", err)
+            case tt.want != "" && err == nil:
+                t.Errorf("Validate() = nil, want error containing %q", tt.want)
+            case tt.want != "" && !strings.Contains(err.Error(), tt.want):
+                t.Errorf("Validate() = %q, want error containing %q", err, tt.want)
+            }
+        })
+    }
+}
+
+func TestNoClassifierRegisteredByDefault(t *testing.T) {
+    if names := Names(); len(names) != 0 {
+        t.Fatalf("classifiers registered without configuration: %v", names)
+    }
+    if _, ok := Lookup("stub"); ok {
+        t.Fatal("stub classifier is available outside tests")
+    }
+}
+
+func TestRegisterAndLookup(t *testing.T) {
+    Register(stubClassifier{})
+    t.Cleanup(func() {
+        mu.Lock()
+        delete(classifiers, "stub")
+        mu.Unlock()
+    })
+
+    c, ok := Lookup("stub")
+    if !ok || c.Name() != "stub" {
+        t.Fatalf("Lookup(stub) = %v, %v", c, ok)
+    }
+    if _, ok := Lookup("missing"); ok {
+        t.Error("Lookup(missing) found a classifier")
+    }
+    if names := Names(); len(names) != 1 || names[0] != "stub" {
+        t.Errorf("Names() = %v, want [stub]", names)
+    }
+}
+
+func TestStubClassifier(t *testing.T) {
+    ctx := context.Background()
+    in := Input{Image: encodeFrame(t, "png", 16, 16)}
+
+    first, err := stubClassifier{}.Classify(ctx, in)
+    if err != nil {
+        t.Fatalf("Classify: %v", err)
+    }
+    again, _ := stubClassifier{}.Classify(ctx, in)
+    total := 0.0
+    for _, label := range models.EmotionLabels {
+        score, ok := first.Scores[label]
+        if !ok {
+            t.Errorf("no score for %s", label)
+        }
+        if again.Scores[label] != score {
+            t.Errorf("%s scored %v then %v for the same input", label, score, again.Scores[label])
+        }
+        total += score
+    }
+    if math.Abs(total-1) > 0.01 {
+        t.Errorf("scores sum to %v, want 1", total)
+    }
+    if first.Confidence < 0.5 || first.Confidence > 1 {
+        t.Errorf("confidence %v outside 0.5..1", first.Confidence)
+    }
+
+    if _, err := (stubClassifier{}).Classify(ctx, Input{Landmarks: make([]float64, 12)}); !errors.Is(err, ErrNoFace) {
+        t.Errorf("all-zero landmarks: err = %v, want ErrNoFace", err)
+    }
+}
+
+func TestLiveThresholdExcludesViewer(t *testing.T) {
+    meetingID := primitive.NewObjectID()
+    // Observe prunes against the clock
+    now := time.Now()
+    start := now.Add(-10 * time.Minute)
+    viewer := primitive.NewObjectID()
+    t.Cleanup(func() {
+        liveMu.Lock()
+        delete(liveSamples, liveKey{meetingID, start.Unix()})
+        liveMu.Unlock()
+    })
+
+    sample := func(user primitive.ObjectID) LiveSample {
+        return LiveSample{
+            MeetingID:       meetingID,
+            OccurrenceStart: start,
+            UserID:          user,
+            Timestamp:       now.Add(-time.Second),
+            Scores:          map[string]float64{models.EmotionHappy: 1},
+        }
+    }
+    Observe(sample(viewer), sample(primitive.NewObjectID()), sample(primitive.NewObjectID()))
+
+    agg := Live(meetingID, start, now, 30*time.Second, 3, viewer)
+    if agg.Participants != 3 || agg.Sufficient || agg.Distribution != nil {
+        t.Fatalf("viewer counted towards the threshold: %+v", agg)
+    }
+
+    Observe(sample(primitive.NewObjectID()))
+    agg = Live(meetingID, start, now, 30*time.Second, 3, viewer)
+    if !agg.Sufficient || agg.Dominant != models.EmotionHappy {
+        t.Fatalf("three other participants should be enough: %+v", agg)
+    }
+}
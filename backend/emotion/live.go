@@ -0,0 +1,159 @@
+package emotion
+
+import (
+    "math"
+    "sync"
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson/primitive"
+
+    "pbommo/models"
+)
+
+// maxLiveSamples bounds the samples kept per occurrence
+const maxLiveSamples = 10000
+
+// LiveSample is a sample fed to the live aggregates
+type LiveSample struct {
+    MeetingID       primitive.ObjectID `json:"meetingId"`
+    OccurrenceStart time.Time          `json:"occurrenceStart"`
+    UserID          primitive.ObjectID `json:"userId"`
+    Timestamp       time.Time          `json:"timestamp"`
+    Scores          map[string]float64 `json:"scores"`
+}
+
+// LiveAggregate is the anonymized mood of a meeting over a recent window.
+// Below the participant threshold only the participant count is filled.
+type LiveAggregate struct {
+    WindowStart  time.Time          `json:"windowStart"`
+    WindowEnd    time.Time          `json:"windowEnd"`
+    Participants int                `json:"participants"`
+    Sufficient   bool               `json:"sufficient"`
+    Distribution map[string]float64 `json:"distribution,omitempty"`
+    Dominant     string             `json:"dominant,omitempty"`
+    // Mood runs from -1 (negative emotions) to 1 (positive emotions)
+    Mood float64 `json:"mood"`
+}
+
+type liveKey struct {
+    meetingID primitive.ObjectID
+    start     int64
+}
+
+var (
+    liveMu      sync.Mutex
+    liveSamples = map[liveKey][]LiveSample{}
+    liveWindow  = 30 * time.Second
+)
+
+// Observe adds samples to the live aggregates
+func Observe(samples ...LiveSample) {
+    liveMu.Lock()
+    defer liveMu.Unlock()
+    now := time.Now()
+    for _, s := range samples {
+        key := liveKey{s.MeetingID, s.OccurrenceStart.Unix()}
+        list := append(liveSamples[key], s)
+        if len(list) > maxLiveSamples {
+            list = list[len(list)-maxLiveSamples:]
+        }
+        liveSamples[key] = list
+    }
+    pruneLive(now)
+}
+
+// Forget drops a user's samples of a meeting, e.g. after consent is withdrawn
+func Forget(meetingID, userID primitive.ObjectID) {
+    liveMu.Lock()
+    defer liveMu.Unlock()
+    for key, list := range liveSamples {
+        if key.meetingID != meetingID {
+            continue
+        }
+        kept := list[:0]
+        for _, s := range list {
+            if s.UserID != userID {
+                kept = append(kept, s)
+            }
+        }
+        liveSamples[key] = kept
+    }
+}
+
+// Live aggregates the samples of an occurrence within window before now.
+// Every participant weighs the same, however many samples they sent, and
+// nothing but the count is returned with fewer than minParticipants other
+// than the viewer.
+func Live(meetingID primitive.ObjectID, occurrenceStart, now time.Time, window time.Duration, minParticipants int, viewer primitive.ObjectID) LiveAggregate {
+    liveMu.Lock()
+    defer liveMu.Unlock()
+    if window > liveWindow {
+        liveWindow = window
+    }
+    pruneLive(now)
+
+    agg := LiveAggregate{WindowStart: now.Add(-window), WindowEnd: now}
+    perUser := map[primitive.ObjectID]map[string]float64{}
+    counts := map[primitive.ObjectID]int{}
+    for _, s := range liveSamples[liveKey{meetingID, occurrenceStart.Unix()}] {
+        if s.Timestamp.Before(agg.WindowStart) || s.Timestamp.After(now) {
+            continue
+        }
+        if perUser[s.UserID] == nil {
+            perUser[s.UserID] = map[string]float64{}
+        }
+        for label, score := range s.Scores {
+            perUser[s.UserID][label] += score
+        }
+        counts[s.UserID]++
+    }
+
+    agg.Participants = len(perUser)
+    others := agg.Participants
+    if _, ok := perUser[viewer]; ok {
+        others--
+    }
+    if others == 0 || others < minParticipants {
+        return agg
+    }
+    agg.Sufficient = true
+    agg.Distribution = map[string]float64{}
+    for userID, sums := range perUser {
+        for _, label := range models.EmotionLabels {
+            agg.Distribution[label] += sums[label] / float64(counts[userID]) / float64(agg.Participants)
+        }
+    }
+    best := -1.0
+    for _, label := range models.EmotionLabels {
+        agg.Distribution[label] = math.Round(agg.Distribution[label]*1000) / 1000
+        if agg.Distribution[label] > best {
+            agg.Dominant, best = label, agg.Distribution[label]
+        }
+    }
+    d := agg.Distribution
+    positive := d[models.EmotionHappy] + d[models.EmotionSurprised]
+    negative := d[models.EmotionSad] + d[models.EmotionAngry] + d[models.EmotionFearful] + d[models.EmotionDisgusted]
+    if positive+negative > 0 {
+        agg.Mood = math.Round((positive-negative)/(positive+negative)*1000) / 1000
+    }
+    return agg
+}
+
+// pruneLive drops samples older than the longest window asked for. Batches
+// may arrive out of order, so every sample is checked.
+func pruneLive(now time.Time) {
+    cutoff := now.Add(-liveWindow)
+    for key, list := range liveSamples {
+        kept := list[:0]
+        for _, s := range list {
+            if !s.Timestamp.Before(cutoff) {
+                kept = append(kept, s)
+            }
+        }
+        if len(kept) == 0 {
+            delete(liveSamples, key)
+        } else {
+            liveSamples[key] = kept
+        }
+    }
+}
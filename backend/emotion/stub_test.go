@@ -0,0 +1,51 @@
+package emotion
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/binary"
+    "math"
+
+    "pbommo/models"
+)
+
+// stubClassifier derives scores from a hash of the input, so the same
+// input always gives the same result. It does not look at the face at all
+// and only exists for tests; an all-zero landmark vector counts as no face.
+type stubClassifier struct{}
+
+func (stubClassifier) Name() string { return "stub" }
+
+func (stubClassifier) Classify(ctx context.Context, in Input) (Result, error) {
+    h := sha256.New()
+    if len(in.Image) > 0 {
+        h.Write(in.Image)
+    } else {
+        face := false
+        buf := make([]byte, 8)
+        for _, v := range in.Landmarks {
+            face = face || v != 0
+            binary.LittleEndian.PutUint64(buf, math.Float64bits(v))
+            h.Write(buf)
+        }
+        if !face {
+            return Result{}, ErrNoFace
+        }
+    }
+    sum := h.Sum(nil)
+
+    scores := make(map[string]float64, len(models.EmotionLabels))
+    total := 0.0
+    for i, label := range models.EmotionLabels {
+        w := float64(sum[i]) + 1
+        scores[label] = w
+        total += w
+    }
+    for label, w := range scores {
+        scores[label] = math.Round(w/total*1000) / 1000
+    }
+    return Result{
+        Scores:     scores,
+        Confidence: 0.5 + float64(sum[len(models.EmotionLabels)])/510,
+    }, nil
+}
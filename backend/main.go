@@ -1,10 +1,16 @@
 package main
 
 import (
+    "context"
     "log"
     "os"
+    "os/signal"
+    "syscall"
 
     "pbommo/config"
+    "pbommo/controllers"
+    "pbommo/notify"
+    "pbommo/realtime"
     "pbommo/routes"
 
     "github.com/gofiber/fiber/v2"
@@ -65,9 +71,49 @@ func main() {
     config.ConnectDB()
     log.Println("✅ Connected to database")
 
+    // Background jobs stop when the process is asked to shut down
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+
+    // Start background jobs
+    if err := notify.EnsureInAppIndexes(context.Background()); err != nil {
+        log.Printf("⚠️ Failed to create notification indexes: %v", err)
+    }
+    if err := controllers.EnsureCalendarTokenIndex(context.Background()); err != nil {
+        log.Printf("⚠️ Failed to create calendar token index: %v", err)
+    }
+    if err := controllers.EnsureOccurrenceIndex(context.Background()); err != nil {
+        log.Printf("⚠️ Failed to create occurrence index: %v", err)
+    }
+    if err := controllers.EnsureEmotionIndexes(context.Background()); err != nil {
+        log.Printf("⚠️ Failed to create emotion sample indexes: %v", err)
+    }
+    if err := controllers.EnsureMeetingNoteIndexes(context.Background()); err != nil {
+        log.Printf("⚠️ Failed to create meeting note indexes: %v", err)
+    }
+    if err := controllers.EnsureActionItemIndexes(context.Background()); err != nil {
+        log.Printf("⚠️ Failed to create action item indexes: %v", err)
+    }
+    controllers.StartReminderScheduler(ctx)
+    controllers.StartWebhookWorker(ctx)
+    controllers.StartMeetingPurger(ctx)
+    controllers.StartActionItemWatcher(ctx)
+    if config.RealtimeBroker() == config.RealtimeBrokerMongo {
+        realtime.SetBroker(realtime.NewMongoBroker(config.MongoClient.Database(config.GetDbName()), "events", 16<<20))
+    }
+    realtime.Start(ctx)
+
     // Setup routes
     routes.SetupRoutes(app)
 
+    go func() {
+        <-ctx.Done()
+        log.Println("🛑 Shutting down")
+        if err := app.Shutdown(); err != nil {
+            log.Printf("⚠️ Failed to shut down server: %v", err)
+        }
+    }()
+
     // Start server
     log.Printf("🚀 Server running at http://localhost:%s", port)
     if err := app.Listen(":" + port); err != nil {
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+    "context"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+
+    "pbommo/config"
+    "pbommo/models"
+)
+
+// AdminOnly middleware untuk routes yang hanya boleh diakses admin.
+// Harus dipasang setelah Protected() karena membaca userID dari locals.
+func AdminOnly() fiber.Handler {
+    return func(c *fiber.Ctx) error {
+        idStr, _ := c.Locals("userID").(string)
+        userID, err := primitive.ObjectIDFromHex(idStr)
+        if err != nil {
+            return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+                "error": "Unauthorized: invalid user in token",
+            })
+        }
+
+        ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        defer cancel()
+
+        var user models.User
+        err = config.UserCollectionRef.FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+        if err != nil || user.Role != models.RoleAdmin {
+            return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+                "error": "Forbidden: admin access required",
+            })
+        }
+
+        return c.Next()
+    }
+}
@@ -1,12 +1,20 @@
 package middleware
 
 import (
+    "context"
     "fmt"
     "os"
     "strings"
+    "time"
 
     "github.com/gofiber/fiber/v2"
     "github.com/golang-jwt/jwt/v5"
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo/options"
+
+    "pbommo/config"
+    "pbommo/models"
 )
 
 // Protected middleware untuk routes yang memerlukan autentikasi
@@ -63,6 +71,25 @@ func Protected() fiber.Handler {
             })
         }
         
+        // Deprovisioned users lose access right away, not when the token expires
+        idStr, _ := claims["id"].(string)
+        userID, err := primitive.ObjectIDFromHex(idStr)
+        if err != nil {
+            return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+                "error": "Unauthorized: invalid user in token",
+            })
+        }
+        ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        defer cancel()
+        var user models.User
+        err = config.UserCollectionRef.FindOne(ctx, bson.M{"_id": userID},
+            options.FindOne().SetProjection(bson.M{"disabled": 1})).Decode(&user)
+        if err != nil || user.Disabled {
+            return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+                "error": "Unauthorized: account not found or disabled",
+            })
+        }
+        
         // Set user ID in locals for later use in handlers
         c.Locals("userID", claims["id"])
         c.Locals("userEmail", claims["email"])
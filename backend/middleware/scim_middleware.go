@@ -0,0 +1,29 @@
+package middleware
+
+import (
+    "crypto/subtle"
+    "os"
+    "strings"
+
+    "github.com/gofiber/fiber/v2"
+)
+
+// ScimAuth middleware untuk endpoint SCIM, memakai bearer token statis dari SCIM_TOKEN
+func ScimAuth() fiber.Handler {
+    return func(c *fiber.Ctx) error {
+        expected := os.Getenv("SCIM_TOKEN")
+        authHeader := c.Get("Authorization")
+        token := strings.TrimPrefix(authHeader, "Bearer ")
+
+        if expected == "" || token == authHeader ||
+            subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+            return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+                "schemas": []string{"urn:ietf:params:scim:api:messages:2.0:Error"},
+                "status":  "401",
+                "detail":  "Unauthorized: missing or invalid SCIM token",
+            }, "application/scim+json")
+        }
+
+        return c.Next()
+    }
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Action item states; open and in progress items are still to be done
+const (
+    ActionOpen       = "open"
+    ActionInProgress = "in_progress"
+    ActionDone       = "done"
+    ActionCancelled  = "cancelled"
+)
+
+// ActionItem is a task agreed in a meeting. Open items are copied to a
+// follow-up meeting when one is scheduled: the original links to the
+// follow-up through CarriedTo, and CarriedFrom of the copy lists the
+// meetings it came through.
+type ActionItem struct {
+    ID                primitive.ObjectID   `bson:"_id,omitempty" json:"id,omitempty"`
+    MeetingID         primitive.ObjectID   `bson:"meetingId" json:"meetingId"`
+    Description       string               `bson:"description" json:"description"`
+    AssigneeID        *primitive.ObjectID  `bson:"assigneeId,omitempty" json:"assigneeId,omitempty"`
+    DueDate           *time.Time           `bson:"dueDate,omitempty" json:"dueDate,omitempty"`
+    Status            string               `bson:"status" json:"status"`
+    Overdue           bool                 `bson:"-" json:"overdue"`
+    CarriedFrom       []primitive.ObjectID `bson:"carriedFrom,omitempty" json:"carriedFrom,omitempty"`
+    CarriedTo         *primitive.ObjectID  `bson:"carriedTo,omitempty" json:"carriedTo,omitempty"`
+    CreatedBy         primitive.ObjectID   `bson:"createdBy" json:"createdBy"`
+    CompletedAt       *time.Time           `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+    OverdueNotifiedAt *time.Time           `bson:"overdueNotifiedAt,omitempty" json:"-"`
+    OverdueRetryAt    *time.Time           `bson:"overdueRetryAt,omitempty" json:"-"`
+    CreatedAt         time.Time            `bson:"createdAt" json:"createdAt"`
+    UpdatedAt         time.Time            `bson:"updatedAt" json:"updatedAt"`
+}
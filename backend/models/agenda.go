@@ -0,0 +1,29 @@
+package models
+
+import (
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Agenda item states. Proposed items were suggested by a participant and
+// do not take up meeting time until the organizer accepts them.
+const (
+    AgendaProposed = "proposed"
+    AgendaOpen     = "open"
+    AgendaDone     = "done"
+)
+
+// AgendaItem is one time-boxed topic of a meeting. Items are kept in
+// Meeting.Agenda in the order they are discussed.
+type AgendaItem struct {
+    ID          primitive.ObjectID  `bson:"_id" json:"id"`
+    Title       string              `bson:"title" json:"title"`
+    OwnerID     *primitive.ObjectID `bson:"ownerId,omitempty" json:"ownerId,omitempty"` // who presents the item
+    Minutes     int                 `bson:"minutes" json:"minutes"`                     // allotted time
+    Notes       string              `bson:"notes,omitempty" json:"notes,omitempty"`
+    Status      string              `bson:"status" json:"status"`
+    ProposedBy  *primitive.ObjectID `bson:"proposedBy,omitempty" json:"proposedBy,omitempty"`
+    CompletedAt *time.Time          `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+    CreatedAt   time.Time           `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WorkingDay is one block of working time on a weekday, as wall-clock times
+// in the user's timezone. A weekday may have several blocks (e.g. around a
+// lunch break); weekdays without blocks are days off.
+type WorkingDay struct {
+    Weekday int    `bson:"weekday" json:"weekday"` // 0 = Sunday
+    Start   string `bson:"start" json:"start"`     // "09:00"
+    End     string `bson:"end" json:"end"`         // "17:00"
+}
+
+// Absence is an out-of-office period during which the user is unavailable
+type Absence struct {
+    ID        primitive.ObjectID `bson:"_id" json:"id"`
+    Start     time.Time          `bson:"start" json:"start"`
+    End       time.Time          `bson:"end" json:"end"`
+    Reason    string             `bson:"reason,omitempty" json:"reason,omitempty"`
+    CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Emotion labels, as produced by face-api.js expression detection
+const (
+    EmotionNeutral   = "neutral"
+    EmotionHappy     = "happy"
+    EmotionSad       = "sad"
+    EmotionAngry     = "angry"
+    EmotionFearful   = "fearful"
+    EmotionDisgusted = "disgusted"
+    EmotionSurprised = "surprised"
+)
+
+// EmotionLabels lists every known emotion label
+var EmotionLabels = []string{
+    EmotionNeutral, EmotionHappy, EmotionSad, EmotionAngry,
+    EmotionFearful, EmotionDisgusted, EmotionSurprised,
+}
+
+// Where an emotion sample was classified
+const (
+    EmotionSourceClient = "client"
+    EmotionSourceServer = "server"
+)
+
+// EmotionSample is one participant's detected emotion at one moment of a
+// tracked meeting. Samples are unique per meeting, user and timestamp, so
+// a client may safely resend a batch.
+type EmotionSample struct {
+    ID              primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+    MeetingID       primitive.ObjectID `bson:"meetingId" json:"meetingId"` // the series for occurrences of a recurring meeting
+    OccurrenceStart time.Time          `bson:"occurrenceStart" json:"occurrenceStart"`
+    UserID          primitive.ObjectID `bson:"userId" json:"userId"`
+    Timestamp       time.Time          `bson:"timestamp" json:"timestamp"`
+    Scores          map[string]float64 `bson:"scores" json:"scores"`         // label -> probability, 0..1
+    Dominant        string             `bson:"dominant" json:"dominant"`     // label with the highest score
+    Confidence      float64            `bson:"confidence" json:"confidence"` // face detection confidence, 0..1
+    Source          string             `bson:"source" json:"source"`
+    CreatedAt       time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// EmotionReport summarizes the emotion samples of one meeting occurrence.
+// Distributions map each label to its average score.
+type EmotionReport struct {
+    MeetingID        primitive.ObjectID   `bson:"meetingId" json:"meetingId"`
+    OccurrenceStart  time.Time            `bson:"occurrenceStart" json:"occurrenceStart"`
+    SampleCount      int                  `bson:"sampleCount" json:"sampleCount"`
+    ParticipantCount int                  `bson:"participantCount" json:"participantCount"`
+    Distribution     map[string]float64   `bson:"distribution" json:"distribution"`
+    EngagementScore  int                  `bson:"engagementScore" json:"engagementScore"` // 0..100
+    Timeline         []EmotionMinute      `bson:"timeline" json:"timeline"`
+    Participants     []ParticipantEmotion `bson:"participants" json:"participants"`
+    Peaks            []EmotionPeak        `bson:"peaks" json:"peaks"`
+    Final            bool                 `bson:"final" json:"final"` // the meeting has ended and the report is cached
+    GeneratedAt      time.Time            `bson:"generatedAt" json:"generatedAt"`
+}
+
+// EmotionMinute is the aggregated distribution of one minute of a meeting
+type EmotionMinute struct {
+    Minute       int                `bson:"minute" json:"minute"` // minutes since the start
+    Start        time.Time          `bson:"start" json:"start"`
+    Samples      int                `bson:"samples" json:"samples"`
+    Participants int                `bson:"participants" json:"participants"`
+    Distribution map[string]float64 `bson:"distribution" json:"distribution"`
+    Dominant     string             `bson:"dominant" json:"dominant"`
+}
+
+// ParticipantEmotion summarizes one participant's samples
+type ParticipantEmotion struct {
+    UserID        primitive.ObjectID `bson:"userId" json:"userId"`
+    Nama          string             `bson:"nama" json:"nama"`
+    Samples       int                `bson:"samples" json:"samples"`
+    ActiveMinutes int                `bson:"activeMinutes" json:"activeMinutes"`
+    FirstSeen     time.Time          `bson:"firstSeen" json:"firstSeen"`
+    LastSeen      time.Time          `bson:"lastSeen" json:"lastSeen"`
+    Distribution  map[string]float64 `bson:"distribution" json:"distribution"`
+    Dominant      string             `bson:"dominant" json:"dominant"`
+    Confidence    float64            `bson:"confidence" json:"confidence"`
+    Engagement    int                `bson:"engagement" json:"engagement"` // 0..100
+}
+
+// EmotionPeak is a minute with unusually strong non-neutral emotion
+type EmotionPeak struct {
+    Minute    int       `bson:"minute" json:"minute"`
+    Start     time.Time `bson:"start" json:"start"`
+    Emotion   string    `bson:"emotion" json:"emotion"`
+    Intensity float64   `bson:"intensity" json:"intensity"` // 1 - neutral score
+}
+
+// Who may see individual results in emotion reports (Meeting.EmotionVisibility).
+// Participants always see their own results and the aggregates.
+const (
+    EmotionVisibilityOrganizer = "organizer" // the organizer sees everyone (default)
+    EmotionVisibilityAggregate = "aggregate" // nobody sees other people's results
+)
+
+// EmotionConsent records a participant opting in to emotion tracking of a
+// meeting. Withdrawing keeps the record, with WithdrawnAt set, and deletes
+// the participant's samples.
+type EmotionConsent struct {
+    ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+    MeetingID   primitive.ObjectID `bson:"meetingId" json:"meetingId"`
+    UserID      primitive.ObjectID `bson:"userId" json:"userId"`
+    GrantedAt   time.Time          `bson:"grantedAt" json:"grantedAt"`
+    WithdrawnAt *time.Time         `bson:"withdrawnAt,omitempty" json:"withdrawnAt,omitempty"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MeetingNote is the shared Markdown notes of a meeting. Every save bumps
+// Version and is kept as a NoteRevision; a save based on an older version
+// is refused so concurrent edits are not lost.
+type MeetingNote struct {
+    ID               primitive.ObjectID   `bson:"_id,omitempty" json:"id,omitempty"`
+    MeetingID        primitive.ObjectID   `bson:"meetingId" json:"meetingId"`
+    Content          string               `bson:"content" json:"content"`
+    Version          int                  `bson:"version" json:"version"`
+    UpdatedBy        primitive.ObjectID   `bson:"updatedBy" json:"updatedBy"`
+    Contributors     []primitive.ObjectID `bson:"contributors" json:"contributors"`
+    PublishedVersion int                  `bson:"publishedVersion,omitempty" json:"publishedVersion,omitempty"` // revision sent out as minutes
+    PublishedBy      *primitive.ObjectID  `bson:"publishedBy,omitempty" json:"publishedBy,omitempty"`
+    PublishedAt      *time.Time           `bson:"publishedAt,omitempty" json:"publishedAt,omitempty"`
+    CreatedAt        time.Time            `bson:"createdAt" json:"createdAt"`
+    UpdatedAt        time.Time            `bson:"updatedAt" json:"updatedAt"`
+}
+
+// NoteRevision is the content of a meeting's notes as saved at one version
+type NoteRevision struct {
+    ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+    MeetingID primitive.ObjectID `bson:"meetingId" json:"meetingId"`
+    Version   int                `bson:"version" json:"version"`
+    Content   string             `bson:"content" json:"content,omitempty"`
+    AuthorID  primitive.ObjectID `bson:"authorId" json:"authorId"`
+    CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// In-app notification types
+const (
+    NotificationInvite           = "invite"
+    NotificationMeetingUpdated   = "meeting_updated"
+    NotificationMeetingCancelled = "meeting_cancelled"
+    NotificationRSVP             = "rsvp"
+    NotificationMention          = "mention"
+    NotificationReminder         = "reminder"
+    NotificationEmotionConsent   = "emotion_consent"
+    NotificationAgendaProposal   = "agenda_proposal"
+    NotificationMinutes          = "minutes"
+    NotificationActionItem       = "action_item"
+    NotificationActionOverdue    = "action_item_overdue"
+)
+
+// Notification is an entry in a user's in-app inbox
+type Notification struct {
+    ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+    UserID    primitive.ObjectID  `bson:"userId" json:"userId"`
+    Type      string              `bson:"type" json:"type"`
+    Title     string              `bson:"title" json:"title"`
+    Body      string              `bson:"body,omitempty" json:"body,omitempty"`
+    MeetingID *primitive.ObjectID `bson:"meetingId,omitempty" json:"meetingId,omitempty"`
+    ActorID   *primitive.ObjectID `bson:"actorId,omitempty" json:"actorId,omitempty"` // who caused it, if anyone
+    Link      string              `bson:"link,omitempty" json:"link,omitempty"`
+    Read      bool                `bson:"read" json:"read"`
+    ReadAt    *time.Time          `bson:"readAt,omitempty" json:"readAt,omitempty"`
+    CreatedAt time.Time           `bson:"createdAt" json:"createdAt"`
+}
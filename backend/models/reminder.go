@@ -0,0 +1,37 @@
+package models
+
+import (
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reminder job states
+const (
+    ReminderPending = "pending"
+    ReminderSent    = "sent"
+    ReminderFailed  = "failed"  // gave up after repeated delivery errors
+    ReminderSkipped = "skipped" // no longer applies, e.g. meeting moved or deleted
+)
+
+// ReminderJob is one reminder for one user, occurrence, offset and channel.
+// Jobs are unique on that combination so planning them again after a
+// restart is a no-op. A scheduler instance leases a job before sending it.
+type ReminderJob struct {
+    ID              primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+    MeetingID       primitive.ObjectID `bson:"meetingId" json:"meetingId"` // the series for occurrences of a recurring meeting
+    UserID          primitive.ObjectID `bson:"userId" json:"userId"`
+    Channel         string             `bson:"channel" json:"channel"`
+    OccurrenceStart time.Time          `bson:"occurrenceStart" json:"occurrenceStart"`
+    OffsetMinutes   int                `bson:"offsetMinutes" json:"offsetMinutes"`
+    FireAt          time.Time          `bson:"fireAt" json:"fireAt"`
+    Status          string             `bson:"status" json:"status"`
+    Attempts        int                `bson:"attempts" json:"attempts"`
+    LeaseOwner      string             `bson:"leaseOwner,omitempty" json:"-"`
+    LeaseUntil      time.Time          `bson:"leaseUntil,omitempty" json:"-"`
+    LastError       string             `bson:"lastError,omitempty" json:"lastError,omitempty"`
+    SentAt          *time.Time         `bson:"sentAt,omitempty" json:"sentAt,omitempty"`
+    FinishedAt      *time.Time         `bson:"finishedAt,omitempty" json:"-"` // when it was sent, skipped or failed; expires the job
+    CreatedAt       time.Time          `bson:"createdAt" json:"createdAt"`
+    UpdatedAt       time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Team groups users; membership is stored on User.Team by team name
+type Team struct {
+    ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+    Name       string             `bson:"name" json:"name"`
+    ExternalID string             `bson:"externalId,omitempty" json:"externalId,omitempty"`
+    CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+    UpdatedAt  time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
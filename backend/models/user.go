@@ -6,23 +6,66 @@ import (
     "go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// User roles
+const (
+    RoleUser  = "User"
+    RoleAdmin = "Admin"
+)
+
 type User struct {
     ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
     Nama         string             `bson:"nama" json:"nama"`
     Email        string             `bson:"email" json:"email"`
     Password     string             `bson:"password" json:"password,omitempty"` // Allow JSON parsing for registration
     Role         string             `bson:"role" json:"role,omitempty"`
+    Team         string             `bson:"team" json:"team,omitempty"`
+    Timezone     string             `bson:"timezone,omitempty" json:"timezone,omitempty"` // IANA name, e.g. Asia/Jakarta
+    Language     string             `bson:"language,omitempty" json:"language,omitempty"` // "id" or "en", for emails
+    WorkingHours []WorkingDay       `bson:"workingHours,omitempty" json:"workingHours,omitempty"` // empty means Mon-Fri 09:00-17:00
+    HolidayCalendar string          `bson:"holidayCalendar,omitempty" json:"holidayCalendar,omitempty"` // name of a file in HOLIDAY_CALENDAR_DIR
+    Absences     []Absence          `bson:"absences,omitempty" json:"absences,omitempty"`
+    Notifications *NotificationPreferences `bson:"notifications,omitempty" json:"notifications,omitempty"`
     Bio          string             `bson:"bio" json:"bio,omitempty"`
     ProfileImage string             `bson:"profileImage" json:"profileImage,omitempty"`
+    ExternalID   string             `bson:"externalId,omitempty" json:"externalId,omitempty"`
+    Disabled     bool               `bson:"disabled" json:"disabled,omitempty"` // set when deprovisioned, blocks login
+    CalendarToken string            `bson:"calendarToken,omitempty" json:"-"` // secret for the iCalendar feed URL
+    InviteToken     string          `bson:"inviteToken,omitempty" json:"-"`
+    InviteExpiresAt time.Time       `bson:"inviteExpiresAt,omitempty" json:"-"`
     CreatedAt    time.Time          `bson:"createdAt" json:"createdAt,omitempty"`
     UpdatedAt    time.Time          `bson:"updatedAt" json:"updatedAt,omitempty"`
 }
 
+// NotificationPreferences mirrors the Notifications tab in Settings
+type NotificationPreferences struct {
+    EmailNotifications bool  `bson:"emailNotifications" json:"emailNotifications"`
+    PushNotifications  bool  `bson:"pushNotifications" json:"pushNotifications"`
+    MeetingReminders   bool  `bson:"meetingReminders" json:"meetingReminders"`
+    WeeklyDigest       bool  `bson:"weeklyDigest" json:"weeklyDigest"`
+    ReminderOffsets    []int `bson:"reminderOffsets,omitempty" json:"reminderOffsets,omitempty"` // minutes before the start, empty uses REMINDER_OFFSETS
+}
+
+// NotificationPrefs returns the user's preferences, or the defaults for
+// users who never saved them
+func (u User) NotificationPrefs() NotificationPreferences {
+    if u.Notifications != nil {
+        return *u.Notifications
+    }
+    return NotificationPreferences{
+        EmailNotifications: true,
+        PushNotifications:  true,
+        MeetingReminders:   true,
+    }
+}
+
 type UserResponse struct {
     ID           primitive.ObjectID `json:"id,omitempty"`
     Nama         string             `json:"nama"`
     Email        string             `json:"email"`
     Role         string             `json:"role,omitempty"`
+    Team         string             `json:"team,omitempty"`
+    Timezone     string             `json:"timezone,omitempty"`
+    Language     string             `json:"language,omitempty"`
     Bio          string             `json:"bio,omitempty"`
     ProfileImage string             `json:"profileImage,omitempty"`
     Status       string             `json:"status,omitempty"`
@@ -37,7 +80,71 @@ type Meeting struct {
     Duration    int                  `bson:"duration" json:"duration"` // in minutes
     CreatedBy   primitive.ObjectID   `bson:"createdBy" json:"createdBy"`
     Participants []primitive.ObjectID `bson:"participants" json:"participants"`
+    Guests       []Guest             `bson:"guests,omitempty" json:"guests,omitempty"` // invited emails without an account
+    Responses    []Response          `bson:"responses,omitempty" json:"responses,omitempty"`
     EmotionTracking bool             `bson:"emotionTracking" json:"emotionTracking"`
+    EmotionVisibility string         `bson:"emotionVisibility,omitempty" json:"emotionVisibility,omitempty"` // who sees individual emotion results
+    Agenda          []AgendaItem     `bson:"agenda,omitempty" json:"agenda,omitempty"`
+    AgendaProposals bool             `bson:"agendaProposals" json:"agendaProposals"` // participants may propose agenda items
+    AgendaVersion   int              `bson:"agendaVersion" json:"-"`                 // bumped on every agenda change
+    FollowUpOf   *primitive.ObjectID `bson:"followUpOf,omitempty" json:"followUpOf,omitempty"` // meeting whose open action items carried over
+    Recurrence   *Recurrence         `bson:"recurrence,omitempty" json:"recurrence,omitempty"`
+    SeriesID     *primitive.ObjectID `bson:"seriesId,omitempty" json:"seriesId,omitempty"`         // set on occurrences of a recurring series
+    RecurrenceID *time.Time          `bson:"recurrenceId,omitempty" json:"recurrenceId,omitempty"` // original start of the occurrence
+    Sequence     int                 `bson:"sequence" json:"sequence"`                             // iCalendar SEQUENCE, bumped on every change
+    ICalUID      string              `bson:"icalUid,omitempty" json:"icalUid,omitempty"`           // UID of the imported VEVENT
+    Status       string              `bson:"status,omitempty" json:"status"`                       // lifecycle status, see MeetingScheduled
+    ActualStart  *time.Time          `bson:"actualStart,omitempty" json:"actualStart,omitempty"`   // when the organizer started it
+    ActualEnd    *time.Time          `bson:"actualEnd,omitempty" json:"actualEnd,omitempty"`       // when the organizer ended it
+    CancelledAt  *time.Time          `bson:"cancelledAt,omitempty" json:"cancelledAt,omitempty"`
+    CancelReason string              `bson:"cancelReason,omitempty" json:"cancelReason,omitempty"`
+    DeletedAt    *time.Time          `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"` // moved to the trash, whatever its status
     CreatedAt    time.Time           `bson:"createdAt" json:"createdAt"`
     UpdatedAt    time.Time           `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Guest is an external participant who accesses the meeting through a
+// personal tokenized link instead of logging in
+type Guest struct {
+    Email     string    `bson:"email" json:"email"`
+    Token     string    `bson:"token" json:"-"`
+    InvitedAt time.Time `bson:"invitedAt" json:"invitedAt"`
+}
+
+// RSVP response states
+const (
+    ResponsePending   = "pending"
+    ResponseAccepted  = "accepted"
+    ResponseDeclined  = "declined"
+    ResponseTentative = "tentative"
+)
+
+// Meeting lifecycle states. Meetings without a stored status are
+// scheduled; scheduled and live meetings complete on their own once their
+// time is over.
+const (
+    MeetingScheduled = "scheduled"
+    MeetingLive      = "live"
+    MeetingCompleted = "completed"
+    MeetingCancelled = "cancelled"
+)
+
+// Response is a participant's or guest's answer to a meeting invitation.
+// Participants without a stored response are pending.
+type Response struct {
+    UserID            *primitive.ObjectID `bson:"userId,omitempty" json:"userId,omitempty"`
+    GuestEmail        string              `bson:"guestEmail,omitempty" json:"guestEmail,omitempty"`
+    Status            string              `bson:"status" json:"status"`
+    Comment           string              `bson:"comment,omitempty" json:"comment,omitempty"`
+    ProposedStartTime *time.Time          `bson:"proposedStartTime,omitempty" json:"proposedStartTime,omitempty"`
+    RespondedAt       time.Time           `bson:"respondedAt" json:"respondedAt"`
+}
+
+// Recurrence describes a recurring meeting series (RFC 5545 RRULE).
+// Occurrences edited individually are stored as separate meetings with
+// SeriesID and RecurrenceID set; cancelled occurrences are listed in ExDates.
+type Recurrence struct {
+    RRule    string      `bson:"rrule" json:"rrule"`
+    Timezone string      `bson:"timezone,omitempty" json:"timezone,omitempty"` // IANA name, defaults to UTC
+    ExDates  []time.Time `bson:"exDates,omitempty" json:"exDates,omitempty"`
 }
\ No newline at end of file
@@ -0,0 +1,58 @@
+package models
+
+import (
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Webhook delivery states
+const (
+    DeliveryPending = "pending"
+    DeliverySuccess = "success"
+    DeliveryFailed  = "failed" // gave up after repeated errors, or the webhook was disabled
+)
+
+// Webhook is an endpoint that receives meeting events as signed HTTP POSTs
+type Webhook struct {
+    ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+    URL         string             `bson:"url" json:"url"`
+    Description string             `bson:"description,omitempty" json:"description,omitempty"`
+    Events      []string           `bson:"events" json:"events"` // empty means all events
+    Secret      string             `bson:"secret" json:"-"`      // HMAC-SHA256 key, only shown on create
+    Active      bool               `bson:"active" json:"active"`
+    // ConsecutiveFailures counts failed attempts since the last success;
+    // the webhook is disabled when it reaches the limit
+    ConsecutiveFailures int                `bson:"consecutiveFailures" json:"consecutiveFailures"`
+    DisabledAt          *time.Time         `bson:"disabledAt,omitempty" json:"disabledAt,omitempty"`
+    DisabledReason      string             `bson:"disabledReason,omitempty" json:"disabledReason,omitempty"`
+    CreatedBy           primitive.ObjectID `bson:"createdBy" json:"createdBy"`
+    CreatedAt           time.Time          `bson:"createdAt" json:"createdAt"`
+    UpdatedAt           time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// WebhookDelivery is one event sent to one webhook, with every attempt
+type WebhookDelivery struct {
+    ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+    WebhookID     primitive.ObjectID  `bson:"webhookId" json:"webhookId"`
+    EventID       string              `bson:"eventId" json:"eventId"`
+    Event         string              `bson:"event" json:"event"`
+    Payload       string              `bson:"payload" json:"payload"`
+    Status        string              `bson:"status" json:"status"`
+    Attempts      []WebhookAttempt    `bson:"attempts" json:"attempts"`
+    NextAttemptAt time.Time           `bson:"nextAttemptAt" json:"nextAttemptAt"`
+    LeaseOwner    string              `bson:"leaseOwner,omitempty" json:"-"`
+    LeaseUntil    *time.Time          `bson:"leaseUntil,omitempty" json:"-"`
+    RedeliveryOf  *primitive.ObjectID `bson:"redeliveryOf,omitempty" json:"redeliveryOf,omitempty"`
+    DeliveredAt   *time.Time          `bson:"deliveredAt,omitempty" json:"deliveredAt,omitempty"`
+    CreatedAt     time.Time           `bson:"createdAt" json:"createdAt"`
+    UpdatedAt     time.Time           `bson:"updatedAt" json:"updatedAt"`
+}
+
+// WebhookAttempt is the outcome of one POST to the endpoint
+type WebhookAttempt struct {
+    At           time.Time `bson:"at" json:"at"`
+    ResponseCode int       `bson:"responseCode,omitempty" json:"responseCode,omitempty"`
+    Error        string    `bson:"error,omitempty" json:"error,omitempty"`
+    DurationMs   int64     `bson:"durationMs" json:"durationMs"`
+}
@@ -0,0 +1,49 @@
+package notify
+
+import (
+    "context"
+    "log"
+
+    "pbommo/models"
+    "pbommo/utils"
+)
+
+func init() {
+    Register(EmailChannel{})
+    Register(LogChannel{})
+}
+
+// EmailChannel sends email through utils.SendMailMessage
+type EmailChannel struct{}
+
+func (EmailChannel) Name() string { return "email" }
+
+func (EmailChannel) Accepts(user models.User) bool {
+    return user.Email != "" && user.NotificationPrefs().EmailNotifications
+}
+
+func (EmailChannel) Send(ctx context.Context, msg Message) error {
+    text := msg.Text
+    if msg.Link != "" && msg.HTML == "" {
+        text += "\n\n" + msg.Link + "\n"
+    }
+    return utils.SendMailMessage(utils.MailMessage{
+        To:          []string{msg.User.Email},
+        Subject:     msg.Subject,
+        Text:        text,
+        HTML:        msg.HTML,
+        Attachments: msg.Attachments,
+    })
+}
+
+// LogChannel only writes messages to the server log, for development
+type LogChannel struct{}
+
+func (LogChannel) Name() string { return "log" }
+
+func (LogChannel) Accepts(user models.User) bool { return true }
+
+func (LogChannel) Send(ctx context.Context, msg Message) error {
+    log.Printf("🔔 [%s] to %s: %s", msg.Kind, msg.User.Email, msg.Subject)
+    return nil
+}
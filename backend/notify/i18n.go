@@ -0,0 +1,118 @@
+package notify
+
+import (
+    "fmt"
+    "strings"
+    "time"
+)
+
+// Supported languages; DefaultLanguage is used for anything else
+const (
+    LangID          = "id"
+    LangEN          = "en"
+    DefaultLanguage = LangID
+)
+
+var translations = map[string]map[string]string{
+    LangID: {
+        "invite.subject":    "Undangan meeting: %s",
+        "invite.heading":    "Anda diundang ke meeting",
+        "invite.intro":      "%s mengundang Anda ke meeting berikut.",
+        "updated.subject":   "Meeting diperbarui: %s",
+        "updated.heading":   "Meeting diperbarui",
+        "updated.intro":     "%s mengubah detail meeting berikut.",
+        "cancelled.subject": "Meeting dibatalkan: %s",
+        "cancelled.heading": "Meeting dibatalkan",
+        "cancelled.intro":   "%s membatalkan meeting berikut.",
+        "greeting":          "Halo %s,",
+        "when":              "Waktu",
+        "duration":          "Durasi",
+        "minutes":           "%d menit",
+        "recurring":         "Berulang",
+        "organizer":         "Penyelenggara",
+        "description":       "Deskripsi",
+        "open":              "Lihat meeting",
+        "reason":            "Alasan",
+        "attachment":        "File kalender (.ics) terlampir untuk memperbarui kalender Anda.",
+        "preferences":       "Anda menerima email ini karena notifikasi email aktif. Ubah di halaman Settings.",
+        "mention.title":     "%s menyebut Anda di %s",
+        "rsvp.accepted":     "%s menerima undangan: %s",
+        "rsvp.declined":     "%s menolak undangan: %s",
+        "rsvp.tentative":    "%s mungkin hadir: %s",
+        "rsvp.pending":      "%s belum memastikan kehadiran: %s",
+        "emotion.consent":   "Emotion tracking aktif di %s. Setujui jika Anda ingin ikut dianalisis.",
+        "agenda.proposed":   "%s mengusulkan agenda \"%s\" untuk %s",
+        "minutes.subject":   "Notulen meeting: %s",
+        "minutes.published": "%s membagikan notulen %s",
+        "action.assigned":   "%s memberi Anda tugas: %s",
+        "action.overdue":    "Tugas melewati tenggat: %s",
+    },
+    LangEN: {
+        "invite.subject":    "Meeting invitation: %s",
+        "invite.heading":    "You are invited to a meeting",
+        "invite.intro":      "%s invited you to the following meeting.",
+        "updated.subject":   "Meeting updated: %s",
+        "updated.heading":   "Meeting updated",
+        "updated.intro":     "%s changed the details of the following meeting.",
+        "cancelled.subject": "Meeting cancelled: %s",
+        "cancelled.heading": "Meeting cancelled",
+        "cancelled.intro":   "%s cancelled the following meeting.",
+        "greeting":          "Hello %s,",
+        "when":              "When",
+        "duration":          "Duration",
+        "minutes":           "%d minutes",
+        "recurring":         "Repeats",
+        "organizer":         "Organizer",
+        "description":       "Description",
+        "open":              "View meeting",
+        "reason":            "Reason",
+        "attachment":        "A calendar file (.ics) is attached to update your calendar.",
+        "preferences":       "You receive this email because email notifications are on. Change this in Settings.",
+        "mention.title":     "%s mentioned you in %s",
+        "rsvp.accepted":     "%s accepted: %s",
+        "rsvp.declined":     "%s declined: %s",
+        "rsvp.tentative":    "%s might attend: %s",
+        "rsvp.pending":      "%s has not decided yet: %s",
+        "emotion.consent":   "Emotion tracking is on for %s. Opt in if you want to take part.",
+        "agenda.proposed":   "%s proposed \"%s\" for the agenda of %s",
+        "minutes.subject":   "Meeting minutes: %s",
+        "minutes.published": "%s shared the minutes of %s",
+        "action.assigned":   "%s assigned you an action item: %s",
+        "action.overdue":    "Action item overdue: %s",
+    },
+}
+
+var (
+    idWeekdays = []string{"Minggu", "Senin", "Selasa", "Rabu", "Kamis", "Jumat", "Sabtu"}
+    idMonths   = []string{"Januari", "Februari", "Maret", "April", "Mei", "Juni", "Juli", "Agustus", "September", "Oktober", "November", "Desember"}
+)
+
+// Language returns lang if it is supported, otherwise DefaultLanguage
+func Language(lang string) string {
+    lang = strings.ToLower(lang)
+    if _, ok := translations[lang]; ok {
+        return lang
+    }
+    return DefaultLanguage
+}
+
+// T returns the translation of key, formatted with args
+func T(lang, key string, args ...interface{}) string {
+    text, ok := translations[Language(lang)][key]
+    if !ok {
+        text = translations[DefaultLanguage][key]
+    }
+    if len(args) > 0 {
+        return fmt.Sprintf(text, args...)
+    }
+    return text
+}
+
+// FormatDateTime formats t with localized weekday and month names,
+// e.g. "Senin, 19 Oktober 2026 09:00 WIB"
+func FormatDateTime(lang string, t time.Time) string {
+    if Language(lang) == LangID {
+        return fmt.Sprintf("%s, %d %s %d %s", idWeekdays[t.Weekday()], t.Day(), idMonths[t.Month()-1], t.Year(), t.Format("15:04 MST"))
+    }
+    return t.Format("Monday, 2 January 2006 15:04 MST")
+}
@@ -0,0 +1,122 @@
+package notify
+
+import (
+    "context"
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+
+    "pbommo/config"
+    "pbommo/models"
+    "pbommo/realtime"
+)
+
+func init() {
+    Register(InAppChannel{})
+}
+
+// InAppChannel stores messages in the user's notification inbox
+type InAppChannel struct{}
+
+func (InAppChannel) Name() string { return "inapp" }
+
+func (InAppChannel) Accepts(user models.User) bool { return !user.ID.IsZero() }
+
+func (InAppChannel) Send(ctx context.Context, msg Message) error {
+    n := models.Notification{
+        UserID: msg.User.ID,
+        Type:   msg.Kind,
+        Title:  msg.Subject,
+        Body:   msg.Text,
+        Link:   msg.Link,
+    }
+    if msg.Meeting != nil {
+        id := msg.Meeting.ID
+        n.MeetingID = &id
+    }
+    return SaveInApp(ctx, n)
+}
+
+// SaveInApp adds notifications to their users' inboxes, pushes them to
+// connected clients and trims each affected inbox to
+// config.NotificationLimit entries
+func SaveInApp(ctx context.Context, notifications ...models.Notification) error {
+    if len(notifications) == 0 {
+        return nil
+    }
+    now := time.Now()
+    docs := make([]interface{}, 0, len(notifications))
+    users := map[primitive.ObjectID]bool{}
+    for _, n := range notifications {
+        n.ID = primitive.NewObjectID()
+        n.Read = false
+        n.ReadAt = nil
+        if n.CreatedAt.IsZero() {
+            n.CreatedAt = now
+        }
+        docs = append(docs, n)
+        users[n.UserID] = true
+    }
+    if _, err := config.NotificationCollectionRef.InsertMany(ctx, docs); err != nil {
+        return err
+    }
+    for _, doc := range docs {
+        n := doc.(models.Notification)
+        realtime.Publish(realtime.EventNotification, []primitive.ObjectID{n.UserID}, n.MeetingID, n)
+    }
+    for userID := range users {
+        if err := trimInbox(ctx, userID); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// trimInbox drops the user's notifications beyond the limit or older than
+// the retention period
+func trimInbox(ctx context.Context, userID primitive.ObjectID) error {
+    cursor, err := config.NotificationCollectionRef.Find(ctx,
+        bson.M{"userId": userID},
+        options.Find().
+            SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+            SetSkip(int64(config.NotificationLimit())).
+            SetProjection(bson.M{"_id": 1}))
+    if err != nil {
+        return err
+    }
+    var excess []struct {
+        ID primitive.ObjectID `bson:"_id"`
+    }
+    if err := cursor.All(ctx, &excess); err != nil {
+        return err
+    }
+
+    filter := bson.M{"userId": userID, "createdAt": bson.M{"$lt": time.Now().Add(-config.NotificationRetention())}}
+    if len(excess) > 0 {
+        ids := make([]primitive.ObjectID, 0, len(excess))
+        for _, e := range excess {
+            ids = append(ids, e.ID)
+        }
+        filter = bson.M{"userId": userID, "$or": []bson.M{
+            {"_id": bson.M{"$in": ids}},
+            {"createdAt": filter["createdAt"]},
+        }}
+    }
+    _, err = config.NotificationCollectionRef.DeleteMany(ctx, filter)
+    return err
+}
+
+// EnsureInAppIndexes creates the inbox indexes, including the TTL index
+// that expires notifications after the retention period
+func EnsureInAppIndexes(ctx context.Context) error {
+    _, err := config.NotificationCollectionRef.Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys: bson.D{{Key: "userId", Value: 1}, {Key: "read", Value: 1}, {Key: "createdAt", Value: -1}},
+    })
+    if err != nil {
+        return err
+    }
+    return config.EnsureTTLIndex(ctx, config.NotificationCollectionRef, "createdAt", config.NotificationRetention())
+}
@@ -0,0 +1,66 @@
+// Package notify delivers messages to users through pluggable channels.
+// Channels register themselves by name; the reminder scheduler and other
+// senders pick channels from configuration.
+package notify
+
+import (
+    "context"
+    "sort"
+    "sync"
+
+    "pbommo/models"
+    "pbommo/utils"
+)
+
+// Message is one notification for one user
+type Message struct {
+    Kind    string // e.g. "reminder"
+    User    models.User
+    Subject string
+    Text    string
+    HTML    string // optional, for channels that render rich text
+    Link    string
+    Meeting *models.Meeting
+    // Attachments are only delivered by the email channel
+    Attachments []utils.MailAttachment
+}
+
+// Channel sends messages over one medium (email, push, in-app, ...)
+type Channel interface {
+    Name() string
+    // Accepts reports whether the user wants messages on this channel
+    Accepts(user models.User) bool
+    Send(ctx context.Context, msg Message) error
+}
+
+var (
+    mu       sync.RWMutex
+    channels = map[string]Channel{}
+)
+
+// Register adds a channel, replacing any channel with the same name
+func Register(ch Channel) {
+    mu.Lock()
+    defer mu.Unlock()
+    channels[ch.Name()] = ch
+}
+
+// Lookup returns the channel registered under name
+func Lookup(name string) (Channel, bool) {
+    mu.RLock()
+    defer mu.RUnlock()
+    ch, ok := channels[name]
+    return ch, ok
+}
+
+// Names lists the registered channels
+func Names() []string {
+    mu.RLock()
+    defer mu.RUnlock()
+    names := make([]string, 0, len(channels))
+    for name := range channels {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
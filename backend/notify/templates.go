@@ -0,0 +1,86 @@
+package notify
+
+import (
+    "bytes"
+    "embed"
+    htmltemplate "html/template"
+    "strings"
+    texttemplate "text/template"
+    "time"
+)
+
+// Meeting email kinds
+const (
+    KindMeetingInvite    = "invite"
+    KindMeetingUpdated   = "updated"
+    KindMeetingCancelled = "cancelled"
+)
+
+//go:embed templates/*
+var templateFS embed.FS
+
+var (
+    textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/*.txt"))
+    htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/*.html"))
+)
+
+// MeetingEmail holds the details rendered into a meeting email
+type MeetingEmail struct {
+    Lang        string
+    Recipient   string
+    Organizer   string
+    Title       string
+    Description string
+    Start       time.Time // already in the recipient's timezone
+    Duration    int       // minutes
+    Recurrence  string    // RRULE, empty for single meetings
+    Reason      string    // why the meeting was cancelled, if given
+    Link        string
+}
+
+// meetingEmailView is the template data: the email with localized labels
+type meetingEmailView struct {
+    MeetingEmail
+    Subject, Heading, Greeting, Intro           string
+    When, Duration                              string
+    WhenLabel, DurationLabel, RecurrenceLabel   string
+    OrganizerLabel, DescriptionLabel, OpenLabel string
+    ReasonLabel                                 string
+    AttachmentNote, PreferencesNote             string
+    Cancelled                                   bool
+}
+
+// RenderMeetingEmail returns the localized subject, plain-text and HTML
+// bodies of a meeting invite, update or cancellation
+func RenderMeetingEmail(kind string, email MeetingEmail) (subject, text, html string, err error) {
+    lang := Language(email.Lang)
+    email.Lang = lang
+    view := meetingEmailView{
+        MeetingEmail:     email,
+        Subject:          T(lang, kind+".subject", email.Title),
+        Heading:          T(lang, kind+".heading"),
+        Greeting:         T(lang, "greeting", email.Recipient),
+        Intro:            T(lang, kind+".intro", email.Organizer),
+        When:             FormatDateTime(lang, email.Start),
+        Duration:         T(lang, "minutes", email.Duration),
+        WhenLabel:        T(lang, "when"),
+        DurationLabel:    T(lang, "duration"),
+        RecurrenceLabel:  T(lang, "recurring"),
+        OrganizerLabel:   T(lang, "organizer"),
+        DescriptionLabel: T(lang, "description"),
+        OpenLabel:        T(lang, "open"),
+        ReasonLabel:      T(lang, "reason"),
+        AttachmentNote:   T(lang, "attachment"),
+        PreferencesNote:  T(lang, "preferences"),
+        Cancelled:        kind == KindMeetingCancelled,
+    }
+
+    var textBuf, htmlBuf bytes.Buffer
+    if err := textTemplates.ExecuteTemplate(&textBuf, "meeting.txt", view); err != nil {
+        return "", "", "", err
+    }
+    if err := htmlTemplates.ExecuteTemplate(&htmlBuf, "meeting.html", view); err != nil {
+        return "", "", "", err
+    }
+    return view.Subject, strings.TrimSpace(textBuf.String()) + "\n", htmlBuf.String(), nil
+}
@@ -0,0 +1,33 @@
+package realtime
+
+import "context"
+
+// MemoryBroker delivers events within this process only; use it when a
+// single server instance runs
+type MemoryBroker struct {
+    events chan Event
+}
+
+func NewMemoryBroker() *MemoryBroker {
+    return &MemoryBroker{events: make(chan Event, 256)}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, ev Event) error {
+    select {
+    case b.events <- ev:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+func (b *MemoryBroker) Run(ctx context.Context, deliver func(Event)) error {
+    for {
+        select {
+        case ev := <-b.events:
+            deliver(ev)
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    }
+}
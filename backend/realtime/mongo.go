@@ -0,0 +1,122 @@
+package realtime
+
+import (
+    "context"
+    "errors"
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson"
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoBroker fans events out across instances through a capped collection.
+// Every instance inserts the events it publishes and tails the collection
+// for events from all instances. Old events fall off the end of the
+// collection on their own.
+type MongoBroker struct {
+    db   *mongo.Database
+    name string
+    size int64
+}
+
+// NewMongoBroker uses the capped collection name in db, creating it with
+// the given size in bytes if it does not exist
+func NewMongoBroker(db *mongo.Database, name string, size int64) *MongoBroker {
+    return &MongoBroker{db: db, name: name, size: size}
+}
+
+func (b *MongoBroker) Publish(ctx context.Context, ev Event) error {
+    _, err := b.db.Collection(b.name).InsertOne(ctx, ev)
+    return err
+}
+
+// Run tails the collection in natural (insertion) order. ObjectIDs from
+// different instances are not ordered, so the tail is resumed by skipping
+// past the last delivered event rather than filtering on _id.
+func (b *MongoBroker) Run(ctx context.Context, deliver func(Event)) error {
+    if err := b.ensureCollection(ctx); err != nil {
+        return err
+    }
+    coll := b.db.Collection(b.name)
+
+    // Start after the newest event; older ones were meant for earlier connections
+    var last Event
+    err := coll.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.M{"$natural": -1})).Decode(&last)
+    if err != nil && err != mongo.ErrNoDocuments {
+        return err
+    }
+
+    for {
+        cursor, err := coll.Find(ctx, bson.M{}, options.Find().
+            SetCursorType(options.TailableAwait).
+            SetMaxAwaitTime(time.Second))
+        if err != nil {
+            return err
+        }
+        if err := skipDelivered(ctx, cursor, last.ID, func(ev Event) {
+            last = ev
+            deliver(ev)
+        }); err != nil {
+            cursor.Close(context.Background())
+            return err
+        }
+        for cursor.Next(ctx) {
+            var ev Event
+            if err := cursor.Decode(&ev); err != nil {
+                continue
+            }
+            last = ev
+            deliver(ev)
+        }
+        err = cursor.Err()
+        cursor.Close(context.Background())
+        if ctx.Err() != nil {
+            return ctx.Err()
+        }
+        if err != nil {
+            return err
+        }
+
+        // A tailable cursor on an empty collection dies at once; wait for
+        // the first event
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-time.After(time.Second):
+        }
+    }
+}
+
+// skipDelivered advances a fresh cursor past the event with id lastID.
+// Events after it in the same batch go to deliver. When lastID is no longer
+// in the collection (it fell off the end), nothing currently stored is
+// delivered again.
+func skipDelivered(ctx context.Context, cursor *mongo.Cursor, lastID primitive.ObjectID, deliver func(Event)) error {
+    if lastID.IsZero() {
+        return nil
+    }
+    found := false
+    for cursor.TryNext(ctx) {
+        var ev Event
+        if err := cursor.Decode(&ev); err != nil {
+            continue
+        }
+        if found {
+            deliver(ev)
+        } else if ev.ID == lastID {
+            found = true
+        }
+    }
+    return cursor.Err()
+}
+
+func (b *MongoBroker) ensureCollection(ctx context.Context) error {
+    err := b.db.CreateCollection(ctx, b.name, options.CreateCollection().SetCapped(true).SetSizeInBytes(b.size))
+    var cmdErr mongo.CommandError
+    if errors.As(err, &cmdErr) && cmdErr.Name == "NamespaceExists" {
+        return nil
+    }
+    return err
+}
@@ -0,0 +1,267 @@
+// Package realtime pushes events to connected clients. Events are published
+// through a Broker, which fans them out to every server instance; each
+// instance then delivers them to its own connections of the target users.
+package realtime
+
+import (
+    "context"
+    "encoding/json"
+    "log"
+    "sync"
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Event types
+const (
+    EventMeetingCreated = "meeting.created"
+    EventMeetingUpdated = "meeting.updated"
+    EventMeetingDeleted = "meeting.deleted"
+    EventRSVP           = "rsvp"
+    EventNotification   = "notification"
+
+    // Internal events, broadcast to handlers only
+    EventEmotionSamples = "emotion.samples"
+    EventEmotionForget  = "emotion.forget"
+)
+
+const (
+    // connectionBuffer is how many events a connection may fall behind
+    // before it is dropped; the client reconnects and refetches
+    connectionBuffer = 64
+    // outboxSize is how many published events may wait for the broker
+    outboxSize = 1024
+)
+
+// Event is one message for a set of users
+type Event struct {
+    ID        primitive.ObjectID   `bson:"_id" json:"id"`
+    Type      string               `bson:"type" json:"type"`
+    Users     []primitive.ObjectID `bson:"users" json:"-"`
+    MeetingID *primitive.ObjectID  `bson:"meetingId,omitempty" json:"meetingId,omitempty"`
+    Data      json.RawMessage      `bson:"data,omitempty" json:"data,omitempty"`
+    CreatedAt time.Time            `bson:"createdAt" json:"createdAt"`
+}
+
+// Broker carries events between server instances
+type Broker interface {
+    Publish(ctx context.Context, ev Event) error
+    // Run delivers every published event, from any instance, to deliver
+    // until ctx is done
+    Run(ctx context.Context, deliver func(Event)) error
+}
+
+// Subscription selects the events a connection receives. Empty fields
+// match everything.
+type Subscription struct {
+    Types    map[string]bool
+    Meetings map[primitive.ObjectID]bool
+}
+
+func (s Subscription) matches(ev Event) bool {
+    if len(s.Types) > 0 && !s.Types[ev.Type] {
+        return false
+    }
+    if len(s.Meetings) > 0 && (ev.MeetingID == nil || !s.Meetings[*ev.MeetingID]) {
+        return false
+    }
+    return true
+}
+
+// Conn is one client connection of a user
+type Conn struct {
+    UserID primitive.ObjectID
+    Events <-chan Event
+    // Done is closed when the connection is dropped for falling behind
+    Done <-chan struct{}
+
+    sub    Subscription
+    events chan Event
+    done   chan struct{}
+    once   sync.Once
+}
+
+func (c *Conn) drop() {
+    c.once.Do(func() { close(c.done) })
+}
+
+// outbox hands published events to a broker one at a time, in the order
+// they were published
+type outbox struct {
+    broker Broker
+    queue  chan Event
+}
+
+func newOutbox(b Broker) *outbox {
+    o := &outbox{broker: b, queue: make(chan Event, outboxSize)}
+    go o.run()
+    return o
+}
+
+func (o *outbox) run() {
+    for ev := range o.queue {
+        ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        if err := o.broker.Publish(ctx, ev); err != nil {
+            log.Printf("Failed to publish %s event: %v", ev.Type, err)
+        }
+        cancel()
+    }
+}
+
+// enqueue never blocks the publisher; when the broker falls too far behind
+// the event is dropped and clients catch up on their next refetch
+func (o *outbox) enqueue(ev Event) {
+    select {
+    case o.queue <- ev:
+    default:
+        log.Printf("Realtime outbox full, dropping %s event", ev.Type)
+    }
+}
+
+var (
+    mu       sync.RWMutex
+    broker   Broker = NewMemoryBroker()
+    out             = newOutbox(broker)
+    conns           = map[primitive.ObjectID]map[*Conn]bool{}
+    handlers        = map[string][]func(Event){}
+)
+
+// SetBroker replaces the broker; call it before Start
+func SetBroker(b Broker) {
+    mu.Lock()
+    defer mu.Unlock()
+    broker = b
+    close(out.queue)
+    out = newOutbox(b)
+}
+
+// Start delivers events from the broker to local connections in the
+// background, restarting the broker if it fails
+func Start(ctx context.Context) {
+    mu.RLock()
+    b := broker
+    mu.RUnlock()
+
+    go func() {
+        for {
+            err := b.Run(ctx, deliver)
+            if ctx.Err() != nil {
+                return
+            }
+            log.Printf("Realtime broker stopped: %v, restarting", err)
+            select {
+            case <-ctx.Done():
+                return
+            case <-time.After(5 * time.Second):
+            }
+        }
+    }()
+}
+
+// Connect registers a connection for the user
+func Connect(userID primitive.ObjectID, sub Subscription) *Conn {
+    events := make(chan Event, connectionBuffer)
+    done := make(chan struct{})
+    c := &Conn{UserID: userID, Events: events, Done: done, sub: sub, events: events, done: done}
+
+    mu.Lock()
+    defer mu.Unlock()
+    if conns[userID] == nil {
+        conns[userID] = map[*Conn]bool{}
+    }
+    conns[userID][c] = true
+    return c
+}
+
+// Disconnect unregisters a connection
+func Disconnect(c *Conn) {
+    mu.Lock()
+    defer mu.Unlock()
+    delete(conns[c.UserID], c)
+    if len(conns[c.UserID]) == 0 {
+        delete(conns, c.UserID)
+    }
+    c.drop()
+}
+
+// Handle registers fn for events of the type on this instance. Handlers
+// see events published by any instance and serve server-side consumers
+// such as in-memory aggregates; they must not block.
+func Handle(eventType string, fn func(Event)) {
+    mu.Lock()
+    defer mu.Unlock()
+    handlers[eventType] = append(handlers[eventType], fn)
+}
+
+// Publish sends an event with data to the given users in the background.
+// Events reach the broker in the order they were published. Failures are
+// logged; clients recover by refetching on reconnect.
+func Publish(eventType string, users []primitive.ObjectID, meetingID *primitive.ObjectID, data interface{}) {
+    if len(users) == 0 {
+        return
+    }
+    publish(eventType, uniqueIDs(users), meetingID, data)
+}
+
+// Broadcast sends an event to the handlers on every instance, without
+// delivering it to any connection
+func Broadcast(eventType string, meetingID *primitive.ObjectID, data interface{}) {
+    publish(eventType, nil, meetingID, data)
+}
+
+func publish(eventType string, users []primitive.ObjectID, meetingID *primitive.ObjectID, data interface{}) {
+    ev := Event{
+        ID:        primitive.NewObjectID(),
+        Type:      eventType,
+        Users:     users,
+        MeetingID: meetingID,
+        CreatedAt: time.Now(),
+    }
+    if data != nil {
+        raw, err := json.Marshal(data)
+        if err != nil {
+            log.Printf("Failed to encode %s event: %v", eventType, err)
+            return
+        }
+        ev.Data = raw
+    }
+
+    mu.RLock()
+    defer mu.RUnlock()
+    out.enqueue(ev)
+}
+
+// deliver hands an event to the local handlers and matching connections
+// without blocking; a connection whose buffer is full is dropped
+func deliver(ev Event) {
+    mu.RLock()
+    defer mu.RUnlock()
+    for _, fn := range handlers[ev.Type] {
+        fn(ev)
+    }
+    for _, userID := range ev.Users {
+        for c := range conns[userID] {
+            if !c.sub.matches(ev) {
+                continue
+            }
+            select {
+            case c.events <- ev:
+            default:
+                c.drop()
+            }
+        }
+    }
+}
+
+func uniqueIDs(ids []primitive.ObjectID) []primitive.ObjectID {
+    seen := map[primitive.ObjectID]bool{}
+    var unique []primitive.ObjectID
+    for _, id := range ids {
+        if !id.IsZero() && !seen[id] {
+            seen[id] = true
+            unique = append(unique, id)
+        }
+    }
+    return unique
+}
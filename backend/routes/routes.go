@@ -16,23 +16,122 @@ func SetupRoutes(app *fiber.App) {
     // Auth routes (unprotected)
     app.Post("/register", controllers.Register)
     app.Post("/login", controllers.Login)
+    app.Post("/invite/accept", controllers.AcceptInvite)
+
+    // iCalendar subscription feed (secret token in URL)
+    app.Get("/calendar/:token/meetings.ics", controllers.CalendarFeed)
+
+    // External guest access to a meeting (secret token in URL)
+    app.Get("/guest/meetings/:token", controllers.GetGuestMeeting)
+    app.Post("/guest/meetings/:token/respond", controllers.RespondAsGuest)
+
+    // Realtime event stream (authenticates itself, token may be in ?token=)
+    app.Get("/events", controllers.StreamEvents)
+    app.Get("/meetings/:id/emotions/live", controllers.StreamLiveEmotions)
 
     // Get all users (unprotected for demo purposes)
     app.Get("/users", controllers.GetUsers)
 
+    // SCIM 2.0 provisioning (bearer SCIM_TOKEN)
+    scim := app.Group("/scim/v2", middleware.ScimAuth())
+    scim.Get("/Users", controllers.ScimListUsers)
+    scim.Post("/Users", controllers.ScimCreateUser)
+    scim.Get("/Users/:id", controllers.ScimGetUser)
+    scim.Put("/Users/:id", controllers.ScimReplaceUser)
+    scim.Patch("/Users/:id", controllers.ScimPatchUser)
+    scim.Delete("/Users/:id", controllers.ScimDeleteUser)
+    scim.Get("/Groups", controllers.ScimListGroups)
+    scim.Post("/Groups", controllers.ScimCreateGroup)
+    scim.Get("/Groups/:id", controllers.ScimGetGroup)
+    scim.Put("/Groups/:id", controllers.ScimReplaceGroup)
+    scim.Patch("/Groups/:id", controllers.ScimPatchGroup)
+    scim.Delete("/Groups/:id", controllers.ScimDeleteGroup)
+
     // API routes (protected)
     api := app.Group("/api")
     api.Use(middleware.Protected())
 
     // User routes
+    api.Post("/users/import", middleware.AdminOnly(), controllers.ImportUsers)
     api.Get("/users/:id", controllers.GetUserById)
     api.Put("/users/:id", controllers.UpdateUser)
     api.Post("/users/:id/profile-image", controllers.UploadProfileImage)
 
+    // Webhook routes (admin only)
+    api.Get("/webhooks", middleware.AdminOnly(), controllers.GetWebhooks)
+    api.Post("/webhooks", middleware.AdminOnly(), controllers.CreateWebhook)
+    api.Get("/webhooks/:id", middleware.AdminOnly(), controllers.GetWebhookById)
+    api.Put("/webhooks/:id", middleware.AdminOnly(), controllers.UpdateWebhook)
+    api.Delete("/webhooks/:id", middleware.AdminOnly(), controllers.DeleteWebhook)
+    api.Get("/webhooks/:id/deliveries", middleware.AdminOnly(), controllers.GetWebhookDeliveries)
+    api.Post("/webhooks/:id/deliveries/:deliveryId/redeliver", middleware.AdminOnly(), controllers.RedeliverWebhook)
+
+    // Notification inbox routes
+    api.Get("/notifications", controllers.GetNotifications)
+    api.Get("/notifications/unread-count", controllers.GetUnreadNotificationCount)
+    api.Put("/notifications/read-all", controllers.MarkAllNotificationsRead)
+    api.Put("/notifications/:id/read", controllers.MarkNotificationRead)
+
+    // Settings routes
+    api.Get("/settings/notifications", controllers.GetNotificationSettings)
+    api.Put("/settings/notifications", controllers.UpdateNotificationSettings)
+
+    // Calendar feed routes
+    api.Get("/calendar/feed", controllers.GetCalendarFeed)
+    api.Post("/calendar/feed/reset", controllers.ResetCalendarFeed)
+
+    // Working hours and out-of-office routes
+    api.Get("/availability", controllers.GetAvailability)
+    api.Put("/availability", controllers.UpdateAvailability)
+    api.Post("/availability/absences", controllers.AddAbsence)
+    api.Delete("/availability/absences/:absenceId", controllers.DeleteAbsence)
+    api.Get("/holiday-calendars", controllers.GetHolidayCalendars)
+
+    // Scheduling assistant routes
+    api.Post("/scheduling/freebusy", controllers.GetFreeBusy)
+    api.Post("/scheduling/suggest", controllers.SuggestSlots)
+
+    // Action item routes
+    api.Get("/action-items", controllers.GetActionItems)
+    api.Put("/action-items/:id", controllers.UpdateActionItem)
+    api.Delete("/action-items/:id", controllers.DeleteActionItem)
+
     // Meeting routes
     api.Post("/meetings", controllers.CreateMeeting)
     api.Get("/meetings", controllers.GetMeetings)
+    api.Get("/meetings/trash", controllers.GetMeetingTrash)
+    api.Post("/meetings/import", controllers.ImportMeetingsICS)
+    api.Post("/meetings/conflicts", controllers.CheckConflicts)
     api.Get("/meetings/:id", controllers.GetMeetingById)
     api.Put("/meetings/:id", controllers.UpdateMeeting)
     api.Delete("/meetings/:id", controllers.DeleteMeeting)
+    api.Get("/meetings/:id/ics", controllers.ExportMeetingICS)
+    api.Post("/meetings/:id/respond", controllers.RespondToMeeting)
+    api.Get("/meetings/:id/responses", controllers.GetMeetingResponses)
+    api.Put("/meetings/:id/occurrences", controllers.UpdateOccurrence)
+    api.Delete("/meetings/:id/occurrences", controllers.CancelOccurrence)
+    api.Post("/meetings/:id/start", controllers.StartMeeting)
+    api.Post("/meetings/:id/end", controllers.EndMeeting)
+    api.Post("/meetings/:id/cancel", controllers.CancelMeeting)
+    api.Post("/meetings/:id/restore", controllers.RestoreMeeting)
+    api.Get("/meetings/:id/agenda", controllers.GetAgenda)
+    api.Post("/meetings/:id/agenda", controllers.AddAgendaItem)
+    api.Put("/meetings/:id/agenda/order", controllers.ReorderAgenda)
+    api.Put("/meetings/:id/agenda/:itemId", controllers.UpdateAgendaItem)
+    api.Delete("/meetings/:id/agenda/:itemId", controllers.DeleteAgendaItem)
+    api.Post("/meetings/:id/agenda/:itemId/complete", controllers.CompleteAgendaItem)
+    api.Post("/meetings/:id/agenda/:itemId/accept", controllers.AcceptAgendaItem)
+    api.Get("/meetings/:id/notes", controllers.GetMeetingNotes)
+    api.Put("/meetings/:id/notes", controllers.SaveMeetingNotes)
+    api.Get("/meetings/:id/notes/revisions", controllers.GetNoteRevisions)
+    api.Get("/meetings/:id/notes/revisions/:version", controllers.GetNoteRevision)
+    api.Post("/meetings/:id/notes/publish", controllers.PublishMeetingMinutes)
+    api.Get("/meetings/:id/action-items", controllers.GetMeetingActionItems)
+    api.Post("/meetings/:id/action-items", controllers.CreateActionItem)
+    api.Post("/meetings/:id/emotions", controllers.IngestEmotionSamples)
+    api.Post("/meetings/:id/emotions/frames", controllers.ClassifyEmotionFrame)
+    api.Get("/meetings/:id/emotions/report", controllers.GetEmotionReport)
+    api.Get("/meetings/:id/emotions/consent", controllers.GetEmotionConsent)
+    api.Post("/meetings/:id/emotions/consent", controllers.GrantEmotionConsent)
+    api.Delete("/meetings/:id/emotions/consent", controllers.WithdrawEmotionConsent)
 }
\ No newline at end of file
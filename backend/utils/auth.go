@@ -1,6 +1,8 @@
 package utils
 
 import (
+    "crypto/rand"
+    "encoding/hex"
     "errors"
     "os"
     "strings"
@@ -64,6 +66,12 @@ func GetUserIDFromToken(c *fiber.Ctx) (primitive.ObjectID, error) {
         return primitive.NilObjectID, errors.New("invalid authorization header format")
     }
 
+    return ParseUserToken(tokenString)
+}
+
+// ParseUserToken validates a JWT and returns the user ID in it. Used where
+// the token cannot come in a header, e.g. EventSource connections.
+func ParseUserToken(tokenString string) (primitive.ObjectID, error) {
     // Get JWT secret
     secret := os.Getenv("JWT_SECRET")
     if secret == "" {
@@ -95,4 +103,13 @@ func GetUserIDFromToken(c *fiber.Ctx) (primitive.ObjectID, error) {
     }
 
     return primitive.NilObjectID, errors.New("invalid token")
-}
\ No newline at end of file
+}
+
+// GenerateRandomToken menghasilkan token acak hex dengan panjang n byte
+func GenerateRandomToken(n int) (string, error) {
+    b := make([]byte, n)
+    if _, err := rand.Read(b); err != nil {
+        return "", err
+    }
+    return hex.EncodeToString(b), nil
+}
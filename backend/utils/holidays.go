@@ -0,0 +1,209 @@
+package utils
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Holiday adalah satu hari libur dari kalender ICS. Date berupa tanggal
+// kalender (YYYY-MM-DD) yang berlaku di timezone masing-masing user.
+type Holiday struct {
+    Date string `json:"date"`
+    Name string `json:"name"`
+}
+
+// ErrHolidayCalendarNotFound dikembalikan untuk nama kalender yang tidak ada
+var ErrHolidayCalendarNotFound = errors.New("holiday calendar not found")
+
+// holidayEvent adalah VEVENT all-day yang sudah diparse
+type holidayEvent struct {
+    Start   time.Time // tengah malam UTC dari tanggal mulai
+    Days    int
+    Name    string
+    Rule    *RRule
+    ExDates []time.Time
+}
+
+type holidayCalendar struct {
+    modTime time.Time
+    events  []holidayEvent
+}
+
+var (
+    holidayMu    sync.Mutex
+    holidayCache = map[string]holidayCalendar{}
+)
+
+// HolidayCalendarDir mengembalikan folder file .ics hari libur (HOLIDAY_CALENDAR_DIR)
+func HolidayCalendarDir() string {
+    if dir := os.Getenv("HOLIDAY_CALENDAR_DIR"); dir != "" {
+        return dir
+    }
+    return "./holidays"
+}
+
+// ListHolidayCalendars mengembalikan nama kalender yang tersedia (nama file tanpa .ics)
+func ListHolidayCalendars() ([]string, error) {
+    entries, err := os.ReadDir(HolidayCalendarDir())
+    if err != nil {
+        if os.IsNotExist(err) {
+            return []string{}, nil
+        }
+        return nil, err
+    }
+    names := []string{}
+    for _, e := range entries {
+        if !e.IsDir() && strings.HasSuffix(strings.ToLower(e.Name()), ".ics") {
+            names = append(names, strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())))
+        }
+    }
+    sort.Strings(names)
+    return names, nil
+}
+
+// HolidayCalendarExists melaporkan apakah file kalender dengan nama tersebut ada
+func HolidayCalendarExists(name string) bool {
+    path, ok := holidayCalendarPath(name)
+    if !ok {
+        return false
+    }
+    info, err := os.Stat(path)
+    return err == nil && !info.IsDir()
+}
+
+// HolidaysBetween mengembalikan hari libur dari kalender name dengan tanggal
+// di antara tanggal kalender from dan to (inklusif). File diparse ulang
+// hanya jika berubah sejak dibaca terakhir.
+func HolidaysBetween(name string, from, to time.Time) ([]Holiday, error) {
+    events, err := loadHolidayCalendar(name)
+    if err != nil {
+        return nil, err
+    }
+
+    first := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+    last := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+
+    holidays := []Holiday{}
+    for _, e := range events {
+        starts := []time.Time{e.Start}
+        if e.Rule != nil {
+            starts = e.Rule.Between(e.Start, time.UTC, first.AddDate(0, 0, -e.Days), last.AddDate(0, 0, 1), e.ExDates)
+        }
+        for _, start := range starts {
+            for i := 0; i < e.Days; i++ {
+                day := start.AddDate(0, 0, i)
+                if !day.Before(first) && !day.After(last) {
+                    holidays = append(holidays, Holiday{Date: day.Format("2006-01-02"), Name: e.Name})
+                }
+            }
+        }
+    }
+    sort.Slice(holidays, func(i, j int) bool { return holidays[i].Date < holidays[j].Date })
+    return holidays, nil
+}
+
+// holidayCalendarPath menolak nama yang keluar dari folder kalender
+func holidayCalendarPath(name string) (string, bool) {
+    if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+        return "", false
+    }
+    return filepath.Join(HolidayCalendarDir(), name+".ics"), true
+}
+
+func loadHolidayCalendar(name string) ([]holidayEvent, error) {
+    path, ok := holidayCalendarPath(name)
+    if !ok {
+        return nil, ErrHolidayCalendarNotFound
+    }
+    info, err := os.Stat(path)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return nil, ErrHolidayCalendarNotFound
+        }
+        return nil, err
+    }
+
+    holidayMu.Lock()
+    defer holidayMu.Unlock()
+    if cached, ok := holidayCache[name]; ok && cached.modTime.Equal(info.ModTime()) {
+        return cached.events, nil
+    }
+
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    cal, err := ParseICalendar(f)
+    if err != nil {
+        return nil, err
+    }
+
+    var events []holidayEvent
+    for _, comp := range cal.Components {
+        if comp.Name != "VEVENT" {
+            continue
+        }
+        event, ok := parseHolidayEvent(comp)
+        if ok {
+            events = append(events, event)
+        }
+    }
+
+    holidayCache[name] = holidayCalendar{modTime: info.ModTime(), events: events}
+    return events, nil
+}
+
+// parseHolidayEvent membaca VEVENT sebagai rentang tanggal. Jam diabaikan:
+// event bertanggal-waktu dianggap libur pada tanggal mulainya.
+func parseHolidayEvent(comp *ICalComponent) (holidayEvent, bool) {
+    if strings.EqualFold(comp.Text("STATUS"), "CANCELLED") {
+        return holidayEvent{}, false
+    }
+    dtstart := comp.Get("DTSTART")
+    if dtstart == nil {
+        return holidayEvent{}, false
+    }
+    start, _, err := ParseICalDateTime(*dtstart)
+    if err != nil {
+        return holidayEvent{}, false
+    }
+    event := holidayEvent{
+        Start: time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC),
+        Days:  1,
+        Name:  comp.Text("SUMMARY"),
+    }
+
+    // DTEND all-day bersifat eksklusif
+    if dtend := comp.Get("DTEND"); dtend != nil {
+        if end, allDay, err := ParseICalDateTime(*dtend); err == nil && allDay {
+            end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+            if days := int(end.Sub(event.Start).Hours() / 24); days > 1 {
+                event.Days = days
+            }
+        }
+    }
+
+    if rrule := comp.Get("RRULE"); rrule != nil {
+        rule, err := ParseRRule(rrule.Value)
+        if err != nil {
+            return holidayEvent{}, false
+        }
+        event.Rule = rule
+        for _, p := range comp.GetAll("EXDATE") {
+            for _, v := range strings.Split(p.Value, ",") {
+                exProp := ICalProperty{Name: p.Name, Params: p.Params, Value: v}
+                if t, _, err := ParseICalDateTime(exProp); err == nil {
+                    event.ExDates = append(event.ExDates, time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC))
+                }
+            }
+        }
+    }
+    return event, true
+}
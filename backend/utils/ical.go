@@ -0,0 +1,231 @@
+package utils
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+)
+
+// ICalEvent adalah satu VEVENT dalam file iCalendar (RFC 5545)
+type ICalEvent struct {
+    UID          string
+    Sequence     int
+    Start        time.Time
+    End          time.Time
+    Timezone     string // IANA, dipakai untuk DTSTART/DTEND jika ada RRULE
+    Summary      string
+    Description  string
+    Organizer    string
+    Attendees    []string
+    RRule        string
+    ExDates      []time.Time
+    RecurrenceID *time.Time
+    Status       string // CONFIRMED atau CANCELLED
+    Created      time.Time
+    LastModified time.Time
+    URL          string
+}
+
+const icalUTCFormat = "20060102T150405Z"
+
+// BuildICalendar menghasilkan isi file .ics untuk daftar event.
+// method kosong berarti feed biasa (tanpa METHOD).
+func BuildICalendar(method string, events []ICalEvent) string {
+    var b strings.Builder
+    writeICalLine(&b, "BEGIN:VCALENDAR")
+    writeICalLine(&b, "VERSION:2.0")
+    writeICalLine(&b, "PRODID:-//PBO-MMO//Meeting Calendar//EN")
+    writeICalLine(&b, "CALSCALE:GREGORIAN")
+    if method != "" {
+        writeICalLine(&b, "METHOD:"+method)
+    }
+
+    // VTIMEZONE untuk setiap TZID yang direferensikan
+    timezones := map[string]time.Time{}
+    for _, e := range events {
+        if useTZID(e) {
+            if first, ok := timezones[e.Timezone]; !ok || e.Start.Before(first) {
+                timezones[e.Timezone] = e.Start
+            }
+        }
+    }
+    names := make([]string, 0, len(timezones))
+    for name := range timezones {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        writeVTimezone(&b, name, timezones[name])
+    }
+
+    now := time.Now().UTC()
+    for _, e := range events {
+        writeICalLine(&b, "BEGIN:VEVENT")
+        writeICalLine(&b, "UID:"+e.UID)
+        writeICalLine(&b, "DTSTAMP:"+now.Format(icalUTCFormat))
+        writeICalLine(&b, fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+        writeICalLine(&b, icalTimeProperty("DTSTART", e.Start, e))
+        writeICalLine(&b, icalTimeProperty("DTEND", e.End, e))
+        if e.RecurrenceID != nil {
+            writeICalLine(&b, icalTimeProperty("RECURRENCE-ID", *e.RecurrenceID, e))
+        }
+        if e.RRule != "" {
+            writeICalLine(&b, "RRULE:"+e.RRule)
+        }
+        for _, d := range e.ExDates {
+            writeICalLine(&b, icalTimeProperty("EXDATE", d, e))
+        }
+        writeICalLine(&b, "SUMMARY:"+EscapeICalText(e.Summary))
+        if e.Description != "" {
+            writeICalLine(&b, "DESCRIPTION:"+EscapeICalText(e.Description))
+        }
+        if e.Organizer != "" {
+            writeICalLine(&b, "ORGANIZER:mailto:"+e.Organizer)
+        }
+        for _, a := range e.Attendees {
+            writeICalLine(&b, "ATTENDEE;ROLE=REQ-PARTICIPANT:mailto:"+a)
+        }
+        if e.URL != "" {
+            writeICalLine(&b, "URL:"+e.URL)
+        }
+        status := e.Status
+        if status == "" {
+            status = "CONFIRMED"
+        }
+        writeICalLine(&b, "STATUS:"+status)
+        if !e.Created.IsZero() {
+            writeICalLine(&b, "CREATED:"+e.Created.UTC().Format(icalUTCFormat))
+        }
+        if !e.LastModified.IsZero() {
+            writeICalLine(&b, "LAST-MODIFIED:"+e.LastModified.UTC().Format(icalUTCFormat))
+        }
+        writeICalLine(&b, "END:VEVENT")
+    }
+
+    writeICalLine(&b, "END:VCALENDAR")
+    return b.String()
+}
+
+// EscapeICalText meng-escape nilai TEXT sesuai RFC 5545 3.3.11
+func EscapeICalText(s string) string {
+    r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+    return r.Replace(s)
+}
+
+// useTZID: event berulang dengan timezone non-UTC ditulis dalam waktu lokal
+// agar pengulangan mengikuti perubahan DST
+func useTZID(e ICalEvent) bool {
+    if e.RRule == "" || e.Timezone == "" || e.Timezone == "UTC" {
+        return false
+    }
+    _, err := time.LoadLocation(e.Timezone)
+    return err == nil
+}
+
+func icalTimeProperty(name string, t time.Time, e ICalEvent) string {
+    if useTZID(e) {
+        loc, _ := time.LoadLocation(e.Timezone)
+        return fmt.Sprintf("%s;TZID=%s:%s", name, e.Timezone, t.In(loc).Format("20060102T150405"))
+    }
+    return name + ":" + t.UTC().Format(icalUTCFormat)
+}
+
+// writeICalLine menulis satu content line dengan folding 75 oktet
+func writeICalLine(b *strings.Builder, line string) {
+    for len(line) > 75 {
+        cut := 75
+        // Jangan memotong di tengah karakter UTF-8
+        for cut > 0 && line[cut]&0xC0 == 0x80 {
+            cut--
+        }
+        b.WriteString(line[:cut])
+        b.WriteString("\r\n ")
+        line = line[cut:]
+    }
+    b.WriteString(line)
+    b.WriteString("\r\n")
+}
+
+// writeVTimezone menulis definisi VTIMEZONE dari database tz Go, berdasarkan
+// transisi pada tahun event pertama
+func writeVTimezone(b *strings.Builder, name string, ref time.Time) {
+    loc, err := time.LoadLocation(name)
+    if err != nil {
+        return
+    }
+
+    year := ref.In(loc).Year()
+    start := time.Date(year, 1, 1, 0, 0, 0, 0, loc)
+    end := start.AddDate(1, 0, 0)
+
+    // Cari transisi offset dalam tahun tersebut
+    type transition struct {
+        at       time.Time
+        from, to int
+        abbr     string
+    }
+    var transitions []transition
+    _, prevOffset := start.Zone()
+    for day := start; day.Before(end); day = day.Add(24 * time.Hour) {
+        next := day.Add(24 * time.Hour)
+        _, offset := next.Zone()
+        if offset == prevOffset {
+            continue
+        }
+        // Binary search ke menit transisi
+        lo, hi := day, next
+        for hi.Sub(lo) > time.Minute {
+            mid := lo.Add(hi.Sub(lo) / 2)
+            if _, o := mid.Zone(); o == prevOffset {
+                lo = mid
+            } else {
+                hi = mid
+            }
+        }
+        abbr, _ := hi.Zone()
+        transitions = append(transitions, transition{at: hi.Truncate(time.Minute), from: prevOffset, to: offset, abbr: abbr})
+        prevOffset = offset
+    }
+
+    writeICalLine(b, "BEGIN:VTIMEZONE")
+    writeICalLine(b, "TZID:"+name)
+    if len(transitions) == 0 {
+        abbr, offset := start.Zone()
+        writeICalLine(b, "BEGIN:STANDARD")
+        writeICalLine(b, "DTSTART:19700101T000000")
+        writeICalLine(b, "TZOFFSETFROM:"+formatICalOffset(offset))
+        writeICalLine(b, "TZOFFSETTO:"+formatICalOffset(offset))
+        writeICalLine(b, "TZNAME:"+abbr)
+        writeICalLine(b, "END:STANDARD")
+    }
+    for _, t := range transitions {
+        component := "STANDARD"
+        if t.to > t.from {
+            component = "DAYLIGHT"
+        }
+        // Waktu lokal transisi dinyatakan dalam offset sebelum transisi
+        local := t.at.UTC().Add(time.Duration(t.from) * time.Second)
+        n := (local.Day()-1)/7 + 1
+        if local.Day()+7 > daysInMonth(local.Year(), local.Month()) {
+            n = -1
+        }
+        writeICalLine(b, "BEGIN:"+component)
+        writeICalLine(b, "DTSTART:"+local.Format("20060102T150405"))
+        writeICalLine(b, fmt.Sprintf("RRULE:FREQ=YEARLY;BYMONTH=%d;BYDAY=%d%s", int(local.Month()), n, weekdayNames[local.Weekday()]))
+        writeICalLine(b, "TZOFFSETFROM:"+formatICalOffset(t.from))
+        writeICalLine(b, "TZOFFSETTO:"+formatICalOffset(t.to))
+        writeICalLine(b, "TZNAME:"+t.abbr)
+        writeICalLine(b, "END:"+component)
+    }
+    writeICalLine(b, "END:VTIMEZONE")
+}
+
+func formatICalOffset(seconds int) string {
+    sign := "+"
+    if seconds < 0 {
+        sign = "-"
+        seconds = -seconds
+    }
+    return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, (seconds%3600)/60)
+}
@@ -0,0 +1,259 @@
+package utils
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "io"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// ICalProperty adalah satu content line, mis. DTSTART;TZID=Asia/Jakarta:20260105T090000
+type ICalProperty struct {
+    Name   string
+    Params map[string]string
+    Value  string
+}
+
+// ICalComponent adalah blok BEGIN/END (VCALENDAR, VEVENT, ...)
+type ICalComponent struct {
+    Name       string
+    Properties []ICalProperty
+    Components []*ICalComponent
+}
+
+// maxICalLines membatasi ukuran file yang diparse
+const maxICalLines = 200000
+
+// ParseICalendar mem-parse stream iCalendar dan mengembalikan komponen VCALENDAR
+func ParseICalendar(r io.Reader) (*ICalComponent, error) {
+    lines, err := unfoldICalLines(r)
+    if err != nil {
+        return nil, err
+    }
+
+    var root *ICalComponent
+    var stack []*ICalComponent
+    for _, line := range lines {
+        prop, err := parseICalProperty(line)
+        if err != nil {
+            return nil, err
+        }
+
+        switch prop.Name {
+        case "BEGIN":
+            comp := &ICalComponent{Name: strings.ToUpper(prop.Value)}
+            if len(stack) > 0 {
+                parent := stack[len(stack)-1]
+                parent.Components = append(parent.Components, comp)
+            } else if root == nil {
+                root = comp
+            }
+            stack = append(stack, comp)
+        case "END":
+            if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(prop.Value) {
+                return nil, fmt.Errorf("unexpected END:%s", prop.Value)
+            }
+            stack = stack[:len(stack)-1]
+        default:
+            if len(stack) == 0 {
+                return nil, errors.New("property outside of a component")
+            }
+            current := stack[len(stack)-1]
+            current.Properties = append(current.Properties, prop)
+        }
+    }
+
+    if root == nil || root.Name != "VCALENDAR" {
+        return nil, errors.New("file is not an iCalendar (missing VCALENDAR)")
+    }
+    if len(stack) > 0 {
+        return nil, fmt.Errorf("unterminated component %s", stack[len(stack)-1].Name)
+    }
+    return root, nil
+}
+
+// Get mengembalikan property pertama dengan nama tersebut, atau nil
+func (c *ICalComponent) Get(name string) *ICalProperty {
+    for i := range c.Properties {
+        if c.Properties[i].Name == name {
+            return &c.Properties[i]
+        }
+    }
+    return nil
+}
+
+// GetAll mengembalikan semua property dengan nama tersebut
+func (c *ICalComponent) GetAll(name string) []ICalProperty {
+    var props []ICalProperty
+    for _, p := range c.Properties {
+        if p.Name == name {
+            props = append(props, p)
+        }
+    }
+    return props
+}
+
+// Text mengembalikan nilai TEXT yang sudah di-unescape, atau "" jika tidak ada
+func (c *ICalComponent) Text(name string) string {
+    if p := c.Get(name); p != nil {
+        return UnescapeICalText(p.Value)
+    }
+    return ""
+}
+
+// UnescapeICalText kebalikan dari EscapeICalText
+func UnescapeICalText(s string) string {
+    var b strings.Builder
+    for i := 0; i < len(s); i++ {
+        if s[i] == '\\' && i+1 < len(s) {
+            i++
+            switch s[i] {
+            case 'n', 'N':
+                b.WriteByte('\n')
+            default:
+                b.WriteByte(s[i])
+            }
+            continue
+        }
+        b.WriteByte(s[i])
+    }
+    return b.String()
+}
+
+// ParseICalDateTime mem-parse property DATE/DATE-TIME dengan TZID.
+// allDay bernilai true untuk VALUE=DATE. TZID yang tidak dikenal dianggap UTC.
+func ParseICalDateTime(p ICalProperty) (t time.Time, allDay bool, err error) {
+    loc := time.UTC
+    if tzid := p.Params["TZID"]; tzid != "" {
+        if l, err := time.LoadLocation(tzid); err == nil {
+            loc = l
+        }
+    }
+
+    value := strings.TrimSpace(p.Value)
+    // EXDATE boleh berisi beberapa nilai; pemanggil memecahnya terlebih dahulu
+    switch {
+    case strings.HasSuffix(value, "Z"):
+        t, err = time.Parse("20060102T150405Z", value)
+    case len(value) == 8:
+        t, err = time.ParseInLocation("20060102", value, loc)
+        allDay = true
+    default:
+        t, err = time.ParseInLocation("20060102T150405", value, loc)
+    }
+    if err != nil {
+        return time.Time{}, false, fmt.Errorf("invalid date-time %q", value)
+    }
+    return t, allDay, nil
+}
+
+// ParseICalDuration mem-parse DURATION iCalendar, mis. PT1H30M atau P1D
+func ParseICalDuration(s string) (time.Duration, error) {
+    s = strings.TrimSpace(strings.ToUpper(s))
+    neg := strings.HasPrefix(s, "-")
+    s = strings.TrimLeft(s, "+-")
+    if !strings.HasPrefix(s, "P") {
+        return 0, fmt.Errorf("invalid duration %q", s)
+    }
+    s = s[1:]
+
+    var total time.Duration
+    inTime := false
+    num := ""
+    for _, ch := range s {
+        switch {
+        case ch >= '0' && ch <= '9':
+            num += string(ch)
+        case ch == 'T':
+            inTime = true
+        default:
+            n, err := strconv.Atoi(num)
+            if err != nil {
+                return 0, fmt.Errorf("invalid duration %q", s)
+            }
+            num = ""
+            switch {
+            case ch == 'W':
+                total += time.Duration(n) * 7 * 24 * time.Hour
+            case ch == 'D':
+                total += time.Duration(n) * 24 * time.Hour
+            case ch == 'H' && inTime:
+                total += time.Duration(n) * time.Hour
+            case ch == 'M' && inTime:
+                total += time.Duration(n) * time.Minute
+            case ch == 'S' && inTime:
+                total += time.Duration(n) * time.Second
+            default:
+                return 0, fmt.Errorf("invalid duration %q", s)
+            }
+        }
+    }
+    if num != "" {
+        return 0, fmt.Errorf("invalid duration %q", s)
+    }
+    if neg {
+        total = -total
+    }
+    return total, nil
+}
+
+// unfoldICalLines membaca baris dan menggabungkan baris lanjutan (diawali spasi/tab)
+func unfoldICalLines(r io.Reader) ([]string, error) {
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+    var lines []string
+    for scanner.Scan() {
+        line := strings.TrimRight(scanner.Text(), "\r")
+        if line == "" {
+            continue
+        }
+        if (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+            lines[len(lines)-1] += line[1:]
+            continue
+        }
+        if len(lines) >= maxICalLines {
+            return nil, errors.New("iCalendar file is too large")
+        }
+        lines = append(lines, line)
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+    return lines, nil
+}
+
+// parseICalProperty memecah "NAME;PARAM=VAL;PARAM2=\"a:b\":value"
+func parseICalProperty(line string) (ICalProperty, error) {
+    prop := ICalProperty{Params: map[string]string{}}
+
+    // Cari ':' pertama di luar tanda kutip
+    inQuotes := false
+    colon := -1
+    for i, ch := range line {
+        if ch == '"' {
+            inQuotes = !inQuotes
+        } else if ch == ':' && !inQuotes {
+            colon = i
+            break
+        }
+    }
+    if colon < 0 {
+        return prop, fmt.Errorf("invalid content line %q", line)
+    }
+
+    head, value := line[:colon], line[colon+1:]
+    parts := strings.Split(head, ";")
+    prop.Name = strings.ToUpper(parts[0])
+    for _, param := range parts[1:] {
+        kv := strings.SplitN(param, "=", 2)
+        if len(kv) == 2 {
+            prop.Params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+        }
+    }
+    prop.Value = value
+    return prop, nil
+}
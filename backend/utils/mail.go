@@ -0,0 +1,185 @@
+package utils
+
+import (
+    "bytes"
+    "encoding/base64"
+    "fmt"
+    "io"
+    "log"
+    "mime"
+    "mime/multipart"
+    "mime/quotedprintable"
+    "net/smtp"
+    "net/textproto"
+    "os"
+    "strings"
+    "time"
+)
+
+// MailAttachment adalah file yang dilampirkan ke email
+type MailAttachment struct {
+    Filename    string
+    ContentType string // mis. text/calendar; charset=utf-8; method=REQUEST
+    Data        []byte
+}
+
+// MailMessage adalah email dengan isi teks, HTML opsional dan lampiran
+type MailMessage struct {
+    To          []string
+    Subject     string
+    Text        string
+    HTML        string
+    Attachments []MailAttachment
+}
+
+// SendMail mengirim email teks biasa melalui SMTP yang dikonfigurasi lewat env.
+// Jika SMTP_HOST tidak diset, email hanya dicatat ke log.
+func SendMail(to []string, subject, body string) error {
+    return SendMailMessage(MailMessage{To: to, Subject: subject, Text: body})
+}
+
+// SendMailMessage mengirim email MIME melalui SMTP (SMTP_HOST, SMTP_PORT,
+// SMTP_USER, SMTP_PASSWORD, SMTP_FROM). Untuk pengujian lokal arahkan ke
+// SMTP sink seperti MailHog (SMTP_HOST=localhost SMTP_PORT=1025).
+func SendMailMessage(msg MailMessage) error {
+    host := os.Getenv("SMTP_HOST")
+    if host == "" {
+        log.Printf("📧 SMTP_HOST not set, skipping email to %s: %s", strings.Join(msg.To, ", "), msg.Subject)
+        return nil
+    }
+
+    port := os.Getenv("SMTP_PORT")
+    if port == "" {
+        port = "25"
+    }
+
+    from := os.Getenv("SMTP_FROM")
+    if from == "" {
+        from = "no-reply@pbommo.local"
+    }
+
+    var auth smtp.Auth
+    if username := os.Getenv("SMTP_USER"); username != "" {
+        auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+    }
+
+    data, err := BuildMailMessage(from, msg)
+    if err != nil {
+        return err
+    }
+    return smtp.SendMail(host+":"+port, auth, from, msg.To, data)
+}
+
+// BuildMailMessage menyusun email lengkap dengan header. Teks dan HTML
+// menjadi multipart/alternative, lampiran membungkusnya dalam multipart/mixed.
+func BuildMailMessage(from string, msg MailMessage) ([]byte, error) {
+    var buf bytes.Buffer
+    fmt.Fprintf(&buf, "From: %s\r\n", from)
+    fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(msg.To, ", "))
+    fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+    fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+    if id, err := GenerateRandomToken(16); err == nil {
+        fmt.Fprintf(&buf, "Message-ID: <%s@pbommo>\r\n", id)
+    }
+    buf.WriteString("MIME-Version: 1.0\r\n")
+
+    if msg.HTML == "" && len(msg.Attachments) == 0 {
+        buf.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+        buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+        if err := writeQuotedPrintable(&buf, msg.Text); err != nil {
+            return nil, err
+        }
+        return buf.Bytes(), nil
+    }
+
+    mixed := multipart.NewWriter(&buf)
+    fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", mixed.Boundary())
+
+    // Isi email: teks saja, atau teks + HTML sebagai alternatif
+    if msg.HTML == "" {
+        if err := writeTextPart(mixed, "text/plain; charset=\"utf-8\"", msg.Text); err != nil {
+            return nil, err
+        }
+    } else {
+        var alt bytes.Buffer
+        altWriter := multipart.NewWriter(&alt)
+        if err := writeTextPart(altWriter, "text/plain; charset=\"utf-8\"", msg.Text); err != nil {
+            return nil, err
+        }
+        if err := writeTextPart(altWriter, "text/html; charset=\"utf-8\"", msg.HTML); err != nil {
+            return nil, err
+        }
+        if err := altWriter.Close(); err != nil {
+            return nil, err
+        }
+        part, err := mixed.CreatePart(textproto.MIMEHeader{
+            "Content-Type": {fmt.Sprintf("multipart/alternative; boundary=%q", altWriter.Boundary())},
+        })
+        if err != nil {
+            return nil, err
+        }
+        if _, err := part.Write(alt.Bytes()); err != nil {
+            return nil, err
+        }
+    }
+
+    for _, a := range msg.Attachments {
+        part, err := mixed.CreatePart(textproto.MIMEHeader{
+            "Content-Type":              {a.ContentType},
+            "Content-Transfer-Encoding": {"base64"},
+            "Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+        })
+        if err != nil {
+            return nil, err
+        }
+        if err := writeBase64(part, a.Data); err != nil {
+            return nil, err
+        }
+    }
+
+    if err := mixed.Close(); err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
+
+func writeTextPart(w *multipart.Writer, contentType, body string) error {
+    part, err := w.CreatePart(textproto.MIMEHeader{
+        "Content-Type":              {contentType},
+        "Content-Transfer-Encoding": {"quoted-printable"},
+    })
+    if err != nil {
+        return err
+    }
+    return writeQuotedPrintable(part, body)
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+    qp := quotedprintable.NewWriter(w)
+    if _, err := qp.Write([]byte(body)); err != nil {
+        return err
+    }
+    return qp.Close()
+}
+
+// writeBase64 menulis data base64 dengan baris maksimal 76 karakter (RFC 2045)
+func writeBase64(w io.Writer, data []byte) error {
+    encoded := base64.StdEncoding.EncodeToString(data)
+    for len(encoded) > 76 {
+        if _, err := w.Write([]byte(encoded[:76] + "\r\n")); err != nil {
+            return err
+        }
+        encoded = encoded[76:]
+    }
+    _, err := w.Write([]byte(encoded + "\r\n"))
+    return err
+}
+
+// FrontendURL mengembalikan base URL frontend untuk link di email
+func FrontendURL() string {
+    url := os.Getenv("FRONTEND_URL")
+    if url == "" {
+        url = "http://localhost:5173"
+    }
+    return strings.TrimRight(url, "/")
+}
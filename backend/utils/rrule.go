@@ -0,0 +1,480 @@
+package utils
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// RRule adalah subset dari recurrence rule RFC 5545 yang didukung:
+// FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, COUNT, UNTIL,
+// BYDAY (dengan ordinal untuk MONTHLY, mis. 1MO atau -1FR), BYMONTHDAY dan BYMONTH.
+type RRule struct {
+    Freq       string
+    Interval   int
+    Count      int
+    Until      time.Time
+    ByDay      []WeekdayNum
+    ByMonthDay []int
+    ByMonth    []int
+}
+
+// WeekdayNum adalah satu nilai BYDAY, N = 0 berarti setiap hari tersebut
+type WeekdayNum struct {
+    Weekday time.Weekday
+    N       int
+}
+
+const (
+    // maxRecurrencePeriods membatasi iterasi agar rule tanpa akhir tetap aman
+    maxRecurrencePeriods = 50000
+    // maxRecurrenceYears membatasi rentang waktu yang diiterasi, sehingga rule
+    // yang tidak pernah cocok (mis. 31 Februari) berhenti lebih awal
+    maxRecurrenceYears = 100
+)
+
+var weekdayCodes = map[string]time.Weekday{
+    "SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+    "TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+var weekdayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// ParseRRule mem-parse string RRULE, dengan atau tanpa prefix "RRULE:"
+func ParseRRule(s string) (*RRule, error) {
+    s = strings.TrimSpace(s)
+    s = strings.TrimPrefix(strings.TrimPrefix(s, "RRULE:"), "rrule:")
+    if s == "" {
+        return nil, errors.New("rrule is empty")
+    }
+
+    r := &RRule{Interval: 1}
+    for _, part := range strings.Split(s, ";") {
+        if part == "" {
+            continue
+        }
+        kv := strings.SplitN(part, "=", 2)
+        if len(kv) != 2 {
+            return nil, fmt.Errorf("invalid rrule part %q", part)
+        }
+        key, value := strings.ToUpper(strings.TrimSpace(kv[0])), strings.ToUpper(strings.TrimSpace(kv[1]))
+
+        switch key {
+        case "FREQ":
+            switch value {
+            case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+                r.Freq = value
+            default:
+                return nil, fmt.Errorf("unsupported FREQ %q", value)
+            }
+        case "INTERVAL":
+            n, err := strconv.Atoi(value)
+            if err != nil || n < 1 {
+                return nil, fmt.Errorf("invalid INTERVAL %q", value)
+            }
+            r.Interval = n
+        case "COUNT":
+            n, err := strconv.Atoi(value)
+            if err != nil || n < 1 {
+                return nil, fmt.Errorf("invalid COUNT %q", value)
+            }
+            r.Count = n
+        case "UNTIL":
+            t, err := parseICalTime(value)
+            if err != nil {
+                return nil, fmt.Errorf("invalid UNTIL %q", value)
+            }
+            r.Until = t
+        case "BYDAY":
+            for _, v := range strings.Split(value, ",") {
+                if len(v) < 2 {
+                    return nil, fmt.Errorf("invalid BYDAY %q", v)
+                }
+                wd, ok := weekdayCodes[v[len(v)-2:]]
+                if !ok {
+                    return nil, fmt.Errorf("invalid BYDAY %q", v)
+                }
+                n := 0
+                if prefix := v[:len(v)-2]; prefix != "" {
+                    var err error
+                    n, err = strconv.Atoi(prefix)
+                    if err != nil || n == 0 || n < -5 || n > 5 {
+                        return nil, fmt.Errorf("invalid BYDAY %q", v)
+                    }
+                }
+                r.ByDay = append(r.ByDay, WeekdayNum{Weekday: wd, N: n})
+            }
+        case "BYMONTHDAY":
+            for _, v := range strings.Split(value, ",") {
+                n, err := strconv.Atoi(v)
+                if err != nil || n == 0 || n < -31 || n > 31 {
+                    return nil, fmt.Errorf("invalid BYMONTHDAY %q", v)
+                }
+                r.ByMonthDay = append(r.ByMonthDay, n)
+            }
+        case "BYMONTH":
+            for _, v := range strings.Split(value, ",") {
+                n, err := strconv.Atoi(v)
+                if err != nil || n < 1 || n > 12 {
+                    return nil, fmt.Errorf("invalid BYMONTH %q", v)
+                }
+                r.ByMonth = append(r.ByMonth, n)
+            }
+        case "WKST":
+            // Minggu selalu dimulai hari Senin
+        default:
+            return nil, fmt.Errorf("unsupported rrule part %q", key)
+        }
+    }
+
+    if r.Freq == "" {
+        return nil, errors.New("rrule requires FREQ")
+    }
+    if r.Count > 0 && !r.Until.IsZero() {
+        return nil, errors.New("rrule cannot contain both COUNT and UNTIL")
+    }
+    for _, d := range r.ByDay {
+        if d.N != 0 && r.Freq != "MONTHLY" {
+            return nil, errors.New("ordinal BYDAY is only supported with FREQ=MONTHLY")
+        }
+    }
+    // Kombinasi yang tidak dievaluasi ditolak daripada diabaikan diam-diam
+    if r.Freq == "YEARLY" && (len(r.ByDay) > 0 || len(r.ByMonthDay) > 0) {
+        return nil, errors.New("BYDAY and BYMONTHDAY are not supported with FREQ=YEARLY")
+    }
+    if r.Freq == "WEEKLY" && len(r.ByMonthDay) > 0 {
+        return nil, errors.New("BYMONTHDAY is not supported with FREQ=WEEKLY")
+    }
+    return r, nil
+}
+
+// String menghasilkan bentuk kanonik rule (tanpa prefix "RRULE:")
+func (r *RRule) String() string {
+    parts := []string{"FREQ=" + r.Freq}
+    if r.Interval > 1 {
+        parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+    }
+    if r.Count > 0 {
+        parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+    }
+    if !r.Until.IsZero() {
+        parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+    }
+    if len(r.ByDay) > 0 {
+        days := make([]string, len(r.ByDay))
+        for i, d := range r.ByDay {
+            days[i] = weekdayNames[d.Weekday]
+            if d.N != 0 {
+                days[i] = strconv.Itoa(d.N) + days[i]
+            }
+        }
+        parts = append(parts, "BYDAY="+strings.Join(days, ","))
+    }
+    if len(r.ByMonthDay) > 0 {
+        parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+    }
+    if len(r.ByMonth) > 0 {
+        parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+    }
+    return strings.Join(parts, ";")
+}
+
+// Between mengembalikan semua kejadian dengan waktu mulai di [from, to),
+// kecuali yang ada di exDates. Aturan dievaluasi dalam timezone loc.
+func (r *RRule) Between(dtstart time.Time, loc *time.Location, from, to time.Time, exDates []time.Time) []time.Time {
+    var result []time.Time
+    r.iterate(dtstart, loc, to, func(t time.Time) bool {
+        if !t.Before(to) {
+            return false
+        }
+        if !t.Before(from) && !containsTime(exDates, t) {
+            result = append(result, t)
+        }
+        return true
+    })
+    return result
+}
+
+// OccursAt melaporkan apakah t adalah salah satu kejadian dari rule
+func (r *RRule) OccursAt(dtstart time.Time, loc *time.Location, t time.Time) bool {
+    found := false
+    r.iterate(dtstart, loc, t, func(occ time.Time) bool {
+        if occ.Equal(t) {
+            found = true
+        }
+        return occ.Before(t)
+    })
+    return found
+}
+
+// CountBefore menghitung jumlah kejadian (termasuk yang di-exclude) sebelum t
+func (r *RRule) CountBefore(dtstart time.Time, loc *time.Location, t time.Time) int {
+    n := 0
+    r.iterate(dtstart, loc, t, func(occ time.Time) bool {
+        if !occ.Before(t) {
+            return false
+        }
+        n++
+        return true
+    })
+    return n
+}
+
+// Last mengembalikan kejadian terakhir dari rule yang berakhir (COUNT/UNTIL).
+// ok bernilai false untuk rule tanpa akhir.
+func (r *RRule) Last(dtstart time.Time, loc *time.Location) (last time.Time, ok bool) {
+    if r.Count == 0 && r.Until.IsZero() {
+        return time.Time{}, false
+    }
+    r.iterate(dtstart, loc, time.Time{}, func(occ time.Time) bool {
+        last = occ
+        return true
+    })
+    return last, !last.IsZero()
+}
+
+// iterate memanggil fn untuk setiap kejadian secara berurutan sampai fn
+// mengembalikan false, rule berakhir, atau periode dimulai setelah horizon
+// (zero berarti tanpa horizon). DTSTART selalu kejadian pertama.
+func (r *RRule) iterate(dtstart time.Time, loc *time.Location, horizon time.Time, fn func(time.Time) bool) {
+    if loc == nil {
+        loc = time.UTC
+    }
+    start := dtstart.In(loc)
+    emitted := 0
+
+    limit := start.AddDate(maxRecurrenceYears, 0, 0)
+    if !r.Until.IsZero() && r.Until.Before(limit) {
+        limit = r.Until
+    }
+    if !horizon.IsZero() && horizon.Before(limit) {
+        limit = horizon
+    }
+
+    emit := func(t time.Time) bool {
+        if !r.Until.IsZero() && t.After(r.Until) {
+            return false
+        }
+        if r.Count > 0 && emitted >= r.Count {
+            return false
+        }
+        emitted++
+        return fn(t.UTC())
+    }
+
+    if !emit(start) {
+        return
+    }
+
+    for period := 0; period < maxRecurrencePeriods; period++ {
+        if r.periodStart(start, loc, period).After(limit) {
+            return
+        }
+        for _, t := range r.candidates(start, loc, period) {
+            if !t.After(start) {
+                continue
+            }
+            if !emit(t) {
+                return
+            }
+        }
+    }
+}
+
+// periodStart adalah awal periode ke-n sejak dtstart; semua kandidat periode
+// tersebut jatuh pada atau setelahnya
+func (r *RRule) periodStart(start time.Time, loc *time.Location, n int) time.Time {
+    switch r.Freq {
+    case "WEEKLY":
+        base := start.AddDate(0, 0, 7*n*r.Interval)
+        offset := (int(base.Weekday()) + 6) % 7
+        monday := base.AddDate(0, 0, -offset)
+        return time.Date(monday.Year(), monday.Month(), monday.Day(), 0, 0, 0, 0, loc)
+    case "MONTHLY":
+        return time.Date(start.Year(), start.Month()+time.Month(n*r.Interval), 1, 0, 0, 0, 0, loc)
+    case "YEARLY":
+        return time.Date(start.Year()+n*r.Interval, 1, 1, 0, 0, 0, 0, loc)
+    }
+    day := start.AddDate(0, 0, n*r.Interval)
+    return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+}
+
+// candidates menghasilkan kejadian (terurut) untuk periode ke-n sejak dtstart
+func (r *RRule) candidates(start time.Time, loc *time.Location, n int) []time.Time {
+    h, m, s := start.Clock()
+    at := func(y int, mo time.Month, d int) time.Time {
+        return time.Date(y, mo, d, h, m, s, 0, loc)
+    }
+
+    var out []time.Time
+    switch r.Freq {
+    case "DAILY":
+        day := start.AddDate(0, 0, n*r.Interval)
+        if r.matchesWeekday(day.Weekday()) && r.matchesMonthDay(day) {
+            out = append(out, at(day.Year(), day.Month(), day.Day()))
+        }
+
+    case "WEEKLY":
+        base := start.AddDate(0, 0, 7*n*r.Interval)
+        if len(r.ByDay) == 0 {
+            out = append(out, at(base.Year(), base.Month(), base.Day()))
+            break
+        }
+        // Senin dari minggu tersebut
+        offset := (int(base.Weekday()) + 6) % 7
+        monday := base.AddDate(0, 0, -offset)
+        for i := 0; i < 7; i++ {
+            day := monday.AddDate(0, 0, i)
+            if r.matchesWeekday(day.Weekday()) {
+                out = append(out, at(day.Year(), day.Month(), day.Day()))
+            }
+        }
+
+    case "MONTHLY":
+        first := time.Date(start.Year(), start.Month()+time.Month(n*r.Interval), 1, 0, 0, 0, 0, loc)
+        daysIn := daysInMonth(first.Year(), first.Month())
+        switch {
+        case len(r.ByMonthDay) > 0:
+            for _, d := range r.ByMonthDay {
+                if d < 0 {
+                    d = daysIn + d + 1
+                }
+                if d >= 1 && d <= daysIn && r.matchesWeekday(time.Date(first.Year(), first.Month(), d, 0, 0, 0, 0, loc).Weekday()) {
+                    out = append(out, at(first.Year(), first.Month(), d))
+                }
+            }
+        case len(r.ByDay) > 0:
+            for _, wd := range r.ByDay {
+                var days []int
+                for d := 1; d <= daysIn; d++ {
+                    if time.Date(first.Year(), first.Month(), d, 0, 0, 0, 0, loc).Weekday() == wd.Weekday {
+                        days = append(days, d)
+                    }
+                }
+                switch {
+                case wd.N == 0:
+                    for _, d := range days {
+                        out = append(out, at(first.Year(), first.Month(), d))
+                    }
+                case wd.N > 0 && wd.N <= len(days):
+                    out = append(out, at(first.Year(), first.Month(), days[wd.N-1]))
+                case wd.N < 0 && -wd.N <= len(days):
+                    out = append(out, at(first.Year(), first.Month(), days[len(days)+wd.N]))
+                }
+            }
+        default:
+            // Bulan tanpa tanggal tersebut dilewati (RFC 5545)
+            if start.Day() <= daysIn {
+                out = append(out, at(first.Year(), first.Month(), start.Day()))
+            }
+        }
+
+    case "YEARLY":
+        year := start.Year() + n*r.Interval
+        months := r.ByMonth
+        if len(months) == 0 {
+            months = []int{int(start.Month())}
+        }
+        for _, mo := range months {
+            if start.Day() <= daysInMonth(year, time.Month(mo)) {
+                out = append(out, at(year, time.Month(mo), start.Day()))
+            }
+        }
+        sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+        return out
+    }
+
+    filtered := out[:0]
+    for _, t := range out {
+        if r.matchesMonth(t.Month()) {
+            filtered = append(filtered, t)
+        }
+    }
+    sort.Slice(filtered, func(i, j int) bool { return filtered[i].Before(filtered[j]) })
+    return dedupeTimes(filtered)
+}
+
+func (r *RRule) matchesWeekday(wd time.Weekday) bool {
+    if len(r.ByDay) == 0 || r.Freq == "MONTHLY" && len(r.ByMonthDay) == 0 {
+        return true
+    }
+    for _, d := range r.ByDay {
+        if d.Weekday == wd {
+            return true
+        }
+    }
+    return false
+}
+
+func (r *RRule) matchesMonthDay(t time.Time) bool {
+    if len(r.ByMonthDay) == 0 {
+        return true
+    }
+    daysIn := daysInMonth(t.Year(), t.Month())
+    for _, d := range r.ByMonthDay {
+        if d == t.Day() || d < 0 && daysIn+d+1 == t.Day() {
+            return true
+        }
+    }
+    return false
+}
+
+func (r *RRule) matchesMonth(m time.Month) bool {
+    if len(r.ByMonth) == 0 {
+        return true
+    }
+    for _, mo := range r.ByMonth {
+        if time.Month(mo) == m {
+            return true
+        }
+    }
+    return false
+}
+
+// parseICalTime mem-parse nilai DATE atau DATE-TIME iCalendar (UTC atau floating)
+func parseICalTime(value string) (time.Time, error) {
+    for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+        if t, err := time.Parse(layout, value); err == nil {
+            if layout == "20060102" {
+                // UNTIL berupa tanggal mencakup seluruh hari tersebut
+                t = t.Add(24*time.Hour - time.Second)
+            }
+            return t, nil
+        }
+    }
+    return time.Time{}, fmt.Errorf("invalid iCalendar time %q", value)
+}
+
+func daysInMonth(year int, month time.Month) int {
+    return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func containsTime(list []time.Time, t time.Time) bool {
+    for _, v := range list {
+        if v.Equal(t) {
+            return true
+        }
+    }
+    return false
+}
+
+func dedupeTimes(sorted []time.Time) []time.Time {
+    out := sorted[:0]
+    for i, t := range sorted {
+        if i == 0 || !t.Equal(sorted[i-1]) {
+            out = append(out, t)
+        }
+    }
+    return out
+}
+
+func joinInts(values []int) string {
+    s := make([]string, len(values))
+    for i, v := range values {
+        s[i] = strconv.Itoa(v)
+    }
+    return strings.Join(s, ",")
+}
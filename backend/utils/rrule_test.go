@@ -0,0 +1,196 @@
+package utils
+
+import (
+    "testing"
+    "time"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+    t.Helper()
+    loc, err := time.LoadLocation(name)
+    if err != nil {
+        t.Skipf("timezone %s unavailable: %v", name, err)
+    }
+    return loc
+}
+
+func utc(value string) time.Time {
+    t, err := time.Parse(time.RFC3339, value)
+    if err != nil {
+        panic(err)
+    }
+    return t
+}
+
+func TestRRuleBetween(t *testing.T) {
+    tests := []struct {
+        name    string
+        rule    string
+        tz      string
+        dtstart string
+        exDates []string
+        want    []string
+    }{
+        {
+            name:    "daily count",
+            rule:    "FREQ=DAILY;COUNT=3",
+            dtstart: "2026-01-05T09:00:00Z",
+            want:    []string{"2026-01-05T09:00:00Z", "2026-01-06T09:00:00Z", "2026-01-07T09:00:00Z"},
+        },
+        {
+            name:    "daily interval until",
+            rule:    "FREQ=DAILY;INTERVAL=2;UNTIL=20260111T090000Z",
+            dtstart: "2026-01-05T09:00:00Z",
+            want:    []string{"2026-01-05T09:00:00Z", "2026-01-07T09:00:00Z", "2026-01-09T09:00:00Z", "2026-01-11T09:00:00Z"},
+        },
+        {
+            name:    "weekly byday count",
+            rule:    "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4",
+            dtstart: "2026-01-05T09:00:00Z",
+            want:    []string{"2026-01-05T09:00:00Z", "2026-01-07T09:00:00Z", "2026-01-12T09:00:00Z", "2026-01-14T09:00:00Z"},
+        },
+        {
+            name:    "weekly exdate still counts",
+            rule:    "FREQ=WEEKLY;COUNT=3",
+            dtstart: "2026-01-05T09:00:00Z",
+            exDates: []string{"2026-01-12T09:00:00Z"},
+            want:    []string{"2026-01-05T09:00:00Z", "2026-01-19T09:00:00Z"},
+        },
+        {
+            name:    "weekly until",
+            rule:    "FREQ=WEEKLY;INTERVAL=2;UNTIL=20260202T090000Z",
+            dtstart: "2026-01-05T09:00:00Z",
+            want:    []string{"2026-01-05T09:00:00Z", "2026-01-19T09:00:00Z", "2026-02-02T09:00:00Z"},
+        },
+        {
+            name:    "monthly skips short months",
+            rule:    "FREQ=MONTHLY;BYMONTHDAY=31;COUNT=3",
+            dtstart: "2026-01-31T09:00:00Z",
+            want:    []string{"2026-01-31T09:00:00Z", "2026-03-31T09:00:00Z", "2026-05-31T09:00:00Z"},
+        },
+        {
+            name:    "monthly last friday",
+            rule:    "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+            dtstart: "2026-01-30T09:00:00Z",
+            want:    []string{"2026-01-30T09:00:00Z", "2026-02-27T09:00:00Z", "2026-03-27T09:00:00Z"},
+        },
+        {
+            name:    "monthly exdate and until",
+            rule:    "FREQ=MONTHLY;UNTIL=20260415T000000Z",
+            dtstart: "2026-01-15T09:00:00Z",
+            exDates: []string{"2026-02-15T09:00:00Z"},
+            want:    []string{"2026-01-15T09:00:00Z", "2026-03-15T09:00:00Z"},
+        },
+        {
+            name:    "yearly count",
+            rule:    "FREQ=YEARLY;COUNT=3",
+            dtstart: "2026-06-01T09:00:00Z",
+            want:    []string{"2026-06-01T09:00:00Z", "2027-06-01T09:00:00Z", "2028-06-01T09:00:00Z"},
+        },
+        {
+            name:    "yearly bymonth until",
+            rule:    "FREQ=YEARLY;BYMONTH=3,9;UNTIL=20270401T000000Z",
+            dtstart: "2026-03-10T09:00:00Z",
+            want:    []string{"2026-03-10T09:00:00Z", "2026-09-10T09:00:00Z", "2027-03-10T09:00:00Z"},
+        },
+        {
+            // Amsterdam moves to summer time on 29 March 2026: the local
+            // time stays 09:00, so the UTC time moves an hour earlier
+            name:    "weekly across dst start",
+            rule:    "FREQ=WEEKLY;COUNT=3",
+            tz:      "Europe/Amsterdam",
+            dtstart: "2026-03-22T08:00:00Z",
+            want:    []string{"2026-03-22T08:00:00Z", "2026-03-29T07:00:00Z", "2026-04-05T07:00:00Z"},
+        },
+        {
+            // New York moves back to standard time on 1 November 2026
+            name:    "daily across dst end",
+            rule:    "FREQ=DAILY;COUNT=3",
+            tz:      "America/New_York",
+            dtstart: "2026-10-31T13:00:00Z",
+            want:    []string{"2026-10-31T13:00:00Z", "2026-11-01T14:00:00Z", "2026-11-02T14:00:00Z"},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rule, err := ParseRRule(tt.rule)
+            if err != nil {
+                t.Fatalf("ParseRRule(%q): %v", tt.rule, err)
+            }
+            loc := time.UTC
+            if tt.tz != "" {
+                loc = mustLocation(t, tt.tz)
+            }
+            var exDates []time.Time
+            for _, ex := range tt.exDates {
+                exDates = append(exDates, utc(ex))
+            }
+
+            dtstart := utc(tt.dtstart)
+            got := rule.Between(dtstart, loc, dtstart, dtstart.AddDate(5, 0, 0), exDates)
+            if len(got) != len(tt.want) {
+                t.Fatalf("got %d occurrences %v, want %v", len(got), got, tt.want)
+            }
+            for i, want := range tt.want {
+                if !got[i].Equal(utc(want)) {
+                    t.Errorf("occurrence %d = %s, want %s", i, got[i].Format(time.RFC3339), want)
+                }
+            }
+        })
+    }
+}
+
+func TestRRuleBetweenWindow(t *testing.T) {
+    rule, err := ParseRRule("FREQ=DAILY")
+    if err != nil {
+        t.Fatal(err)
+    }
+    dtstart := utc("2026-01-01T09:00:00Z")
+    got := rule.Between(dtstart, time.UTC, utc("2026-01-10T00:00:00Z"), utc("2026-01-12T09:00:00Z"), nil)
+    want := []string{"2026-01-10T09:00:00Z", "2026-01-11T09:00:00Z"}
+    if len(got) != len(want) {
+        t.Fatalf("got %v, want %v", got, want)
+    }
+    for i := range want {
+        if !got[i].Equal(utc(want[i])) {
+            t.Errorf("occurrence %d = %s, want %s", i, got[i].Format(time.RFC3339), want[i])
+        }
+    }
+}
+
+func TestRRuleOccursAtAndCountBefore(t *testing.T) {
+    loc := mustLocation(t, "Europe/Amsterdam")
+    rule, err := ParseRRule("FREQ=WEEKLY;BYDAY=TU,TH;COUNT=6")
+    if err != nil {
+        t.Fatal(err)
+    }
+    // Tuesday 24 March 2026, 10:00 in Amsterdam (still winter time)
+    dtstart := utc("2026-03-24T09:00:00Z")
+
+    if !rule.OccursAt(dtstart, loc, utc("2026-03-31T08:00:00Z")) {
+        t.Error("Tuesday after the DST change, 10:00 local, should occur")
+    }
+    if rule.OccursAt(dtstart, loc, utc("2026-03-31T09:00:00Z")) {
+        t.Error("11:00 local should not occur")
+    }
+    if rule.OccursAt(dtstart, loc, utc("2026-04-14T08:00:00Z")) {
+        t.Error("seventh occurrence is past COUNT")
+    }
+    // 24, 26 and 31 March; the occurrence at t itself is not counted
+    if n := rule.CountBefore(dtstart, loc, utc("2026-04-02T08:00:00Z")); n != 3 {
+        t.Errorf("CountBefore = %d, want 3", n)
+    }
+    last, ok := rule.Last(dtstart, loc)
+    if !ok || !last.Equal(utc("2026-04-09T08:00:00Z")) {
+        t.Errorf("Last = %s, %v; want 2026-04-09T08:00:00Z", last.Format(time.RFC3339), ok)
+    }
+}
+
+func TestParseRRuleInvalid(t *testing.T) {
+    for _, rule := range []string{"", "FREQ=HOURLY", "FREQ=DAILY;COUNT=x", "FREQ=WEEKLY;BYDAY=XX", "COUNT=3"} {
+        if _, err := ParseRRule(rule); err == nil {
+            t.Errorf("ParseRRule(%q) should fail", rule)
+        }
+    }
+}
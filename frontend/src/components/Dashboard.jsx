@@ -2,14 +2,45 @@ import { useState, useEffect, useContext } from 'react';
 import { Link } from 'react-router-dom';
 import { AuthContext } from '../context/AuthContext';
 import Sidebar from './Sidebar';
+import { getNotifications, markAllNotificationsRead } from '../services/notificationService';
+import { subscribeToEvents } from '../services/realtimeService';
 import '../styles/Dashboard.css';
 
 function Dashboard() {
     const { user } = useContext(AuthContext);
     const [upcomingMeetings, setUpcomingMeetings] = useState([]);
     const [teamMembers, setTeamMembers] = useState([]);
+    const [notifications, setNotifications] = useState([]);
+    const [unreadCount, setUnreadCount] = useState(0);
     const [isLoading, setIsLoading] = useState(true);
 
+    useEffect(() => {
+        getNotifications({ limit: 5 })
+            .then(data => {
+                setNotifications(data.notifications);
+                setUnreadCount(data.unreadCount);
+            })
+            .catch(() => {});
+
+        // New notifications arrive without polling
+        return subscribeToEvents({
+            notification: (event) => {
+                setNotifications(prev => [event.data, ...prev].slice(0, 5));
+                setUnreadCount(prev => prev + 1);
+            },
+        }, { types: ['notification'] });
+    }, []);
+
+    const handleMarkAllRead = async () => {
+        try {
+            await markAllNotificationsRead();
+            setNotifications(prev => prev.map(n => ({ ...n, read: true })));
+            setUnreadCount(0);
+        } catch (error) {
+            console.error("Error marking notifications as read:", error);
+        }
+    };
+
     useEffect(() => {
         // Fungsi untuk mengambil data meeting dan anggota tim
         const fetchDashboardData = async () => {
@@ -143,6 +174,34 @@ function Dashboard() {
                         </div>
                     </div>
 
+                    {/* Recent Activity Card */}
+                    <div className="dashboard-card activity">
+                        <div className="card-header">
+                            <h3>Recent Activity{unreadCount > 0 && ` (${unreadCount})`}</h3>
+                            {unreadCount > 0 && (
+                                <button className="mark-read-button" onClick={handleMarkAllRead}>
+                                    Mark all as read
+                                </button>
+                            )}
+                        </div>
+                        <div className="card-content">
+                            {notifications.length > 0 ? (
+                                <div className="activity-list">
+                                    {notifications.map(notification => (
+                                        <div key={notification.id} className={`activity-item ${notification.read ? '' : 'unread'}`}>
+                                            <div className="activity-title">{notification.title}</div>
+                                            <div className="activity-time">{notification.body}</div>
+                                        </div>
+                                    ))}
+                                </div>
+                            ) : (
+                                <div className="empty-state">
+                                    <p>No recent activity</p>
+                                </div>
+                            )}
+                        </div>
+                    </div>
+
                     {/* Quick Stats Card */}
                     <div className="dashboard-card stats">
                         <div className="card-header">
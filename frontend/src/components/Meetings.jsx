@@ -26,7 +26,7 @@ function Meetings() {
                 date: '2025-07-22',
                 time: '09:00',
                 participants: ['John Doe', 'Alice Smith', 'Bob Johnson'],
-                status: 'upcoming'
+                status: 'scheduled'
             },
             {
                 id: 2,
@@ -47,7 +47,7 @@ function Meetings() {
             id: meetings.length + 1,
             ...newMeeting,
             participants: newMeeting.participants.split(',').map(p => p.trim()),
-            status: 'upcoming'
+            status: 'scheduled'
         };
         setMeetings([...meetings, meeting]);
         setNewMeeting({ title: '', description: '', date: '', time: '', participants: [] });
@@ -86,7 +86,7 @@ function Meetings() {
                                 <i className="fas fa-calendar-alt"></i>
                             </div>
                             <div className="stat-info">
-                                <h3>{meetings.filter(m => m.status === 'upcoming').length}</h3>
+                                <h3>{meetings.filter(m => m.status === 'scheduled' || m.status === 'live').length}</h3>
                                 <p>Upcoming Meetings</p>
                             </div>
                         </div>
@@ -1,5 +1,6 @@
-import { useState, useContext } from 'react';
+import { useState, useContext, useEffect } from 'react';
 import { AuthContext } from '../context/AuthContext';
+import { getNotificationSettings, updateNotificationSettings } from '../services/userService';
 import Sidebar from './Sidebar';
 import '../styles/Settings.css';
 
@@ -37,11 +38,21 @@ function Settings() {
         console.log('Changing password');
     };
 
+    useEffect(() => {
+        getNotificationSettings()
+            .then(settings => setNotifications(settings))
+            .catch(() => {});
+    }, []);
+
     const handleNotificationChange = (setting) => {
-        setNotifications(prev => ({
-            ...prev,
-            [setting]: !prev[setting]
-        }));
+        const updated = { ...notifications, [setting]: !notifications[setting] };
+        if (updated.defaultOffsets) {
+            delete updated.reminderOffsets;
+        }
+        setNotifications(updated);
+        updateNotificationSettings(updated)
+            .then(settings => setNotifications(settings))
+            .catch(() => setNotifications(notifications));
     };
 
     return (
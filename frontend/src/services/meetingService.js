@@ -30,8 +30,9 @@ export const createMeeting = async (meetingData) => {
     }
 };
 
-// Get all meetings for a user
-export const getMeetings = async (userId, date) => {
+// Get all meetings for a user, optionally only those in the given
+// statuses (scheduled, live, completed, cancelled)
+export const getMeetings = async (userId, date, statuses) => {
     try {
         let url = '/api/meetings';
         const params = {};
@@ -43,6 +44,10 @@ export const getMeetings = async (userId, date) => {
         if (date) {
             params.date = formatDate(date);
         }
+
+        if (statuses && statuses.length > 0) {
+            params.status = statuses.join(',');
+        }
         
         const response = await api.get(url, { params });
         return response.data;
@@ -74,10 +79,12 @@ export const updateMeeting = async (id, meetingData) => {
     }
 };
 
-// Delete a meeting
-export const deleteMeeting = async (id) => {
+// Delete a meeting, or one occurrence of a recurring meeting; it stays in
+// the organizer's trash until purged
+export const deleteMeeting = async (id, occurrenceStart) => {
     try {
-        const response = await api.delete(`/api/meetings/${id}`);
+        const params = occurrenceStart ? { start: new Date(occurrenceStart).toISOString() } : {};
+        const response = await api.delete(`/api/meetings/${id}`, { params });
         return response.data;
     } catch (error) {
         console.error(`Error deleting meeting ${id}:`, error);
@@ -85,6 +92,308 @@ export const deleteMeeting = async (id) => {
     }
 };
 
+// Change the status of a meeting (organizer only). action is 'start',
+// 'end' or 'cancel'; occurrences of a recurring meeting need their start.
+const changeMeetingStatus = async (id, action, occurrenceStart) => {
+    try {
+        const params = occurrenceStart ? { start: new Date(occurrenceStart).toISOString() } : {};
+        const response = await api.post(`/api/meetings/${id}/${action}`, null, { params });
+        return response.data;
+    } catch (error) {
+        console.error(`Error changing status of meeting ${id}:`, error);
+        throw error;
+    }
+};
+
+export const startMeeting = (id, occurrenceStart) => changeMeetingStatus(id, 'start', occurrenceStart);
+
+export const endMeeting = (id, occurrenceStart) => changeMeetingStatus(id, 'end', occurrenceStart);
+
+export const cancelMeeting = (id, occurrenceStart) => changeMeetingStatus(id, 'cancel', occurrenceStart);
+
+// Get the cancelled and deleted meetings the current user can still restore
+export const getMeetingTrash = async () => {
+    try {
+        const response = await api.get('/api/meetings/trash');
+        return response.data;
+    } catch (error) {
+        console.error('Error fetching meeting trash:', error);
+        throw error;
+    }
+};
+
+// Restore a cancelled or deleted meeting from the trash
+export const restoreMeeting = async (id, force = false) => {
+    try {
+        const response = await api.post(`/api/meetings/${id}/restore`, null, { params: force ? { force: true } : {} });
+        return response.data;
+    } catch (error) {
+        console.error(`Error restoring meeting ${id}:`, error);
+        throw error;
+    }
+};
+
+// Get the agenda of a meeting with the minutes it takes up
+export const getAgenda = async (id) => {
+    try {
+        const response = await api.get(`/api/meetings/${id}/agenda`);
+        return response.data;
+    } catch (error) {
+        console.error(`Error fetching agenda of meeting ${id}:`, error);
+        throw error;
+    }
+};
+
+// Add an agenda item ({ title, ownerId, minutes, notes }); participants
+// propose items instead when the organizer allows it
+export const addAgendaItem = async (id, item) => {
+    try {
+        const response = await api.post(`/api/meetings/${id}/agenda`, item);
+        return response.data;
+    } catch (error) {
+        console.error(`Error adding agenda item to meeting ${id}:`, error);
+        throw error;
+    }
+};
+
+// Update the given fields of an agenda item
+export const updateAgendaItem = async (id, itemId, changes) => {
+    try {
+        const response = await api.put(`/api/meetings/${id}/agenda/${itemId}`, changes);
+        return response.data;
+    } catch (error) {
+        console.error(`Error updating agenda item ${itemId}:`, error);
+        throw error;
+    }
+};
+
+// Delete an agenda item, or withdraw an own proposal
+export const deleteAgendaItem = async (id, itemId) => {
+    try {
+        const response = await api.delete(`/api/meetings/${id}/agenda/${itemId}`);
+        return response.data;
+    } catch (error) {
+        console.error(`Error deleting agenda item ${itemId}:`, error);
+        throw error;
+    }
+};
+
+// Reorder the agenda; itemIds must list every item
+export const reorderAgenda = async (id, itemIds) => {
+    try {
+        const response = await api.put(`/api/meetings/${id}/agenda/order`, { itemIds });
+        return response.data;
+    } catch (error) {
+        console.error(`Error reordering agenda of meeting ${id}:`, error);
+        throw error;
+    }
+};
+
+// Mark an agenda item done, or open again with done = false
+export const completeAgendaItem = async (id, itemId, done = true) => {
+    try {
+        const response = await api.post(`/api/meetings/${id}/agenda/${itemId}/complete`, { done });
+        return response.data;
+    } catch (error) {
+        console.error(`Error completing agenda item ${itemId}:`, error);
+        throw error;
+    }
+};
+
+// Accept a proposed agenda item (organizer only)
+export const acceptAgendaItem = async (id, itemId) => {
+    try {
+        const response = await api.post(`/api/meetings/${id}/agenda/${itemId}/accept`);
+        return response.data;
+    } catch (error) {
+        console.error(`Error accepting agenda item ${itemId}:`, error);
+        throw error;
+    }
+};
+
+// Get the notes of a meeting; occurrences of a recurring meeting need their start
+export const getMeetingNotes = async (id, occurrenceStart) => {
+    try {
+        const params = occurrenceStart ? { start: new Date(occurrenceStart).toISOString() } : {};
+        const response = await api.get(`/api/meetings/${id}/notes`, { params });
+        return response.data;
+    } catch (error) {
+        console.error(`Error fetching notes of meeting ${id}:`, error);
+        throw error;
+    }
+};
+
+// Save the notes of a meeting. version is the version the edit started
+// from; a 409 response carries the newer note to merge with.
+export const saveMeetingNotes = async (id, content, version, occurrenceStart) => {
+    try {
+        const params = occurrenceStart ? { start: new Date(occurrenceStart).toISOString() } : {};
+        const response = await api.put(`/api/meetings/${id}/notes`, { content, version }, { params });
+        return response.data;
+    } catch (error) {
+        console.error(`Error saving notes of meeting ${id}:`, error);
+        throw error;
+    }
+};
+
+// Get the revision history of a meeting's notes, or one revision with content
+export const getNoteRevisions = async (id, version, occurrenceStart) => {
+    try {
+        const params = occurrenceStart ? { start: new Date(occurrenceStart).toISOString() } : {};
+        const url = version ? `/api/meetings/${id}/notes/revisions/${version}` : `/api/meetings/${id}/notes/revisions`;
+        const response = await api.get(url, { params });
+        return response.data;
+    } catch (error) {
+        console.error(`Error fetching note revisions of meeting ${id}:`, error);
+        throw error;
+    }
+};
+
+// Send the notes as minutes to all participants after the meeting (organizer only)
+export const publishMeetingMinutes = async (id, occurrenceStart) => {
+    try {
+        const params = occurrenceStart ? { start: new Date(occurrenceStart).toISOString() } : {};
+        const response = await api.post(`/api/meetings/${id}/notes/publish`, null, { params });
+        return response.data;
+    } catch (error) {
+        console.error(`Error publishing minutes of meeting ${id}:`, error);
+        throw error;
+    }
+};
+
+// Get the action items of a meeting, or of one occurrence of a series
+export const getMeetingActionItems = async (id, occurrenceStart) => {
+    try {
+        const params = occurrenceStart ? { start: new Date(occurrenceStart).toISOString() } : {};
+        const response = await api.get(`/api/meetings/${id}/action-items`, { params });
+        return response.data;
+    } catch (error) {
+        console.error(`Error fetching action items of meeting ${id}:`, error);
+        throw error;
+    }
+};
+
+// Add an action item ({ description, assigneeId, dueDate }) to a meeting
+export const createActionItem = async (id, item, occurrenceStart) => {
+    try {
+        const params = occurrenceStart ? { start: new Date(occurrenceStart).toISOString() } : {};
+        const response = await api.post(`/api/meetings/${id}/action-items`, item, { params });
+        return response.data;
+    } catch (error) {
+        console.error(`Error creating action item for meeting ${id}:`, error);
+        throw error;
+    }
+};
+
+// Get the current user's action items across meetings
+// (filters: { status, overdue })
+export const getActionItems = async (filters = {}) => {
+    try {
+        const response = await api.get('/api/action-items', { params: { assignee: 'me', ...filters } });
+        return response.data;
+    } catch (error) {
+        console.error('Error fetching action items:', error);
+        throw error;
+    }
+};
+
+// Update an action item; assignees may only change its status
+export const updateActionItem = async (itemId, changes) => {
+    try {
+        const response = await api.put(`/api/action-items/${itemId}`, changes);
+        return response.data;
+    } catch (error) {
+        console.error(`Error updating action item ${itemId}:`, error);
+        throw error;
+    }
+};
+
+// Delete an action item
+export const deleteActionItem = async (itemId) => {
+    try {
+        const response = await api.delete(`/api/action-items/${itemId}`);
+        return response.data;
+    } catch (error) {
+        console.error(`Error deleting action item ${itemId}:`, error);
+        throw error;
+    }
+};
+
+// Send a batch of emotion samples ({ timestamp, scores, confidence })
+// recorded during a meeting with emotion tracking
+export const sendEmotionSamples = async (id, samples) => {
+    try {
+        const response = await api.post(`/api/meetings/${id}/emotions`, { samples });
+        return response.data;
+    } catch (error) {
+        console.error(`Error sending emotion samples for meeting ${id}:`, error);
+        throw error;
+    }
+};
+
+// Upload a face frame (Blob, JPEG or PNG) for server-side emotion
+// classification. The server does not keep the image.
+export const sendEmotionFrame = async (id, frame, timestamp = new Date()) => {
+    try {
+        const formData = new FormData();
+        formData.append('frame', frame);
+        formData.append('timestamp', timestamp.toISOString());
+        const response = await api.post(`/api/meetings/${id}/emotions/frames`, formData, {
+            headers: { 'Content-Type': 'multipart/form-data' },
+        });
+        return response.data;
+    } catch (error) {
+        console.error(`Error sending emotion frame for meeting ${id}:`, error);
+        throw error;
+    }
+};
+
+// Get the emotion analytics report of a meeting. For recurring meetings
+// pass the occurrence start to pick an occurrence.
+export const getEmotionReport = async (id, start) => {
+    try {
+        const params = start ? { start } : {};
+        const response = await api.get(`/api/meetings/${id}/emotions/report`, { params });
+        return response.data.report;
+    } catch (error) {
+        console.error(`Error fetching emotion report for meeting ${id}:`, error);
+        throw error;
+    }
+};
+
+// Get the current user's emotion tracking consent for a meeting
+export const getEmotionConsent = async (id) => {
+    try {
+        const response = await api.get(`/api/meetings/${id}/emotions/consent`);
+        return response.data;
+    } catch (error) {
+        console.error(`Error fetching emotion consent for meeting ${id}:`, error);
+        throw error;
+    }
+};
+
+// Opt in to emotion tracking for a meeting
+export const grantEmotionConsent = async (id) => {
+    try {
+        const response = await api.post(`/api/meetings/${id}/emotions/consent`);
+        return response.data;
+    } catch (error) {
+        console.error(`Error granting emotion consent for meeting ${id}:`, error);
+        throw error;
+    }
+};
+
+// Opt out of emotion tracking; the server deletes your samples
+export const withdrawEmotionConsent = async (id) => {
+    try {
+        const response = await api.delete(`/api/meetings/${id}/emotions/consent`);
+        return response.data;
+    } catch (error) {
+        console.error(`Error withdrawing emotion consent for meeting ${id}:`, error);
+        throw error;
+    }
+};
+
 // Helper function to format date to YYYY-MM-DD
 const formatDate = (date) => {
     const d = new Date(date);
@@ -0,0 +1,64 @@
+import axios from 'axios';
+
+const API_URL = 'http://localhost:8080';
+
+// Create axios instance
+const api = axios.create({
+    baseURL: API_URL,
+    headers: {
+        'Content-Type': 'application/json',
+    },
+});
+
+// Add token to requests if it exists
+api.interceptors.request.use((config) => {
+    const token = localStorage.getItem('token');
+    if (token) {
+        config.headers.Authorization = `Bearer ${token}`;
+    }
+    return config;
+});
+
+// Get notifications of the logged in user, newest first
+export const getNotifications = async (params = {}) => {
+    try {
+        const response = await api.get('/api/notifications', { params });
+        return response.data;
+    } catch (error) {
+        console.error('Error fetching notifications:', error);
+        throw error;
+    }
+};
+
+// Get the number of unread notifications
+export const getUnreadCount = async () => {
+    try {
+        const response = await api.get('/api/notifications/unread-count');
+        return response.data.unreadCount;
+    } catch (error) {
+        console.error('Error fetching unread count:', error);
+        throw error;
+    }
+};
+
+// Mark one notification as read
+export const markNotificationRead = async (id) => {
+    try {
+        const response = await api.put(`/api/notifications/${id}/read`);
+        return response.data;
+    } catch (error) {
+        console.error(`Error marking notification ${id} as read:`, error);
+        throw error;
+    }
+};
+
+// Mark all notifications as read
+export const markAllNotificationsRead = async () => {
+    try {
+        const response = await api.put('/api/notifications/read-all');
+        return response.data;
+    } catch (error) {
+        console.error('Error marking notifications as read:', error);
+        throw error;
+    }
+};
@@ -0,0 +1,62 @@
+const API_URL = 'http://localhost:8080';
+
+// Subscribe to realtime events of the logged in user.
+// handlers maps event types (e.g. 'notification', 'meeting.updated') to
+// callbacks receiving the parsed event. Returns a function that closes
+// the connection. The browser reconnects automatically after errors.
+export const subscribeToEvents = (handlers, options = {}) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+        return () => {};
+    }
+
+    const params = new URLSearchParams({ token });
+    if (options.types) {
+        params.set('types', options.types.join(','));
+    }
+    if (options.meetings) {
+        params.set('meetings', options.meetings.join(','));
+    }
+
+    const source = new EventSource(`${API_URL}/events?${params}`);
+    Object.entries(handlers).forEach(([type, handler]) => {
+        source.addEventListener(type, (e) => {
+            try {
+                handler(JSON.parse(e.data));
+            } catch (error) {
+                console.error(`Error handling ${type} event:`, error);
+            }
+        });
+    });
+
+    return () => source.close();
+};
+
+// Subscribe to the live mood of a meeting in progress (organizer only).
+// onMood receives the rolling aggregate every few seconds; onEnded is
+// called once when the meeting is over. Returns a function that closes
+// the connection.
+export const subscribeToLiveEmotions = (meetingId, onMood, onEnded) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+        return () => {};
+    }
+
+    const params = new URLSearchParams({ token });
+    const source = new EventSource(`${API_URL}/meetings/${meetingId}/emotions/live?${params}`);
+    source.addEventListener('mood', (e) => {
+        try {
+            onMood(JSON.parse(e.data));
+        } catch (error) {
+            console.error('Error handling mood event:', error);
+        }
+    });
+    source.addEventListener('ended', () => {
+        source.close();
+        if (onEnded) {
+            onEnded();
+        }
+    });
+
+    return () => source.close();
+};
@@ -94,4 +94,25 @@ export const uploadProfileImage = async (id, formData) => {
         console.error(`Error uploading profile image for user ${id}:`, error);
         throw error;
     }
-};
\ No newline at end of file
+};
+// Get notification settings of the logged in user
+export const getNotificationSettings = async () => {
+    try {
+        const response = await api.get('/api/settings/notifications');
+        return response.data;
+    } catch (error) {
+        console.error('Error fetching notification settings:', error);
+        throw error;
+    }
+};
+
+// Save notification settings of the logged in user
+export const updateNotificationSettings = async (settings) => {
+    try {
+        const response = await api.put('/api/settings/notifications', settings);
+        return response.data;
+    } catch (error) {
+        console.error('Error updating notification settings:', error);
+        throw error;
+    }
+};